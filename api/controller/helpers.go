@@ -1,7 +1,11 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nebojsaj1726/user-manager/domain"
@@ -11,8 +15,120 @@ import (
 func ValidateObjectID(c *gin.Context, id string) (primitive.ObjectID, bool) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: "Invalid ID"})
+		c.Error(domain.NewProblem(http.StatusBadRequest, "Invalid ID").WithCode("invalid_id", nil))
 		return primitive.NilObjectID, false
 	}
 	return objectID, true
 }
+
+// ParseIfMatch returns the user version expected by the If-Match header, or
+// domain.AnyVersion when the header is absent or "*". It writes the error
+// response itself and returns false when the request cannot proceed.
+func ParseIfMatch(c *gin.Context, required bool) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if required {
+			c.Error(domain.NewProblem(http.StatusPreconditionRequired, "If-Match header is required").WithCode("if_match_required", nil))
+			return domain.AnyVersion, false
+		}
+		return domain.AnyVersion, true
+	}
+
+	version, err := ifMatchVersion(header)
+	if err != nil {
+		c.Error(domain.NewProblem(http.StatusPreconditionFailed, err.Error()))
+		return domain.AnyVersion, false
+	}
+
+	return version, true
+}
+
+// ifMatchVersion parses a non-empty If-Match header.
+func ifMatchVersion(header string) (int64, error) {
+	if header == "*" {
+		return domain.AnyVersion, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+	if err != nil || len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' || version < 1 {
+		return domain.AnyVersion, errors.New("If-Match does not match the current version")
+	}
+
+	return version, nil
+}
+
+// ParsePage reads the page and limit parameters of list endpoints.
+func ParsePage(c *gin.Context) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.Error(domain.NewProblem(http.StatusBadRequest, "Invalid page parameter").WithCode("invalid_page", nil))
+		return 0, 0, false
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.Error(domain.NewProblem(http.StatusBadRequest, "Invalid limit parameter").WithCode("invalid_limit", nil))
+		return 0, 0, false
+	}
+
+	return page, limit, true
+}
+
+// splitList reads a comma-separated query parameter, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// userFilterParams are the query parameters read by ParseUserFilter.
+var userFilterParams = []string{"email", "min_age", "max_age"}
+
+// ParseUserFilter reads the filters shared by the list and export endpoints.
+func ParseUserFilter(c *gin.Context) (domain.UserFilter, bool) {
+	filter, err := parseUserFilter(c.Query)
+	if err != nil {
+		c.Error(err)
+		return filter, false
+	}
+	return filter, true
+}
+
+func parseUserFilter(get func(string) string) (domain.UserFilter, error) {
+	filter := domain.UserFilter{Email: get("email")}
+
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"min_age", &filter.MinAge}, {"max_age", &filter.MaxAge}} {
+		value := get(param.name)
+		if value == "" {
+			continue
+		}
+		age, err := strconv.Atoi(value)
+		if err != nil || age < 0 {
+			return filter, domain.NewValidationError("Invalid %s parameter", param.name)
+		}
+		*param.target = age
+	}
+
+	if filter.MaxAge > 0 && filter.MinAge > filter.MaxAge {
+		return filter, domain.NewFieldError(domain.ErrValidation, domain.FieldError{
+			Field:   "min_age",
+			Code:    "max",
+			Message: fmt.Sprintf("min_age must be at most %d", filter.MaxAge),
+			Params:  map[string]interface{}{"max": filter.MaxAge},
+		})
+	}
+
+	return filter, nil
+}
+
+func SetETag(c *gin.Context, user *domain.User) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, user.Version))
+}
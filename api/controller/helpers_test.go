@@ -0,0 +1,69 @@
+package controller_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		query      string
+		want       domain.UserFilter
+		wantFields []domain.FieldError
+		wantErr    string
+	}{
+		{name: "no filters", query: ""},
+		{
+			name:  "all filters",
+			query: "email=example.com&min_age=18&max_age=40",
+			want:  domain.UserFilter{Email: "example.com", MinAge: 18, MaxAge: 40},
+		},
+		{name: "equal ages", query: "min_age=30&max_age=30", want: domain.UserFilter{MinAge: 30, MaxAge: 30}},
+		{name: "only min_age", query: "min_age=30", want: domain.UserFilter{MinAge: 30}},
+		{name: "invalid min_age", query: "min_age=old", wantErr: "Invalid min_age parameter"},
+		{name: "negative max_age", query: "max_age=-1", wantErr: "Invalid max_age parameter"},
+		{
+			name:    "min_age above max_age",
+			query:   "min_age=40&max_age=30",
+			wantErr: "min_age must be at most 30",
+			wantFields: []domain.FieldError{
+				{Field: "min_age", Code: "max", Message: "min_age must be at most 30", Params: map[string]interface{}{"max": 30}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/users?"+tt.query, nil)
+
+			filter, ok := controller.ParseUserFilter(c)
+			if tt.wantErr == "" {
+				assert.True(t, ok)
+				assert.Empty(t, c.Errors)
+				assert.Equal(t, tt.want, filter)
+				return
+			}
+
+			assert.False(t, ok)
+			if !assert.Len(t, c.Errors, 1) {
+				t.FailNow()
+			}
+			err := c.Errors[0].Err
+			assert.ErrorIs(t, err, domain.ErrValidation)
+			assert.EqualError(t, err, tt.wantErr)
+			if tt.wantFields != nil {
+				assert.Equal(t, tt.wantFields, err.(*domain.Error).Fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+type JobController struct {
+	JobUsecase domain.JobUsecase
+}
+
+func (jc *JobController) GetByID(c *gin.Context) {
+	objectID, valid := ValidateObjectID(c, c.Param("id"))
+	if !valid {
+		return
+	}
+
+	job, err := jc.JobUsecase.GetByID(c, objectID.Hex())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (jc *JobController) Cancel(c *gin.Context) {
+	objectID, valid := ValidateObjectID(c, c.Param("id"))
+	if !valid {
+		return
+	}
+
+	job, err := jc.JobUsecase.Cancel(c, objectID.Hex())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (jc *JobController) Result(c *gin.Context) {
+	objectID, valid := ValidateObjectID(c, c.Param("id"))
+	if !valid {
+		return
+	}
+
+	job, result, err := jc.JobUsecase.OpenResult(c, objectID.Hex())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer result.Close()
+
+	c.Header("Content-Type", job.ResultContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s"`, job.Type, job.ID.Hex()))
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, result)
+}
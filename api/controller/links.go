@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// pageLinks returns the links to the first, previous, next and last page of
+// a list, which keep every other query parameter of the request. Pages past
+// the end link back to the last page. The links are also added to the Link
+// header (RFC 8288).
+func pageLinks(c *gin.Context, total int64, page, limit int) *domain.Links {
+	last := 1
+	if total > 0 {
+		last = int((total + int64(limit) - 1) / int64(limit))
+	}
+
+	pageURL := func(page int) string {
+		u := middleware.RequestURL(c)
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(limit))
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	links := &domain.Links{First: pageURL(1), Last: pageURL(last)}
+	if page > 1 {
+		links.Prev = pageURL(min(page-1, last))
+	}
+	if page < last {
+		links.Next = pageURL(page + 1)
+	}
+
+	var header []string
+	for _, link := range []struct{ rel, target string }{
+		{"first", links.First},
+		{"prev", links.Prev},
+		{"next", links.Next},
+		{"last", links.Last},
+	} {
+		if link.target != "" {
+			header = append(header, fmt.Sprintf(`<%s>; rel="%s"`, link.target, link.rel))
+		}
+	}
+	c.Writer.Header().Add("Link", strings.Join(header, ", "))
+
+	return links
+}
+
+// userLink returns the self link of a user in collection, the path of the
+// users resource the request was made under.
+func userLink(c *gin.Context, collection string, user *domain.User) *domain.Links {
+	u := middleware.RequestURL(c)
+	u.Path = path.Join(collection, user.ID.Hex())
+	u.RawPath = ""
+	u.RawQuery = ""
+	return &domain.Links{Self: u.String()}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/i18n"
+	"github.com/nebojsaj1726/user-manager/api/media"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// UserPresenter writes the user responses of one API version, so a version
+// can change its response shapes without its own set of handlers.
+type UserPresenter interface {
+	Created(c *gin.Context, user *domain.User)
+	Updated(c *gin.Context, user *domain.User)
+	Deleted(c *gin.Context)
+	List(c *gin.Context, users []domain.User, suggestions []string, total int64, page, limit int)
+	SearchResults(c *gin.Context, hits []domain.UserSearchHit, suggestions []string, total int64, page, limit int)
+}
+
+// V1Presenter keeps the original response shapes used by the web client.
+type V1Presenter struct{}
+
+func (V1Presenter) Created(c *gin.Context, user *domain.User) {
+	message := i18n.From(c).Text("user.created", "User created successfully", nil)
+	media.Render(c, http.StatusOK, domain.UserResponse{Message: message, User: user})
+}
+
+func (V1Presenter) Updated(c *gin.Context, user *domain.User) {
+	message := i18n.From(c).Text("user.updated", "User updated successfully", nil)
+	media.Render(c, http.StatusOK, domain.UserResponse{Message: message, User: user})
+}
+
+func (V1Presenter) Deleted(c *gin.Context) {
+	message := i18n.From(c).Text("user.deleted", "User deleted successfully", nil)
+	media.Render(c, http.StatusOK, domain.SuccessResponse{Message: message})
+}
+
+func (V1Presenter) List(c *gin.Context, users []domain.User, suggestions []string, total int64, page, limit int) {
+	items, links := listItems(c, users, total, page, limit)
+	media.Render(c, http.StatusOK, domain.UserListResponse{Users: items, Total: total, Suggestions: suggestions, Links: links})
+}
+
+func (V1Presenter) SearchResults(c *gin.Context, hits []domain.UserSearchHit, suggestions []string, total int64, page, limit int) {
+	items, links := searchItems(c, hits, total, page, limit)
+	media.Render(c, http.StatusOK, domain.UserSearchResponse{Users: items, Total: total, Suggestions: suggestions, Links: links})
+}
+
+// V2Presenter returns resources without message wrappers, answers creates
+// with 201 and a Location header, and pages lists in a data envelope.
+type V2Presenter struct{}
+
+func (V2Presenter) Created(c *gin.Context, user *domain.User) {
+	c.Header("Location", c.Request.URL.Path+"/"+user.ID.Hex())
+	media.Render(c, http.StatusCreated, user)
+}
+
+func (V2Presenter) Updated(c *gin.Context, user *domain.User) {
+	media.Render(c, http.StatusOK, user)
+}
+
+func (V2Presenter) Deleted(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+}
+
+func (V2Presenter) List(c *gin.Context, users []domain.User, suggestions []string, total int64, page, limit int) {
+	items, links := listItems(c, users, total, page, limit)
+	media.Render(c, http.StatusOK, domain.UserPage{Data: items, Page: page, Limit: limit, Total: total, Suggestions: suggestions, Links: links})
+}
+
+func (V2Presenter) SearchResults(c *gin.Context, hits []domain.UserSearchHit, suggestions []string, total int64, page, limit int) {
+	items, links := searchItems(c, hits, total, page, limit)
+	media.Render(c, http.StatusOK, domain.UserSearchPage{Data: items, Page: page, Limit: limit, Total: total, Suggestions: suggestions, Links: links})
+}
+
+// listItems returns the users of a list with their links, and the links of
+// its page.
+func listItems(c *gin.Context, users []domain.User, total int64, page, limit int) ([]domain.UserItem, *domain.Links) {
+	items := make([]domain.UserItem, len(users))
+	for i := range users {
+		items[i] = domain.UserItem{User: users[i], Links: userLink(c, c.Request.URL.Path, &users[i])}
+	}
+	return items, pageLinks(c, total, page, limit)
+}
+
+// searchItems is listItems for search results, which are served below the
+// users they link to.
+func searchItems(c *gin.Context, hits []domain.UserSearchHit, total int64, page, limit int) ([]domain.UserSearchItem, *domain.Links) {
+	items := make([]domain.UserSearchItem, len(hits))
+	for i := range hits {
+		items[i] = domain.UserSearchItem{UserSearchHit: hits[i], Links: userLink(c, path.Dir(c.Request.URL.Path), &hits[i].User)}
+	}
+	return items, pageLinks(c, total, page, limit)
+}
@@ -0,0 +1,341 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/api/scim"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ScimController serves users as SCIM 2.0 resources for identity providers.
+// Responses and errors use the SCIM formats rather than the REST API ones.
+type ScimController struct {
+	UserUsecase domain.UserUsecase
+	// RequireIfMatch rejects writes to existing users without an If-Match
+	// header, as for the REST API.
+	RequireIfMatch bool
+	// MaxResults caps the count of a list request.
+	MaxResults int
+}
+
+func (sc *ScimController) List(c *gin.Context) {
+	var filter domain.UserFilter
+	if expr := c.Query("filter"); expr != "" {
+		expression, err := scim.ParseFilter(expr)
+		if err != nil {
+			scimError(c, err)
+			return
+		}
+		filter.Expression = expression
+	}
+
+	startIndex, err := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if err != nil {
+		scimError(c, scim.NewError(http.StatusBadRequest, scim.ErrInvalidValue, "startIndex must be an integer"))
+		return
+	}
+	// A startIndex below 1 is interpreted as 1 (RFC 7644, section 3.4.2.4).
+	if startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(sc.MaxResults)))
+	if err != nil {
+		scimError(c, scim.NewError(http.StatusBadRequest, scim.ErrInvalidValue, "count must be an integer"))
+		return
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > sc.MaxResults {
+		count = sc.MaxResults
+	}
+
+	total, err := sc.UserUsecase.Count(c, filter)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	resources := []scim.User{}
+	// A count of 0 asks for the total only.
+	if count > 0 {
+		users, err := sc.UserUsecase.FetchRange(c, filter, startIndex-1, count)
+		if err != nil {
+			scimError(c, err)
+			return
+		}
+		for i := range users {
+			resources = append(resources, scim.FromUser(&users[i], scimLocation(c, users[i].ID)))
+		}
+	}
+
+	scimJSON(c, http.StatusOK, scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func (sc *ScimController) Create(c *gin.Context) {
+	var resource scim.User
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		scimError(c, scim.NewError(http.StatusBadRequest, scim.ErrInvalidSyntax, err.Error()))
+		return
+	}
+
+	user, err := resource.ToUser()
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	user.ID = primitive.NewObjectID()
+
+	if err := sc.UserUsecase.Create(c, user); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	location := scimLocation(c, user.ID)
+	c.Header("Location", location)
+	SetETag(c, user)
+	scimJSON(c, http.StatusCreated, scim.FromUser(user, location))
+}
+
+func (sc *ScimController) Get(c *gin.Context) {
+	user, ok := sc.user(c)
+	if !ok {
+		return
+	}
+
+	SetETag(c, user)
+	scimJSON(c, http.StatusOK, scim.FromUser(user, scimLocation(c, user.ID)))
+}
+
+func (sc *ScimController) Replace(c *gin.Context) {
+	objectID, ok := scimObjectID(c)
+	if !ok {
+		return
+	}
+
+	version, ok := scimIfMatch(c, sc.RequireIfMatch)
+	if !ok {
+		return
+	}
+
+	var resource scim.User
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		scimError(c, scim.NewError(http.StatusBadRequest, scim.ErrInvalidSyntax, err.Error()))
+		return
+	}
+
+	user, err := resource.ToUser()
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	user.Version = version
+
+	sc.update(c, objectID.Hex(), user)
+}
+
+func (sc *ScimController) Patch(c *gin.Context) {
+	version, ok := scimIfMatch(c, sc.RequireIfMatch)
+	if !ok {
+		return
+	}
+
+	var request scim.PatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		scimError(c, scim.NewError(http.StatusBadRequest, scim.ErrInvalidSyntax, err.Error()))
+		return
+	}
+
+	user, ok := sc.user(c)
+	if !ok {
+		return
+	}
+
+	if version != domain.AnyVersion && version != user.Version {
+		scimError(c, scim.NewError(http.StatusPreconditionFailed, "", "version mismatch: user has been modified"))
+		return
+	}
+
+	if err := request.Apply(user); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	// The update only succeeds if the user is unchanged since it was read.
+	sc.update(c, user.ID.Hex(), user)
+}
+
+func (sc *ScimController) Delete(c *gin.Context) {
+	objectID, ok := scimObjectID(c)
+	if !ok {
+		return
+	}
+
+	version, ok := scimIfMatch(c, sc.RequireIfMatch)
+	if !ok {
+		return
+	}
+
+	if err := sc.UserUsecase.Delete(c, objectID.Hex(), version); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (sc *ScimController) ServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, scim.NewServiceProviderConfig(sc.MaxResults))
+}
+
+func (sc *ScimController) ResourceTypes(c *gin.Context) {
+	scimJSON(c, http.StatusOK, discoveryList(scim.ResourceTypes))
+}
+
+func (sc *ScimController) ResourceType(c *gin.Context) {
+	for _, resourceType := range scim.ResourceTypes {
+		if resourceType.ID == c.Param("id") {
+			scimJSON(c, http.StatusOK, resourceType)
+			return
+		}
+	}
+	scimError(c, scim.NewError(http.StatusNotFound, "", "Resource type not found"))
+}
+
+func (sc *ScimController) Schemas(c *gin.Context) {
+	scimJSON(c, http.StatusOK, discoveryList(scim.Schemas))
+}
+
+func (sc *ScimController) Schema(c *gin.Context) {
+	for _, schema := range scim.Schemas {
+		if schema.ID == c.Param("id") {
+			scimJSON(c, http.StatusOK, schema)
+			return
+		}
+	}
+	scimError(c, scim.NewError(http.StatusNotFound, "", "Schema not found"))
+}
+
+func (sc *ScimController) user(c *gin.Context) (*domain.User, bool) {
+	objectID, ok := scimObjectID(c)
+	if !ok {
+		return nil, false
+	}
+
+	user, err := sc.UserUsecase.GetByID(c, objectID.Hex())
+	if err != nil {
+		scimError(c, err)
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (sc *ScimController) update(c *gin.Context, id string, user *domain.User) {
+	if err := sc.UserUsecase.Update(c, id, user); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	updated, err := sc.UserUsecase.GetByID(c, id)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	SetETag(c, updated)
+	scimJSON(c, http.StatusOK, scim.FromUser(updated, scimLocation(c, updated.ID)))
+}
+
+func scimObjectID(c *gin.Context) (primitive.ObjectID, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		// Unknown ids are not distinguished from malformed ones.
+		scimError(c, scim.NewError(http.StatusNotFound, "", "User not found"))
+		return primitive.NilObjectID, false
+	}
+	return objectID, true
+}
+
+func scimIfMatch(c *gin.Context, required bool) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if required {
+			scimError(c, scim.NewError(http.StatusPreconditionRequired, "", "If-Match header is required"))
+			return domain.AnyVersion, false
+		}
+		return domain.AnyVersion, true
+	}
+
+	version, err := ifMatchVersion(header)
+	if err != nil {
+		scimError(c, scim.NewError(http.StatusPreconditionFailed, "", err.Error()))
+		return domain.AnyVersion, false
+	}
+	return version, true
+}
+
+// scimLocation is the absolute URL of the user resource, which SCIM clients
+// store to address the user later. It is built like the links of the REST
+// API, so it names the host clients reach through trusted proxies.
+func scimLocation(c *gin.Context, id primitive.ObjectID) string {
+	u := middleware.RequestURL(c)
+	u.Path = strings.TrimSuffix(c.FullPath(), "/:id") + "/" + id.Hex()
+	u.RawPath = ""
+	u.RawQuery = ""
+	return u.String()
+}
+
+func discoveryList[T any](resources []T) gin.H {
+	return gin.H{
+		"schemas":      []string{scim.SchemaListResponse},
+		"totalResults": len(resources),
+		"startIndex":   1,
+		"itemsPerPage": len(resources),
+		"Resources":    resources,
+	}
+}
+
+// scimJSON writes body as JSON with the SCIM media type; gin keeps a
+// Content-Type that is already set.
+func scimJSON(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scim.ContentType+"; charset=utf-8")
+	c.JSON(status, body)
+}
+
+func scimError(c *gin.Context, err error) {
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		scimErr = scimErrorFor(err)
+	}
+	scimJSON(c, scimErr.StatusCode(), scimErr)
+}
+
+func scimErrorFor(err error) *scim.Error {
+	message := err.Error()
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return scim.NewError(http.StatusNotFound, "", message)
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return scim.NewError(http.StatusPreconditionFailed, "", message)
+	case errors.Is(err, domain.ErrConflict):
+		return scim.NewError(http.StatusConflict, scim.ErrUniqueness, message)
+	case errors.Is(err, domain.ErrValidation):
+		return scim.NewError(http.StatusBadRequest, scim.ErrInvalidValue, message)
+	default:
+		return scim.NewError(http.StatusInternalServerError, "", "Something went wrong")
+	}
+}
@@ -0,0 +1,75 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/api/scim"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestScimController_RequireIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sc := &controller.ScimController{RequireIfMatch: true, MaxResults: 10}
+	router := gin.New()
+	router.PUT("/Users/:id", sc.Replace)
+	router.PATCH("/Users/:id", sc.Patch)
+	router.DELETE("/Users/:id", sc.Delete)
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			req := httptest.NewRequest(method, "/Users/507f1f77bcf86cd799439011", strings.NewReader(`{}`))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+			assert.Contains(t, w.Body.String(), "If-Match header is required")
+		})
+	}
+}
+
+func TestScimController_Location(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := primitive.NewObjectID()
+	sc := &controller.ScimController{
+		UserUsecase: &MockUserUsecase{
+			GetByIDFunc: func(ctx context.Context, userID string) (*domain.User, error) {
+				return &domain.User{ID: id, Email: "jane@example.com", Age: 30, Version: 1}, nil
+			},
+		},
+		MaxResults: 10,
+	}
+	externalURL, err := middleware.ExternalURL([]string{"192.0.2.1"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	router := gin.New()
+	router.Use(externalURL)
+	router.GET("/scim/v2/Users/:id", sc.Get)
+
+	// The location names the host the proxy was addressed at.
+	req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users/"+id.Hex()+"?attributes=userName", nil)
+	req.RemoteAddr = "192.0.2.1:4321"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "api.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resource scim.User
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resource))
+	if assert.NotNil(t, resource.Meta) {
+		assert.Equal(t, "https://api.example.com/scim/v2/Users/"+id.Hex(), resource.Meta.Location)
+	}
+}
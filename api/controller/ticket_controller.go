@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+type TicketController struct {
+	TicketUsecase domain.TicketUsecase
+}
+
+// Create issues a ticket with which a client that cannot set headers opens
+// an event stream or WebSocket.
+func (tc *TicketController) Create(c *gin.Context) {
+	ticket, err := tc.TicketUsecase.Issue(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, ticket)
+}
@@ -1,77 +1,223 @@
 package controller
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/nebojsaj1726/user-manager/api/i18n"
+	"github.com/nebojsaj1726/user-manager/api/media"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
 	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/userio"
+	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type UserController struct {
-	UserUsecase domain.UserUsecase
+	UserUsecase    domain.UserUsecase
+	JobUsecase     domain.JobUsecase
+	RequireIfMatch bool
+	MaxBatchSize   int
+	MaxLookupSize  int
+	// Presenter shapes the responses of the API version served by this
+	// controller; V1Presenter is used when it is nil.
+	Presenter UserPresenter
+}
+
+func (uc *UserController) presenter() UserPresenter {
+	if uc.Presenter == nil {
+		return V1Presenter{}
+	}
+	return uc.Presenter
 }
 
 func (uc *UserController) Create(c *gin.Context) {
 	var user domain.User
 
-	if err := c.ShouldBind(&user); err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+	if err := media.Bind(c, &user); err != nil {
+		c.Error(uc.userBindingError(c, "", &user, err))
 		return
 	}
 
 	user.ID = primitive.NewObjectID()
 
 	if err := uc.UserUsecase.Create(c, &user); err != nil {
-		if strings.Contains(err.Error(), "email must be unique") || strings.Contains(err.Error(), "age must be greater than 18") {
-			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: "Something went wrong"})
-		}
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "User created successfully",
-		"user":    user,
-	})
+	SetETag(c, &user)
+	uc.presenter().Created(c, &user)
+}
+
+// userBindingError reports a user body that failed binding together with the
+// rules the usecase checks on the fields that were read, so the problem lists
+// every rejected field rather than only those the binding tags cover.
+func (uc *UserController) userBindingError(c *gin.Context, id string, user *domain.User, err error) error {
+	err = bindingError(err)
+	if !errors.Is(err, domain.ErrValidation) {
+		return err
+	}
+	return mergeFieldErrors(err, uc.UserUsecase.Validate(c, id, user))
 }
 
 func (uc *UserController) Fetch(c *gin.Context) {
-	page := c.DefaultQuery("page", "1")
-	limit := c.DefaultQuery("limit", "10")
+	if c.Query("ids") != "" || c.Query("emails") != "" {
+		uc.lookup(c, domain.UserLookupRequest{
+			IDs:    splitList(c.Query("ids")),
+			Emails: splitList(c.Query("emails")),
+		})
+		return
+	}
+
+	page, limit, valid := ParsePage(c)
+	if !valid {
+		return
+	}
+
+	filter, valid := ParseUserFilter(c)
+	if !valid {
+		return
+	}
+
+	users, err := uc.UserUsecase.Fetch(c, filter, page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	pageInt, err := strconv.Atoi(page)
-	if err != nil || pageInt < 1 {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: "Invalid page parameter"})
+	total, err := uc.UserUsecase.Count(c, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// An exact email that matches nothing is likely misspelled.
+	var suggestions []string
+	if total == 0 && filter.Email != "" {
+		similar := filter
+		similar.Text, similar.Email = filter.Email, ""
+		suggestions, err = uc.UserUsecase.Suggest(c, similar, suggestionLimit)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	uc.presenter().List(c, users, suggestions, total, page, limit)
+}
+
+// suggestionLimit is the number of "did you mean" emails returned when a
+// search or an email filter finds nothing.
+const suggestionLimit = 5
+
+// Search ranks users by relevance to the q parameter, or by edit distance
+// with mode=fuzzy. The list filters and paging parameters apply as they do
+// to Fetch.
+func (uc *UserController) Search(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.Error(domain.NewProblem(http.StatusBadRequest, "The q parameter is required").WithCode("q_required", nil))
+		return
+	}
+
+	mode := c.DefaultQuery("mode", "text")
+	if mode != "text" && mode != "fuzzy" {
+		c.Error(domain.NewProblem(http.StatusBadRequest, "Invalid mode parameter").WithCode("invalid_mode", nil))
+		return
+	}
+
+	page, limit, valid := ParsePage(c)
+	if !valid {
+		return
+	}
+
+	filter, valid := ParseUserFilter(c)
+	if !valid {
+		return
+	}
+	filter.Text = text
+
+	var hits []domain.UserSearchHit
+	var total int64
+	var err error
+	if mode == "fuzzy" {
+		hits, total, err = uc.UserUsecase.FuzzySearch(c, filter, page, limit)
+	} else {
+		hits, err = uc.UserUsecase.Search(c, filter, page, limit)
+		if err == nil {
+			total, err = uc.UserUsecase.Count(c, filter)
+		}
+	}
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	limitInt, err := strconv.Atoi(limit)
-	if err != nil || limitInt < 1 {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: "Invalid limit parameter"})
+	var suggestions []string
+	if total == 0 && mode == "text" {
+		suggestions, err = uc.UserUsecase.Suggest(c, filter, suggestionLimit)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	uc.presenter().SearchResults(c, hits, suggestions, total, page, limit)
+}
+
+func (uc *UserController) Export(c *gin.Context) {
+	filter, valid := ParseUserFilter(c)
+	if !valid {
 		return
 	}
 
-	users, err := uc.UserUsecase.Fetch(c, pageInt, limitInt)
+	format := c.DefaultQuery("format", "json")
+	contentType, err := userio.ExportContentType(format)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: err.Error()})
+		c.Error(domain.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	if c.Query("async") == "true" {
+		params := map[string]string{domain.JobParamFormat: format}
+		for _, param := range userFilterParams {
+			if value := c.Query(param); value != "" {
+				params[param] = value
+			}
+		}
+		uc.submitJob(c, &domain.Job{Type: domain.JobTypeExport, Params: params, ResultContentType: contentType}, nil)
 		return
 	}
 
-	total, err := uc.UserUsecase.Count(c)
+	writer, err := userio.NewExportWriter(c.Writer, format)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: err.Error()})
+		c.Error(domain.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="users.%s"`, format))
+
+	if err := uc.UserUsecase.Export(c, filter, writer.Write); err != nil {
+		// The status line has already been sent, so the truncated body is
+		// the only signal the client gets.
+		log.Errorf("User export stopped: %v", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"users": users,
-		"total": total,
-	})
+	if err := writer.Close(); err != nil {
+		log.Errorf("Failed to finish user export: %v", err)
+	}
+	c.Writer.WriteHeaderNow()
 }
 
 func (uc *UserController) GetByID(c *gin.Context) {
@@ -84,15 +230,12 @@ func (uc *UserController) GetByID(c *gin.Context) {
 
 	user, err := uc.UserUsecase.GetByID(c, objectID.Hex())
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, domain.ErrorResponse{Message: "User not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: err.Error()})
-		}
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	SetETag(c, user)
+	media.Render(c, http.StatusOK, user)
 }
 
 func (uc *UserController) Update(c *gin.Context) {
@@ -105,30 +248,67 @@ func (uc *UserController) Update(c *gin.Context) {
 
 	var user domain.User
 
-	if err := c.ShouldBind(&user); err != nil {
-		c.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
+	version, ok := ParseIfMatch(c, uc.RequireIfMatch)
+	if !ok {
+		return
+	}
+
+	if err := media.Bind(c, &user); err != nil {
+		c.Error(uc.userBindingError(c, objectID.Hex(), &user, err))
 		return
 	}
 
+	user.Version = version
+
 	if err := uc.UserUsecase.Update(c, objectID.Hex(), &user); err != nil {
-		if strings.Contains(err.Error(), "email must be unique") || strings.Contains(err.Error(), "age must be greater than 18") {
-			c.JSON(http.StatusBadRequest, domain.ErrorResponse{Message: err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: "Something went wrong"})
-		}
+		c.Error(err)
 		return
 	}
 
 	updatedUser, err := uc.UserUsecase.GetByID(c, objectID.Hex())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: "Failed to retrieve updated user"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "User updated successfully",
-		"user":    updatedUser,
-	})
+	SetETag(c, updatedUser)
+	uc.presenter().Updated(c, updatedUser)
+}
+
+func (uc *UserController) Patch(c *gin.Context) {
+	id := c.Param("id")
+
+	objectID, valid := ValidateObjectID(c, id)
+	if !valid {
+		return
+	}
+
+	version, ok := ParseIfMatch(c, uc.RequireIfMatch)
+	if !ok {
+		return
+	}
+
+	if c.ContentType() != domain.ContentTypeJSONPatch {
+		c.Error(domain.NewProblem(http.StatusUnsupportedMediaType, "Content-Type must be "+domain.ContentTypeJSONPatch).
+			WithCode("unsupported_patch_type", map[string]interface{}{"type": domain.ContentTypeJSONPatch}))
+		return
+	}
+
+	var ops []domain.PatchOperation
+
+	if err := c.ShouldBindJSON(&ops); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	user, err := uc.UserUsecase.Patch(c, objectID.Hex(), version, ops)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	SetETag(c, user)
+	uc.presenter().Updated(c, user)
 }
 
 func (uc *UserController) Delete(c *gin.Context) {
@@ -139,10 +319,223 @@ func (uc *UserController) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := uc.UserUsecase.Delete(c, objectID.Hex()); err != nil {
-		c.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: err.Error()})
+	version, ok := ParseIfMatch(c, uc.RequireIfMatch)
+	if !ok {
+		return
+	}
+
+	if err := uc.UserUsecase.Delete(c, objectID.Hex(), version); err != nil {
+		c.Error(err)
+		return
+	}
+
+	uc.presenter().Deleted(c)
+}
+
+func (uc *UserController) Batch(c *gin.Context) {
+	var request domain.BatchRequest
+
+	if err := media.Bind(c, &request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	// Asynchronous batches are not limited, they are what large bulk
+	// changes are meant to use.
+	if c.Query("async") == "true" {
+		input, err := json.Marshal(request)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		job := &domain.Job{Type: domain.JobTypeBatch, Total: int64(len(request.Operations)), ResultContentType: "application/json; charset=utf-8"}
+		if uc.RequireIfMatch {
+			job.Params = map[string]string{domain.JobParamRequireVersion: "true"}
+		}
+		uc.submitJob(c, job, bytes.NewReader(input))
+		return
+	}
+
+	if len(request.Operations) > uc.MaxBatchSize {
+		c.Error(domain.NewProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("A batch can contain at most %d operations", uc.MaxBatchSize)).
+			WithCode("batch_too_large", map[string]interface{}{"max": uc.MaxBatchSize}))
+		return
+	}
+
+	options := domain.BatchOptions{Atomic: request.Mode == domain.BatchModeAtomic, RequireVersion: uc.RequireIfMatch}
+
+	items, err := uc.UserUsecase.Batch(c, request.Operations, options)
+	if err != nil && items == nil {
+		c.Error(err)
+		return
+	}
+
+	localizer := i18n.From(c)
+	results := batchResults(localizer, items, 0)
+
+	if err != nil {
+		_, message := batchError(localizer, err)
+		media.Render(c, http.StatusBadRequest, domain.BatchResponse{Message: message, Results: results})
+		return
+	}
+
+	media.Render(c, http.StatusOK, domain.BatchResponse{Results: results})
+}
+
+// Lookup is the POST /users:lookup variant of GET /users?ids=, for lists
+// that do not fit in a URL.
+func (uc *UserController) Lookup(c *gin.Context) {
+	var request domain.UserLookupRequest
+
+	if err := media.Bind(c, &request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	uc.lookup(c, request)
+}
+
+func (uc *UserController) lookup(c *gin.Context, request domain.UserLookupRequest) {
+	if err := binding.Validator.ValidateStruct(&request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	size := len(request.IDs) + len(request.Emails)
+	if size == 0 {
+		c.Error(domain.NewProblem(http.StatusBadRequest, "At least one ID or email is required").WithCode("lookup_empty", nil))
+		return
+	}
+	if size > uc.MaxLookupSize {
+		c.Error(domain.NewProblem(http.StatusBadRequest, fmt.Sprintf("A lookup can contain at most %d IDs and emails", uc.MaxLookupSize)).
+			WithCode("lookup_too_large", map[string]interface{}{"max": uc.MaxLookupSize}))
+		return
+	}
+
+	ids := make([]string, len(request.IDs))
+	for i, id := range request.IDs {
+		objectID, valid := ValidateObjectID(c, id)
+		if !valid {
+			return
+		}
+		ids[i] = objectID.Hex()
+	}
+
+	lookup, err := uc.UserUsecase.Lookup(c, ids, request.Emails)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	media.Render(c, http.StatusOK, lookup)
+}
+
+func (uc *UserController) Import(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.Error(domain.NewProblem(http.StatusBadRequest, "Invalid dry_run parameter").WithCode("invalid_dry_run", nil))
+		return
+	}
+
+	format := c.DefaultQuery("report", "json")
+	contentType, err := userio.ReportContentType(format)
+	if err != nil {
+		c.Error(domain.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	body, err := importBody(c)
+	if err != nil {
+		c.Error(domain.NewProblem(http.StatusUnsupportedMediaType, err.Error()))
+		return
+	}
+
+	mapping := c.QueryMap("map")
+
+	if c.Query("async") == "true" {
+		params := map[string]string{domain.JobParamDryRun: strconv.FormatBool(dryRun), domain.JobParamReport: format}
+		for field, column := range mapping {
+			params[domain.JobParamMapping+field] = column
+		}
+		uc.submitJob(c, &domain.Job{Type: domain.JobTypeImport, Params: params, ResultContentType: contentType}, body)
+		return
+	}
+
+	source, err := userio.NewCSVSource(body, mapping)
+	if err != nil {
+		c.Error(domain.NewProblem(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, domain.SuccessResponse{Message: "User deleted successfully"})
+	report, err := userio.NewReportWriter(c.Writer, format)
+	if err != nil {
+		c.Error(domain.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	if format == "csv" {
+		c.Header("Content-Disposition", `attachment; filename="import-report.csv"`)
+	}
+
+	summary, err := uc.UserUsecase.Import(c, source, dryRun, report.WriteRow)
+	if err != nil {
+		log.Errorf("User import stopped: %v", err)
+		err = fmt.Errorf("import stopped before the end of the file")
+	}
+
+	if err := report.Close(summary, err); err != nil {
+		log.Errorf("Failed to write import report: %v", err)
+	}
+}
+
+// importBody returns the CSV stream of an import request, either the raw body
+// or the "file" part of a multipart form, without buffering it.
+func importBody(c *gin.Context) (io.Reader, error) {
+	switch c.ContentType() {
+	case "text/csv", "application/csv":
+		return c.Request.Body, nil
+	case "multipart/form-data":
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return nil, fmt.Errorf("multipart body has no file part")
+			}
+			if part.FormName() == "file" {
+				return part, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Content-Type must be text/csv or multipart/form-data")
+	}
+}
+
+func batchResults(localizer *i18n.Localizer, items []domain.BatchItemResult, offset int) []domain.BatchResult {
+	results := make([]domain.BatchResult, len(items))
+	for i, item := range items {
+		results[i] = domain.BatchResult{Index: item.Index + offset, Status: http.StatusOK, User: item.User}
+		if item.Err != nil {
+			results[i].Status = batchItemStatus(item.Err)
+			results[i].Code, results[i].Error = batchError(localizer, item.Err)
+		}
+	}
+	return results
+}
+
+// batchError returns the code and translated message of err. Errors other
+// than domain errors are logged and reported as internal errors.
+func batchError(localizer *i18n.Localizer, err error) (string, string) {
+	problem := localizer.Problem(middleware.ProblemFor(err))
+	return problem.Code, problem.Detail
+}
+
+func batchItemStatus(err error) int {
+	if errors.Is(err, domain.ErrBatchAborted) {
+		return http.StatusFailedDependency
+	}
+	return middleware.ProblemFor(err).Status
 }
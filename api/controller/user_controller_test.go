@@ -0,0 +1,64 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockUserUsecase implements the methods used by the tests; the embedded
+// interface panics on anything else.
+type MockUserUsecase struct {
+	domain.UserUsecase
+	ValidateFunc func(ctx context.Context, id string, user *domain.User) error
+	GetByIDFunc  func(ctx context.Context, id string) (*domain.User, error)
+}
+
+func (m *MockUserUsecase) Validate(ctx context.Context, id string, user *domain.User) error {
+	return m.ValidateFunc(ctx, id, user)
+}
+
+func (m *MockUserUsecase) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	return m.GetByIDFunc(ctx, id)
+}
+
+func TestUserController_CreateMergesValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller.RegisterFieldNames()
+
+	uc := &controller.UserController{UserUsecase: &MockUserUsecase{
+		ValidateFunc: func(ctx context.Context, id string, user *domain.User) error {
+			return domain.NewFieldError(domain.ErrValidation,
+				domain.FieldError{Field: "age", Code: "gt", Message: "age must be greater than 18"},
+				domain.FieldError{Field: "email", Code: "email", Message: "email must be a valid address"},
+			)
+		},
+	}}
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"age":15}`))
+	req.Header.Set("Content-Type", "application/json")
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	uc.Create(c)
+
+	if !assert.Len(t, c.Errors, 1) {
+		t.FailNow()
+	}
+	var err *domain.Error
+	if !assert.True(t, errors.As(c.Errors.Last().Err, &err)) {
+		t.FailNow()
+	}
+	assert.ErrorIs(t, err, domain.ErrValidation)
+	assert.Equal(t, []domain.FieldError{
+		{Field: "email", Code: "required", Message: "email is required"},
+		{Field: "age", Code: "gt", Message: "age must be greater than 18"},
+	}, err.Fields)
+}
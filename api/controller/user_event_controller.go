@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+type UserEventController struct {
+	UserEventUsecase domain.UserEventUsecase
+	// PollInterval is how often the event log is checked for new events.
+	PollInterval time.Duration
+	// HeartbeatInterval is how long a stream may stay silent before a
+	// comment is sent to keep proxies from closing it.
+	HeartbeatInterval time.Duration
+	// Done ends all streams when it is closed, so that a graceful shutdown
+	// does not wait for clients to leave. Clients reconnect elsewhere.
+	Done <-chan struct{}
+}
+
+// Stream sends user changes as server-sent events named after the change,
+// with the event sequence number as the SSE id. Clients resume after the last event they
+// saw with the Last-Event-ID header, which EventSource sends when it
+// reconnects, or the last_event_id parameter; otherwise the stream starts
+// with the changes made from now on.
+func (ec *UserEventController) Stream(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	if lastEventID == "" {
+		var err error
+		lastEventID, err = ec.UserEventUsecase.LastEventID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	} else if seq, err := strconv.ParseInt(lastEventID, 10, 64); err != nil || seq < 0 {
+		c.Error(domain.NewProblem(http.StatusBadRequest, "Invalid Last-Event-ID").WithCode("invalid_last_event_id", nil))
+		return
+	}
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// A reconnecting EventSource waits for the poll interval, which is when
+	// new events could show up at the earliest.
+	if _, err := fmt.Fprintf(c.Writer, "retry: %d\n\n", ec.PollInterval.Milliseconds()); err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	poll := time.NewTicker(ec.PollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(ec.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		events, err := ec.UserEventUsecase.Since(c, lastEventID, domain.UserEventBatchSize)
+		if err != nil {
+			// The client reconnects with the last ID it received.
+			log.Errorf("User event stream stopped: %v", err)
+			return
+		}
+
+		for _, event := range events {
+			err := sse.Encode(c.Writer, sse.Event{Id: strconv.FormatInt(event.Seq, 10), Event: event.Type, Data: event})
+			if err != nil {
+				return
+			}
+			lastEventID = strconv.FormatInt(event.Seq, 10)
+		}
+		if len(events) > 0 {
+			c.Writer.Flush()
+			heartbeat.Reset(ec.HeartbeatInterval)
+		}
+		if len(events) == domain.UserEventBatchSize {
+			continue
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ec.Done:
+			return
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-poll.C:
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/i18n"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+func (uc *UserController) submitJob(c *gin.Context, job *domain.Job, input io.Reader) {
+	job.BasePath = c.GetString(middleware.ContextKeyAPIBasePath)
+	if err := uc.JobUsecase.Submit(c, job, input); err != nil {
+		c.Error(fmt.Errorf("failed to submit %s job: %w", job.Type, err))
+		return
+	}
+
+	c.Header("Location", job.BasePath+"/jobs/"+job.ID.Hex())
+	c.JSON(http.StatusAccepted, job)
+}
+
+// BatchJobResult reports the outcome of an operation of a batch job in the
+// format of synchronous batches. Job results are not negotiated, so their
+// messages are in English.
+func BatchJobResult(item domain.BatchItemResult) domain.BatchResult {
+	return batchResults(i18n.English, []domain.BatchItemResult{item}, 0)[0]
+}
@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/nebojsaj1726/user-manager/api/socket"
+)
+
+type UserSocketController struct {
+	Hub      *socket.Hub
+	Upgrader websocket.Upgrader
+}
+
+// Connect upgrades the request to a WebSocket on which the client
+// subscribes to user changes. The protocol is described by socket.Message.
+func (sc *UserSocketController) Connect(c *gin.Context) {
+	conn, err := sc.Upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade already answered with an HTTP error.
+		return
+	}
+
+	sc.Hub.Serve(conn)
+}
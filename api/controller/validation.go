@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/nebojsaj1726/user-manager/api/media"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// RegisterFieldNames makes binding errors name fields as they appear in
+// JSON rather than by their Go struct field names. It configures the shared
+// gin validator and is called once while the routes are set up.
+func RegisterFieldNames() {
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+// bindingError turns a binding failure into a validation error listing
+// every rejected field, in the format of the rules checked by the usecases.
+// Malformed bodies that cannot be attributed to a field are reported as is.
+func bindingError(err error) error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]domain.FieldError, len(validationErrors))
+		for i, fe := range validationErrors {
+			fields[i] = fieldError(fe)
+		}
+		return domain.NewFieldError(domain.ErrValidation, fields...)
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		kind := jsonType(typeError.Type)
+		article := "a"
+		if strings.ContainsRune("aeiou", rune(kind[0])) {
+			article = "an"
+		}
+		return domain.NewFieldError(domain.ErrValidation, domain.FieldError{
+			Field:   typeError.Field,
+			Code:    "type",
+			Message: fmt.Sprintf("%s must be %s %s", typeError.Field, article, kind),
+			Params:  map[string]interface{}{"type": kind},
+		})
+	}
+
+	if errors.Is(err, media.ErrUnsupported) {
+		return domain.NewProblem(http.StatusUnsupportedMediaType, "The request body cannot be read in this media type").
+			WithCode("unsupported_body_media_type", nil)
+	}
+
+	return domain.NewProblem(http.StatusBadRequest, err.Error())
+}
+
+// mergeFieldErrors adds the fields rejected by extra to the validation error
+// err, so that a client sees every problem with its input at once. Fields
+// err already reports are kept as they are. err is returned unchanged when
+// either error is not a field validation error.
+func mergeFieldErrors(err, extra error) error {
+	var base, more *domain.Error
+	if !errors.Is(err, domain.ErrValidation) || !errors.As(err, &base) ||
+		!errors.Is(extra, domain.ErrValidation) || !errors.As(extra, &more) {
+		return err
+	}
+
+	fields := append([]domain.FieldError(nil), base.Fields...)
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		seen[field.Field] = true
+	}
+	for _, field := range more.Fields {
+		if !seen[field.Field] {
+			fields = append(fields, field)
+		}
+	}
+	return domain.NewFieldError(domain.ErrValidation, fields...)
+}
+
+func fieldError(fe validator.FieldError) domain.FieldError {
+	// The namespace starts with the name of the bound struct.
+	_, field, _ := strings.Cut(fe.Namespace(), ".")
+	if field == "" {
+		field = fe.Field()
+	}
+
+	result := domain.FieldError{Field: field, Code: fe.Tag()}
+
+	switch fe.Tag() {
+	case "required":
+		result.Message = field + " is required"
+	case "email":
+		result.Message = field + " must be a valid address"
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		result.Params = map[string]interface{}{fe.Tag(): param(fe.Param())}
+		switch fe.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			result.Message = fmt.Sprintf("%s must have %s %s items", field, bound, fe.Param())
+			result.Params["unit"] = "items"
+		case reflect.String:
+			result.Message = fmt.Sprintf("%s must have %s %s characters", field, bound, fe.Param())
+			result.Params["unit"] = "characters"
+		default:
+			result.Message = fmt.Sprintf("%s must be %s %s", field, bound, fe.Param())
+		}
+	case "oneof":
+		values := strings.Fields(fe.Param())
+		result.Message = fmt.Sprintf("%s must be one of %s", field, strings.Join(values, ", "))
+		result.Params = map[string]interface{}{"values": values}
+	default:
+		result.Message = field + " is invalid"
+		if fe.Param() != "" {
+			result.Params = map[string]interface{}{fe.Tag(): param(fe.Param())}
+		}
+	}
+
+	return result
+}
+
+// param returns a numeric rule parameter as a number.
+func param(value string) interface{} {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	return value
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
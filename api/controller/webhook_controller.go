@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/media"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+type WebhookController struct {
+	WebhookUsecase domain.WebhookUsecase
+}
+
+// Create answers with the secret, which is not returned again unless it is
+// replaced.
+func (wc *WebhookController) Create(c *gin.Context) {
+	var request domain.WebhookRequest
+	if err := media.Bind(c, &request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	webhook, err := wc.WebhookUsecase.Create(c, &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Location", c.Request.URL.Path+"/"+webhook.ID.Hex())
+	c.JSON(http.StatusCreated, webhook)
+}
+
+func (wc *WebhookController) Fetch(c *gin.Context) {
+	page, limit, valid := ParsePage(c)
+	if !valid {
+		return
+	}
+
+	webhooks, total, err := wc.WebhookUsecase.Fetch(c, page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, domain.WebhookPage{Data: webhooks, Page: page, Limit: limit, Total: total})
+}
+
+func (wc *WebhookController) GetByID(c *gin.Context) {
+	objectID, valid := ValidateObjectID(c, c.Param("id"))
+	if !valid {
+		return
+	}
+
+	webhook, err := wc.WebhookUsecase.GetByID(c, objectID.Hex())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhook.Secret = ""
+	c.JSON(http.StatusOK, webhook)
+}
+
+// Update replaces a webhook. Enabling a disabled webhook resets its
+// failures.
+func (wc *WebhookController) Update(c *gin.Context) {
+	objectID, valid := ValidateObjectID(c, c.Param("id"))
+	if !valid {
+		return
+	}
+
+	var request domain.WebhookRequest
+	if err := media.Bind(c, &request); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	webhook, err := wc.WebhookUsecase.Update(c, objectID.Hex(), &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if request.Secret == "" {
+		webhook.Secret = ""
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (wc *WebhookController) Delete(c *gin.Context) {
+	objectID, valid := ValidateObjectID(c, c.Param("id"))
+	if !valid {
+		return
+	}
+
+	if err := wc.WebhookUsecase.Delete(c, objectID.Hex()); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (wc *WebhookController) Deliveries(c *gin.Context) {
+	objectID, valid := ValidateObjectID(c, c.Param("id"))
+	if !valid {
+		return
+	}
+	page, limit, valid := ParsePage(c)
+	if !valid {
+		return
+	}
+
+	deliveries, total, err := wc.WebhookUsecase.Deliveries(c, objectID.Hex(), page, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.WebhookDeliveryPage{Data: deliveries, Page: page, Limit: limit, Total: total})
+}
+
+func (wc *WebhookController) Delivery(c *gin.Context) {
+	webhookID, deliveryID, valid := deliveryIDs(c)
+	if !valid {
+		return
+	}
+
+	delivery, err := wc.WebhookUsecase.Delivery(c, webhookID, deliveryID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// Replay sends a delivery again with its original payload and Webhook-Id,
+// whatever its status.
+func (wc *WebhookController) Replay(c *gin.Context) {
+	webhookID, deliveryID, valid := deliveryIDs(c)
+	if !valid {
+		return
+	}
+
+	delivery, err := wc.WebhookUsecase.Replay(c, webhookID, deliveryID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func deliveryIDs(c *gin.Context) (string, string, bool) {
+	webhookID, valid := ValidateObjectID(c, c.Param("id"))
+	if !valid {
+		return "", "", false
+	}
+	deliveryID, valid := ValidateObjectID(c, c.Param("delivery_id"))
+	if !valid {
+		return "", "", false
+	}
+	return webhookID.Hex(), deliveryID.Hex(), true
+}
@@ -0,0 +1,34 @@
+package gql
+
+import "github.com/nebojsaj1726/user-manager/domain"
+
+const (
+	CodeBadUserInput    = "BAD_USER_INPUT"
+	CodeNotFound        = "NOT_FOUND"
+	CodeConflict        = "CONFLICT"
+	CodeTimeout         = "TIMEOUT"
+	CodeInternal        = "INTERNAL"
+	CodeQueryTooDeep    = "QUERY_TOO_DEEP"
+	CodeQueryTooComplex = "QUERY_TOO_COMPLEX"
+)
+
+// Error is reported in the errors list of the response with its code under
+// extensions, so clients do not need to parse messages. Rejected input
+// fields are listed under extensions as "fields".
+type Error struct {
+	Code    string
+	Message string
+	Fields  []domain.FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code}
+	if len(e.Fields) > 0 {
+		extensions["fields"] = e.Fields
+	}
+	return extensions
+}
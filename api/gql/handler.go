@@ -0,0 +1,90 @@
+package gql
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+type Request struct {
+	Query         string                 `json:"query" form:"query" binding:"required"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type Handler struct {
+	Schema graphql.Schema
+	Limits Limits
+}
+
+// Serve executes queries sent as JSON in a POST body, or as the query
+// parameter of a GET request. Mutations are only accepted over POST.
+func (h *Handler) Serve(c *gin.Context) {
+	var req Request
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())}})
+		return
+	}
+	if variables := c.Query("variables"); c.Request.Method == http.MethodGet && variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			c.JSON(http.StatusBadRequest, graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError("variables must be a JSON object")}})
+			return
+		}
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	validation := graphql.ValidateDocument(&h.Schema, document, nil)
+	if !validation.IsValid {
+		c.JSON(http.StatusBadRequest, graphql.Result{Errors: validation.Errors})
+		return
+	}
+
+	if err := h.Limits.check(document, req.Variables); err != nil {
+		c.JSON(http.StatusBadRequest, graphql.Result{Errors: []gqlerrors.FormattedError{{Message: err.Message, Extensions: err.Extensions()}}})
+		return
+	}
+
+	if c.Request.Method == http.MethodGet && isMutation(document, req.OperationName) {
+		c.JSON(http.StatusMethodNotAllowed, graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError("mutations require POST")}})
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.Schema,
+		AST:           document,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       c,
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+func isMutation(document *ast.Document, operationName string) bool {
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			if operation.Operation == ast.OperationTypeMutation {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package gql_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/nebojsaj1726/user-manager/api/gql"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// MockUserUsecase implements the methods used by the resolvers; the
+// embedded interface panics on anything else.
+type MockUserUsecase struct {
+	domain.UserUsecase
+	users      []domain.User
+	countCalls int
+	deleted    []int64
+}
+
+func (m *MockUserUsecase) FetchRange(ctx context.Context, filter domain.UserFilter, offset, limit int) ([]domain.User, error) {
+	start := min(offset, len(m.users))
+	end := min(start+limit, len(m.users))
+	return m.users[start:end], nil
+}
+
+func (m *MockUserUsecase) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	m.countCalls++
+	return int64(len(m.users)), nil
+}
+
+func (m *MockUserUsecase) Create(ctx context.Context, user *domain.User) error {
+	if user.Age < 18 {
+		return domain.NewValidationError("age must be greater than 18")
+	}
+	return nil
+}
+
+func (m *MockUserUsecase) Delete(ctx context.Context, id string, version int64) error {
+	m.deleted = append(m.deleted, version)
+	return nil
+}
+
+type response struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, usecase domain.UserUsecase, query string, variables map[string]interface{}) (int, response) {
+	t.Helper()
+	return executeSchema(t, usecase, false, query, variables)
+}
+
+func executeSchema(t *testing.T, usecase domain.UserUsecase, requireVersion bool, query string, variables map[string]interface{}) (int, response) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	schema, err := gql.NewSchema(usecase, requireVersion)
+	assert.NoError(t, err)
+
+	handler := &gql.Handler{Schema: schema, Limits: gql.Limits{MaxDepth: 3, MaxComplexity: 100}}
+	router := gin.New()
+	router.POST("/graphql", handler.Serve)
+
+	body, _ := json.Marshal(gql.Request{Query: query, Variables: variables})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp response
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestUsersConnection(t *testing.T) {
+	mockUsecase := &MockUserUsecase{}
+	for i := 0; i < 5; i++ {
+		mockUsecase.users = append(mockUsecase.users, domain.User{ID: primitive.NewObjectID(), Age: 20 + i, Email: "user@example.com"})
+	}
+
+	query := `query($after: String) {
+		users(first: 2, after: $after) {
+			nodes { age }
+			pageInfo { hasNextPage endCursor }
+			totalCount
+		}
+	}`
+
+	status, resp := execute(t, mockUsecase, query, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Errors)
+
+	users := resp.Data["users"].(map[string]interface{})
+	assert.Len(t, users["nodes"], 2)
+	assert.Equal(t, float64(5), users["totalCount"])
+	assert.Equal(t, 1, mockUsecase.countCalls)
+
+	pageInfo := users["pageInfo"].(map[string]interface{})
+	assert.Equal(t, true, pageInfo["hasNextPage"])
+
+	_, resp = execute(t, mockUsecase, query, map[string]interface{}{"after": pageInfo["endCursor"]})
+	nodes := resp.Data["users"].(map[string]interface{})["nodes"].([]interface{})
+	assert.Equal(t, float64(22), nodes[0].(map[string]interface{})["age"])
+}
+
+func TestLimits(t *testing.T) {
+	t.Run("depth", func(t *testing.T) {
+		status, resp := execute(t, &MockUserUsecase{}, `{ users { pageInfo { endCursor } } }`, nil)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, resp.Errors)
+
+		status, resp = execute(t, &MockUserUsecase{}, `{ users { ...edges } } fragment edges on UserConnection { edges { node { id } } }`, nil)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, gql.CodeQueryTooDeep, resp.Errors[0].Extensions["code"])
+	})
+
+	t.Run("complexity", func(t *testing.T) {
+		status, resp := execute(t, &MockUserUsecase{}, `{ users(first: 100) { nodes { id age email } } }`, nil)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, gql.CodeQueryTooComplex, resp.Errors[0].Extensions["code"])
+	})
+}
+
+func TestCreateUserError(t *testing.T) {
+	status, resp := execute(t, &MockUserUsecase{}, `mutation { createUser(input: {age: 10, email: "a@example.com"}) { id } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "age must be greater than 18", resp.Errors[0].Message)
+	assert.Equal(t, gql.CodeBadUserInput, resp.Errors[0].Extensions["code"])
+}
+
+func TestRequireVersion(t *testing.T) {
+	mockUsecase := &MockUserUsecase{}
+	id := primitive.NewObjectID().Hex()
+
+	status, resp := executeSchema(t, mockUsecase, true, `mutation($id: ID!) { deleteUser(id: $id) }`, map[string]interface{}{"id": id})
+	assert.Equal(t, http.StatusOK, status)
+	if !assert.Len(t, resp.Errors, 1) {
+		t.FailNow()
+	}
+	assert.Equal(t, gql.CodeBadUserInput, resp.Errors[0].Extensions["code"])
+	assert.Empty(t, mockUsecase.deleted)
+
+	_, resp = executeSchema(t, mockUsecase, true, `mutation($id: ID!) { deleteUser(id: $id, version: 3) }`, map[string]interface{}{"id": id})
+	assert.Empty(t, resp.Errors)
+	assert.Equal(t, []int64{3}, mockUsecase.deleted)
+
+	_, resp = execute(t, mockUsecase, `mutation($id: ID!) { deleteUser(id: $id) }`, map[string]interface{}{"id": id})
+	assert.Empty(t, resp.Errors)
+	assert.Equal(t, []int64{3, 0}, mockUsecase.deleted)
+}
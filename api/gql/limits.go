@@ -0,0 +1,108 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bound the work a single query may cause. Introspection fields are
+// not counted, their shape is fixed by the schema.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// queryCost walks one operation, expanding fragments. Every field costs 1,
+// and the selections below a paginated field are multiplied by its page size.
+type queryCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func (l Limits) check(document *ast.Document, variables map[string]interface{}) *Error {
+	cost := queryCost{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+	}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			cost.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		depth, complexity := cost.selectionSet(operation.SelectionSet)
+		if l.MaxDepth > 0 && depth > l.MaxDepth {
+			return &Error{Code: CodeQueryTooDeep, Message: fmt.Sprintf("query depth %d exceeds the limit of %d", depth, l.MaxDepth)}
+		}
+		if l.MaxComplexity > 0 && complexity > l.MaxComplexity {
+			return &Error{Code: CodeQueryTooComplex, Message: fmt.Sprintf("query complexity %d exceeds the limit of %d", complexity, l.MaxComplexity)}
+		}
+	}
+
+	return nil
+}
+
+func (q queryCost) selectionSet(set *ast.SelectionSet) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	for _, selection := range set.Selections {
+		var d, c int
+
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			d, c = q.selectionSet(selection.SelectionSet)
+			d, c = d+1, 1+c*q.multiplier(selection)
+		case *ast.InlineFragment:
+			d, c = q.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, ok := q.fragments[selection.Name.Value]; ok {
+				d, c = q.selectionSet(fragment.SelectionSet)
+			}
+		}
+
+		depth = max(depth, d)
+		complexity += c
+	}
+
+	return depth, complexity
+}
+
+// multiplier is the page size requested from a connection field.
+func (q queryCost) multiplier(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil && n > 0 {
+				return min(n, maxPageSize)
+			}
+		case *ast.Variable:
+			if n, ok := q.variables[value.Name.Value].(float64); ok && n > 0 {
+				return min(int(n), maxPageSize)
+			}
+		}
+		return defaultPageSize
+	}
+
+	if field.Name.Value == "users" {
+		return defaultPageSize
+	}
+	return 1
+}
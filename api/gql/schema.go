@@ -0,0 +1,476 @@
+// Package gql serves a GraphQL API for users, resolved through the same use
+// cases as the REST controllers.
+package gql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+	cursorPrefix    = "offset:"
+)
+
+// userConnection is resolved lazily: users are only counted when
+// totalCount or pageInfo.hasNextPage is selected.
+type userConnection struct {
+	users  []domain.User
+	offset int
+	first  int
+	filter domain.UserFilter
+
+	once     sync.Once
+	total    int64
+	countErr error
+}
+
+type resolver struct {
+	userUsecase    domain.UserUsecase
+	requireVersion bool
+}
+
+// NewSchema builds the GraphQL schema:
+//
+//	type Query {
+//	  user(id: ID!): User
+//	  users(filter: UserFilter, first: Int = 10, after: String): UserConnection!
+//	  userCount(filter: UserFilter): Int!
+//	}
+//	type Mutation {
+//	  createUser(input: UserInput!): User!
+//	  updateUser(id: ID!, input: UserInput!, version: Int): User!
+//	  deleteUser(id: ID!, version: Int): Boolean!
+//	}
+//
+// With requireVersion, updateUser and deleteUser reject a missing version as
+// the REST API rejects writes without If-Match.
+func NewSchema(userUsecase domain.UserUsecase, requireVersion bool) (graphql.Schema, error) {
+	r := &resolver{userUsecase: userUsecase, requireVersion: requireVersion}
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(domain.User).ID.Hex(), nil
+				},
+			},
+			"age": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(domain.User).Age, nil
+				},
+			},
+			"email": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(domain.User).Email, nil
+				},
+			},
+			"version": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Incremented on every write; pass it back to update or delete conditionally.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return int(p.Source.(domain.User).Version), nil
+				},
+			},
+		},
+	})
+
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(userType)},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Boolean),
+				Resolve: r.hasNextPage,
+			},
+			"hasPreviousPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*userConnection).offset > 0, nil
+				},
+			},
+			"startCursor": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					connection := p.Source.(*userConnection)
+					if len(connection.users) == 0 {
+						return nil, nil
+					}
+					return encodeCursor(connection.offset), nil
+				},
+			},
+			"endCursor": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					connection := p.Source.(*userConnection)
+					if len(connection.users) == 0 {
+						return nil, nil
+					}
+					return encodeCursor(connection.offset + len(connection.users) - 1), nil
+				},
+			},
+		},
+	})
+
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edgeType))),
+				Resolve: r.edges,
+			},
+			"nodes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*userConnection).users, nil
+				},
+			},
+			"pageInfo": &graphql.Field{
+				Type: graphql.NewNonNull(pageInfoType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+			"totalCount": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Int),
+				Resolve: r.totalCount,
+			},
+		},
+	})
+
+	filterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UserFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"email":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"minAge": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"maxAge": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	})
+
+	inputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UserInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"age":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"email": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: r.user,
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(connectionType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: filterType},
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+					"after":  &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: r.users,
+			},
+			"userCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: filterType},
+				},
+				Resolve: r.userCount,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(inputType)},
+				},
+				Resolve: r.createUser,
+			},
+			"updateUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(inputType)},
+					"version": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: r.updateUser,
+			},
+			"deleteUser": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"version": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: r.deleteUser,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func (r *resolver) user(p graphql.ResolveParams) (interface{}, error) {
+	id, err := objectID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.userUsecase.GetByID(p.Context, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolverError(err)
+	}
+
+	return *user, nil
+}
+
+func (r *resolver) users(p graphql.ResolveParams) (interface{}, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 1 || first > maxPageSize {
+		return nil, &Error{Code: CodeBadUserInput, Message: fmt.Sprintf("first must be between 1 and %d", maxPageSize)}
+	}
+
+	offset := 0
+	if after, ok := p.Args["after"].(string); ok {
+		position, err := decodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		offset = position + 1
+	}
+
+	filter := userFilter(p.Args["filter"])
+
+	users, err := r.userUsecase.FetchRange(p.Context, filter, offset, first)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+
+	return &userConnection{users: users, offset: offset, first: first, filter: filter}, nil
+}
+
+func (r *resolver) edges(p graphql.ResolveParams) (interface{}, error) {
+	connection := p.Source.(*userConnection)
+
+	edges := make([]map[string]interface{}, len(connection.users))
+	for i, user := range connection.users {
+		edges[i] = map[string]interface{}{
+			"cursor": encodeCursor(connection.offset + i),
+			"node":   user,
+		}
+	}
+
+	return edges, nil
+}
+
+func (r *resolver) hasNextPage(p graphql.ResolveParams) (interface{}, error) {
+	connection := p.Source.(*userConnection)
+	if len(connection.users) < connection.first {
+		return false, nil
+	}
+
+	total, err := r.count(p.Context, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return int64(connection.offset+len(connection.users)) < total, nil
+}
+
+func (r *resolver) totalCount(p graphql.ResolveParams) (interface{}, error) {
+	count, err := r.count(p.Context, p.Source.(*userConnection))
+	if err != nil {
+		return nil, err
+	}
+	return int(count), nil
+}
+
+// count is shared by totalCount and hasNextPage; the result is kept on the
+// connection so a query selecting both counts once.
+func (r *resolver) count(ctx context.Context, connection *userConnection) (int64, error) {
+	connection.once.Do(func() {
+		count, err := r.userUsecase.Count(ctx, connection.filter)
+		if err != nil {
+			connection.countErr = resolverError(err)
+			return
+		}
+		connection.total = count
+	})
+	return connection.total, connection.countErr
+}
+
+func (r *resolver) userCount(p graphql.ResolveParams) (interface{}, error) {
+	count, err := r.userUsecase.Count(p.Context, userFilter(p.Args["filter"]))
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return int(count), nil
+}
+
+func (r *resolver) createUser(p graphql.ResolveParams) (interface{}, error) {
+	user := userInput(p.Args["input"])
+	user.ID = primitive.NewObjectID()
+
+	if err := r.userUsecase.Create(p.Context, user); err != nil {
+		return nil, resolverError(err)
+	}
+
+	return *user, nil
+}
+
+func (r *resolver) updateUser(p graphql.ResolveParams) (interface{}, error) {
+	id, err := objectID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	user := userInput(p.Args["input"])
+	user.Version, err = r.version(p.Args["version"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.userUsecase.Update(p.Context, id, user); err != nil {
+		return nil, resolverError(err)
+	}
+
+	updated, err := r.userUsecase.GetByID(p.Context, id)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+
+	return *updated, nil
+}
+
+func (r *resolver) deleteUser(p graphql.ResolveParams) (interface{}, error) {
+	id, err := objectID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := r.version(p.Args["version"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.userUsecase.Delete(p.Context, id, version); err != nil {
+		return nil, resolverError(err)
+	}
+
+	return true, nil
+}
+
+func objectID(arg interface{}) (string, error) {
+	id, _ := arg.(string)
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return "", &Error{Code: CodeBadUserInput, Message: "invalid ID"}
+	}
+	return id, nil
+}
+
+// version reads the version argument of a write, where 0 means any version.
+func (r *resolver) version(arg interface{}) (int64, error) {
+	v, _ := arg.(int)
+	if v == 0 && r.requireVersion {
+		return 0, &Error{
+			Code:    CodeBadUserInput,
+			Message: "version is required",
+			Fields:  []domain.FieldError{{Field: "version", Code: "required", Message: "version is required"}},
+		}
+	}
+	return int64(v), nil
+}
+
+func userFilter(arg interface{}) domain.UserFilter {
+	var filter domain.UserFilter
+
+	fields, _ := arg.(map[string]interface{})
+	filter.Email, _ = fields["email"].(string)
+	filter.MinAge, _ = fields["minAge"].(int)
+	filter.MaxAge, _ = fields["maxAge"].(int)
+
+	return filter
+}
+
+func userInput(arg interface{}) *domain.User {
+	fields, _ := arg.(map[string]interface{})
+
+	user := &domain.User{}
+	user.Age, _ = fields["age"].(int)
+	user.Email, _ = fields["email"].(string)
+
+	return user
+}
+
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(raw), cursorPrefix) {
+		if offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix)); err == nil && offset >= 0 {
+			return offset, nil
+		}
+	}
+	return 0, &Error{Code: CodeBadUserInput, Message: "invalid cursor"}
+}
+
+// resolverError classifies use case errors the same way UserController
+// does, without leaking unexpected errors to clients.
+func resolverError(err error) error {
+	message := err.Error()
+
+	var fields []domain.FieldError
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		fields = domainErr.Fields
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return &Error{Code: CodeNotFound, Message: message}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeTimeout, Message: message}
+	case errors.Is(err, domain.ErrConflict):
+		return &Error{Code: CodeConflict, Message: message, Fields: fields}
+	case errors.Is(err, domain.ErrValidation):
+		return &Error{Code: CodeBadUserInput, Message: message, Fields: fields}
+	default:
+		log.Errorf("GraphQL resolver failed: %v", err)
+		return &Error{Code: CodeInternal, Message: "something went wrong"}
+	}
+}
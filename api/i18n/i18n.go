@@ -0,0 +1,166 @@
+// Package i18n translates the messages of API responses into the language
+// negotiated from Accept-Language. English is the source language: messages
+// are written in English where they are created and catalogs, keyed by the
+// stable codes that accompany them, hold their translations.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+const contextKeyLocalizer = "i18n.localizer"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Catalog holds the translations of every supported language but English.
+type Catalog struct {
+	languages []language.Tag
+	messages  map[language.Tag]map[string]string
+	matcher   language.Matcher
+}
+
+// Default returns the catalog of the translations shipped with the API.
+func Default() *Catalog {
+	catalog, err := load()
+	if err != nil {
+		panic(err)
+	}
+	return catalog
+}
+
+func load() (*Catalog, error) {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{
+		languages: []language.Tag{language.English},
+		messages:  map[language.Tag]map[string]string{language.English: {}},
+	}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(file.Name(), path.Ext(file.Name())))
+		if err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", file.Name(), err)
+		}
+
+		data, err := locales.ReadFile("locales/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", file.Name(), err)
+		}
+
+		catalog.languages = append(catalog.languages, tag)
+		catalog.messages[tag] = messages
+	}
+	catalog.matcher = language.NewMatcher(catalog.languages)
+
+	return catalog, nil
+}
+
+func (c *Catalog) Languages() []language.Tag {
+	return c.languages
+}
+
+// Keys returns the keys translated for tag.
+func (c *Catalog) Keys(tag language.Tag) []string {
+	keys := make([]string, 0, len(c.messages[tag]))
+	for key := range c.messages[tag] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Localizer returns the localizer of the supported language that best
+// matches an Accept-Language header. Regional variants fall back to their
+// language, e.g. de-AT to de, and anything unsupported to English.
+func (c *Catalog) Localizer(acceptLanguage string) *Localizer {
+	preferred, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, confidence := c.matcher.Match(preferred...)
+	if confidence == language.No {
+		index = 0
+	}
+
+	tag := c.languages[index]
+	return &Localizer{language: tag, messages: c.messages[tag]}
+}
+
+// Negotiate makes the localizer matching the Accept-Language of the request
+// available to handlers through From and announces its language in the
+// Content-Language header.
+func Negotiate(catalog *Catalog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		localizer := catalog.Localizer(c.GetHeader("Accept-Language"))
+
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Header("Content-Language", localizer.language.String())
+		c.Set(contextKeyLocalizer, localizer)
+		c.Next()
+	}
+}
+
+// English leaves messages in English, for work done outside of requests.
+var English = &Localizer{language: language.English}
+
+// From returns the localizer of a request, or English outside of Negotiate.
+func From(c *gin.Context) *Localizer {
+	if localizer, ok := c.Value(contextKeyLocalizer).(*Localizer); ok {
+		return localizer
+	}
+	return English
+}
+
+// Localizer translates messages into one language.
+type Localizer struct {
+	language language.Tag
+	messages map[string]string
+}
+
+func (l *Localizer) Language() language.Tag {
+	return l.language
+}
+
+// Text returns the translation of key with its {name} placeholders replaced
+// by params, or message, the English original, when there is none.
+func (l *Localizer) Text(key, message string, params map[string]interface{}) string {
+	translation, ok := l.lookup(key)
+	if !ok {
+		return message
+	}
+	return format(translation, params)
+}
+
+func (l *Localizer) lookup(key string) (string, bool) {
+	translation, ok := l.messages[key]
+	return translation, ok && translation != ""
+}
+
+func format(message string, params map[string]interface{}) string {
+	if len(params) == 0 {
+		return message
+	}
+
+	replacements := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		replacements = append(replacements, "{"+name+"}", formatValue(value))
+	}
+	return strings.NewReplacer(replacements...).Replace(message)
+}
+
+func formatValue(value interface{}) string {
+	if values, ok := value.([]string); ok {
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,247 @@
+package i18n_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/i18n"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestCatalog_Localizer(t *testing.T) {
+	catalog := i18n.Default()
+
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-AT,de;q=0.9", "de"},
+		{"fr-CA", "fr"},
+		{"ja, es;q=0.5", "es"},
+		{"en-GB, de;q=0.8", "en"},
+		{"it", "en"},
+		{"not a language", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			assert.Equal(t, tt.want, catalog.Localizer(tt.acceptLanguage).Language().String())
+		})
+	}
+}
+
+// Every translation covers the same keys with the same placeholders, so a
+// message added to one catalog is not silently missing from the others.
+func TestCatalog_Consistent(t *testing.T) {
+	catalog := i18n.Default()
+	placeholder := regexp.MustCompile(`\{\w+\}`)
+
+	languages := catalog.Languages()[1:]
+	if !assert.NotEmpty(t, languages) {
+		t.FailNow()
+	}
+
+	reference := catalog.Keys(languages[0])
+	sort.Strings(reference)
+	referenceLocalizer := catalog.Localizer(languages[0].String())
+
+	for _, tag := range languages[1:] {
+		keys := catalog.Keys(tag)
+		sort.Strings(keys)
+		assert.Equal(t, reference, keys, tag.String())
+
+		localizer := catalog.Localizer(tag.String())
+		for _, key := range reference {
+			want := placeholder.FindAllString(referenceLocalizer.Text(key, "", nil), -1)
+			got := placeholder.FindAllString(localizer.Text(key, "", nil), -1)
+			sort.Strings(want)
+			sort.Strings(got)
+			assert.Equal(t, want, got, "%s: %s", tag, key)
+		}
+	}
+}
+
+func TestLocalizer_Problem(t *testing.T) {
+	german := i18n.Default().Localizer("de")
+
+	t.Run("coded", func(t *testing.T) {
+		problem := domain.NewProblem(http.StatusRequestEntityTooLarge, "A batch can contain at most 500 operations").
+			WithCode("batch_too_large", map[string]interface{}{"max": 500})
+
+		localized := german.Problem(problem)
+		assert.Equal(t, "Inhalt zu groß", localized.Title)
+		assert.Equal(t, "Ein Stapel darf höchstens 500 Operationen enthalten", localized.Detail)
+		assert.Equal(t, "batch_too_large", localized.Code)
+		assert.Equal(t, "A batch can contain at most 500 operations", problem.Detail)
+	})
+
+	t.Run("field errors", func(t *testing.T) {
+		fields := []domain.FieldError{
+			{Field: "age", Code: "gt", Message: "age must be greater than 18", Params: map[string]interface{}{"gt": 18}},
+			{Field: "events", Code: "min", Message: "events must have at least 1 items", Params: map[string]interface{}{"min": 1, "unit": "items"}},
+			// Rules without a translation fall back to field.invalid.
+			{Field: "url", Code: "hostname", Message: "url is invalid"},
+		}
+		problem := domain.NewProblem(http.StatusBadRequest, "age must be greater than 18; events must have at least 1 items; url is invalid")
+		problem.Errors = fields
+
+		localized := german.Problem(problem)
+		assert.Equal(t, "age muss größer als 18 sein; events muss mindestens 1 Einträge haben; url ist ungültig", localized.Detail)
+		assert.Equal(t, "gt", localized.Errors[0].Code)
+		assert.Equal(t, "age must be greater than 18", fields[0].Message)
+	})
+
+	t.Run("generic code", func(t *testing.T) {
+		problem := domain.NewProblem(http.StatusBadRequest, "invalid patch: no operations").WithCode("validation_failed", nil)
+
+		localized := german.Problem(problem)
+		assert.Equal(t, "Ungültige Anfrage", localized.Title)
+		assert.Equal(t, "Die Anfrage ist ungültig", localized.Detail)
+		assert.Equal(t, "invalid patch: no operations", problem.Detail)
+	})
+
+	t.Run("english", func(t *testing.T) {
+		problem := domain.NewProblem(http.StatusNotFound, "user not found").WithCode("user_not_found", nil)
+		assert.Equal(t, problem, i18n.English.Problem(problem))
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(i18n.Negotiate(i18n.Default()))
+	router.GET("/users", func(c *gin.Context) {
+		c.String(http.StatusOK, i18n.From(c).Text("user.created", "User created successfully", nil))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set("Accept-Language", "es-MX,es;q=0.9,en;q=0.8")
+	router.ServeHTTP(w, r)
+
+	assert.Equal(t, "Usuario creado correctamente", w.Body.String())
+	assert.Equal(t, "es", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, "User created successfully", w.Body.String())
+	assert.Equal(t, language.English.String(), w.Header().Get("Content-Language"))
+}
+
+// Every problem and field code the module emits has a translation. Codes
+// are collected from the source: WithCode and NewCodedError calls, Error and
+// FieldError literals, and the validation rules of binding tags.
+func TestCatalog_CoversCodes(t *testing.T) {
+	catalog := i18n.Default()
+	keys := emittedKeys(t, filepath.Join("..", ".."))
+	if !assert.Contains(t, keys, "error.not_representable") || !assert.Contains(t, keys, "field.url") {
+		t.FailNow()
+	}
+
+	for _, tag := range catalog.Languages()[1:] {
+		translated := catalog.Keys(tag)
+		for _, key := range keys {
+			assert.Contains(t, translated, key, "%s: %s", tag, key)
+		}
+	}
+}
+
+func emittedKeys(t *testing.T, root string) []string {
+	found := make(map[string]bool)
+	add := func(prefix string, expr ast.Expr) {
+		if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			if code, err := strconv.Unquote(lit.Value); err == nil && code != "" {
+				found[prefix+code] = true
+			}
+		}
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == "web" || strings.HasPrefix(d.Name(), ".")) && path != root {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.CallExpr:
+				switch name := calledName(node.Fun); {
+				case name == "WithCode" && len(node.Args) > 0:
+					add("error.", node.Args[0])
+				case name == "NewCodedError" && len(node.Args) > 1:
+					add("error.", node.Args[1])
+				}
+			case *ast.CompositeLit:
+				prefix := map[string]string{"Error": "error.", "FieldError": "field."}[calledName(node.Type)]
+				for _, elt := range node.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok && prefix != "" {
+						if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Code" {
+							add(prefix, kv.Value)
+						}
+					}
+				}
+			case *ast.Field:
+				if node.Tag == nil {
+					return true
+				}
+				tag, _ := strconv.Unquote(node.Tag.Value)
+				for _, rule := range strings.Split(reflect.StructTag(tag).Get("binding"), ",") {
+					name, _, _ := strings.Cut(rule, "=")
+					if name != "" && name != "-" && name != "dive" && name != "omitempty" {
+						found["field."+name] = true
+					}
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// calledName is the name of a function or type, without its package.
+func calledName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package i18n
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// Problem returns a copy of problem with its title, detail and field errors
+// translated. Codes and params are kept, so clients can still tell problems
+// apart whatever their language.
+func (l *Localizer) Problem(problem *domain.Problem) *domain.Problem {
+	localized := *problem
+	localized.Title = l.Text("status."+strconv.Itoa(problem.Status), problem.Title, nil)
+
+	if len(problem.Errors) > 0 {
+		localized.Errors = make([]domain.FieldError, len(problem.Errors))
+		messages := make([]string, len(problem.Errors))
+		for i, fieldError := range problem.Errors {
+			localized.Errors[i] = l.FieldError(fieldError)
+			messages[i] = localized.Errors[i].Message
+		}
+		// A detail made of the field messages is made of the translated ones.
+		if problem.Detail == joinMessages(problem.Errors) {
+			localized.Detail = strings.Join(messages, "; ")
+		}
+	}
+
+	if problem.Code != "" {
+		localized.Detail = l.Text("error."+problem.Code, localized.Detail, problem.Params)
+	}
+
+	return &localized
+}
+
+// FieldError translates the message of a rejected field. Rules checked on
+// strings and lists name their unit, e.g. "min.items", for languages that
+// phrase them differently; rules without a translation of their own are
+// reported as invalid.
+func (l *Localizer) FieldError(fieldError domain.FieldError) domain.FieldError {
+	key := "field." + fieldError.Code
+	if unit, ok := fieldError.Params["unit"].(string); ok {
+		key += "." + unit
+	}
+	if _, ok := l.lookup(key); !ok {
+		key = "field.invalid"
+	}
+
+	params := map[string]interface{}{"field": fieldError.Field}
+	for name, value := range fieldError.Params {
+		params[name] = value
+	}
+
+	fieldError.Message = l.Text(key, fieldError.Message, params)
+	return fieldError
+}
+
+func joinMessages(fields []domain.FieldError) string {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,165 @@
+package media
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+
+	userv1 "github.com/nebojsaj1726/user-manager/api/proto/user/v1"
+	"github.com/nebojsaj1726/user-manager/api/protoconv"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+type JSON struct{}
+
+func (JSON) MediaType() string { return TypeJSON }
+
+func (JSON) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (JSON) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// MsgPack encodes values through their JSON form, so field names and
+// omitted fields are the same as in JSON without a second set of tags.
+type MsgPack struct{}
+
+func (MsgPack) MediaType() string { return TypeMsgPack }
+
+func (MsgPack) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	err = codec.NewEncoderBytes(&out, msgpackHandle()).Encode(fromJSONNumbers(document))
+	return out, err
+}
+
+func (MsgPack) Unmarshal(data []byte, v interface{}) error {
+	var document interface{}
+	if err := codec.NewDecoderBytes(data, msgpackHandle()).Decode(&document); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func msgpackHandle() *codec.MsgpackHandle {
+	handle := &codec.MsgpackHandle{}
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	handle.RawToString = true
+	handle.WriteExt = true
+	return handle
+}
+
+// fromJSONNumbers replaces json.Number with integers where possible so they
+// are not encoded as strings or widened to floats.
+func fromJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = fromJSONNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = fromJSONNumbers(item)
+		}
+	}
+	return value
+}
+
+// XML names the root element after the Go type, for example <userPage>.
+type XML struct{}
+
+func (XML) MediaType() string { return TypeXML }
+
+func (XML) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	root := xml.StartElement{Name: xml.Name{Local: rootElement(v)}}
+	if err := xml.NewEncoder(&buf).EncodeElement(v, root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (XML) Unmarshal(data []byte, v interface{}) error { return xml.Unmarshal(data, v) }
+
+func rootElement(v interface{}) string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	name := t.Name()
+	if name == "" {
+		return "response"
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(first)) + name[size:]
+}
+
+// Protobuf uses the messages of the gRPC API. Only users have one; other
+// values are ErrUnsupported. That includes user lists, whose message has no
+// room for the links of the REST API.
+type Protobuf struct{}
+
+func (Protobuf) MediaType() string { return TypeProtobuf }
+
+func (Protobuf) Marshal(v interface{}) ([]byte, error) {
+	var message proto.Message
+	switch v := v.(type) {
+	case proto.Message:
+		message = v
+	case domain.User:
+		message = protoconv.UserToProto(&v)
+	case *domain.User:
+		message = protoconv.UserToProto(v)
+	case domain.UserResponse:
+		message = protoconv.UserToProto(v.User)
+	default:
+		return nil, ErrUnsupported
+	}
+	return proto.Marshal(message)
+}
+
+func (Protobuf) Unmarshal(data []byte, v interface{}) error {
+	switch v := v.(type) {
+	case proto.Message:
+		return proto.Unmarshal(data, v)
+	case *domain.User:
+		var message userv1.User
+		if err := proto.Unmarshal(data, &message); err != nil {
+			return err
+		}
+		*v = *protoconv.UserFromProto(&message)
+		return nil
+	default:
+		return ErrUnsupported
+	}
+}
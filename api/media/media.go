@@ -0,0 +1,256 @@
+// Package media negotiates the representation of request and response
+// bodies. Handlers render and bind through the codec registry instead of
+// calling a format directly, so new formats only need a Codec.
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const (
+	TypeJSON     = "application/json"
+	TypeMsgPack  = "application/msgpack"
+	TypeXML      = "application/xml"
+	TypeProtobuf = "application/x-protobuf"
+
+	contextKeyCodec    = "media.codec"
+	contextKeyRegistry = "media.registry"
+)
+
+// ErrUnsupported is returned by codecs for values that have no
+// representation in their format.
+var ErrUnsupported = errors.New("value cannot be represented in this media type")
+
+type Codec interface {
+	MediaType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Registry holds the codecs a route group speaks. The first codec is the
+// default for clients that accept anything.
+type Registry struct {
+	codecs []Codec
+}
+
+func NewRegistry(codecs ...Codec) *Registry {
+	return &Registry{codecs: codecs}
+}
+
+// Default returns a registry of JSON, MessagePack, XML and protobuf.
+func Default() *Registry {
+	return NewRegistry(JSON{}, MsgPack{}, XML{}, Protobuf{})
+}
+
+func (r *Registry) Register(codec Codec) {
+	r.codecs = append(r.codecs, codec)
+}
+
+func (r *Registry) MediaTypes() []string {
+	types := make([]string, len(r.codecs))
+	for i, codec := range r.codecs {
+		types[i] = codec.MediaType()
+	}
+	return types
+}
+
+// ForContentType returns the codec of a request body, or nil.
+func (r *Registry) ForContentType(contentType string) Codec {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	for _, codec := range r.codecs {
+		if codec.MediaType() == mediaType {
+			return codec
+		}
+	}
+	return nil
+}
+
+// ForAccept returns the preferred codec acceptable to an Accept header, or
+// nil when none is. An empty header accepts anything.
+func (r *Registry) ForAccept(accept string) Codec {
+	if codecs := r.accepted(accept); len(codecs) > 0 {
+		return codecs[0]
+	}
+	return nil
+}
+
+// accepted returns the codecs acceptable to an Accept header, most preferred
+// first.
+func (r *Registry) accepted(accept string) []Codec {
+	if strings.TrimSpace(accept) == "" {
+		return r.codecs
+	}
+
+	var codecs []Codec
+	seen := make(map[Codec]bool)
+	for _, mediaRange := range parseAccept(accept) {
+		for _, codec := range r.codecs {
+			if !seen[codec] && matches(mediaRange, codec.MediaType()) {
+				seen[codec] = true
+				codecs = append(codecs, codec)
+			}
+		}
+	}
+	return codecs
+}
+
+// Negotiate selects the response codec from the Accept header and answers
+// 406 Not Acceptable before the handler runs when there is none.
+func Negotiate(registry *Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept")
+
+		codec := registry.ForAccept(c.GetHeader("Accept"))
+		if codec == nil {
+			c.Error(domain.NewProblem(http.StatusNotAcceptable,
+				fmt.Sprintf("None of %q can be produced; supported media types are %s", c.GetHeader("Accept"), strings.Join(registry.MediaTypes(), ", "))).
+				WithCode("not_acceptable", map[string]interface{}{"types": registry.MediaTypes()}))
+			c.Abort()
+			return
+		}
+
+		c.Set(contextKeyRegistry, registry)
+		c.Set(contextKeyCodec, codec)
+		c.Next()
+	}
+}
+
+// Render writes v in the negotiated representation, or as JSON outside of
+// Negotiate. A value the negotiated codec cannot represent is written with
+// the next codec the Accept header allows, and answered with 406 Not
+// Acceptable when there is none.
+func Render(c *gin.Context, status int, v interface{}) {
+	codec, ok := c.Value(contextKeyCodec).(Codec)
+	if !ok {
+		codec = JSON{}
+	}
+
+	data, err := codec.Marshal(v)
+	if errors.Is(err, ErrUnsupported) {
+		if registry, ok := c.Value(contextKeyRegistry).(*Registry); ok {
+			for _, next := range registry.accepted(c.GetHeader("Accept")) {
+				if data, err = next.Marshal(v); !errors.Is(err, ErrUnsupported) {
+					codec = next
+					break
+				}
+			}
+		}
+	}
+	if errors.Is(err, ErrUnsupported) {
+		types := representable(c, v)
+		c.Error(domain.NewProblem(http.StatusNotAcceptable,
+			fmt.Sprintf("This response cannot be produced as %s; supported media types are %s", codec.MediaType(), strings.Join(types, ", "))).
+			WithCode("not_representable", map[string]interface{}{"type": codec.MediaType(), "types": types}))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Data(status, codec.MediaType(), data)
+}
+
+// representable returns the media types of the registry that can represent
+// v.
+func representable(c *gin.Context, v interface{}) []string {
+	registry, ok := c.Value(contextKeyRegistry).(*Registry)
+	if !ok {
+		return []string{TypeJSON}
+	}
+
+	var types []string
+	for _, codec := range registry.codecs {
+		if _, err := codec.Marshal(v); !errors.Is(err, ErrUnsupported) {
+			types = append(types, codec.MediaType())
+		}
+	}
+	return types
+}
+
+// Bind decodes the request body with the codec of its Content-Type and
+// validates it like gin's binding. A body without a Content-Type is read with
+// the default codec and forms are left to c.ShouldBind. Other content types
+// are ErrUnsupported.
+func Bind(c *gin.Context, v interface{}) error {
+	registry, ok := c.Value(contextKeyRegistry).(*Registry)
+	if !ok {
+		registry = Default()
+	}
+
+	var codec Codec
+	switch contentType := c.ContentType(); contentType {
+	case "":
+		codec = registry.codecs[0]
+	case binding.MIMEPOSTForm, binding.MIMEMultipartPOSTForm:
+		return c.ShouldBind(v)
+	default:
+		if codec = registry.ForContentType(contentType); codec == nil {
+			return ErrUnsupported
+		}
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	if err := codec.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	return binding.Validator.ValidateStruct(v)
+}
+
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
+
+func matches(r mediaRange, mediaType string) bool {
+	if r.mediaType == "*/*" || r.mediaType == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(r.mediaType, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
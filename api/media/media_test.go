@@ -0,0 +1,176 @@
+package media_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/media"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestForAccept(t *testing.T) {
+	registry := media.Default()
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", media.TypeJSON},
+		{"*/*", media.TypeJSON},
+		{"application/xml", media.TypeXML},
+		{"application/msgpack, application/json;q=0.5", media.TypeMsgPack},
+		{"application/json;q=0.5, application/x-protobuf", media.TypeProtobuf},
+		{"text/html, application/*;q=0.8", media.TypeJSON},
+		{"application/xml;q=0, */*;q=0.1", media.TypeJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			codec := registry.ForAccept(tt.accept)
+			if assert.NotNil(t, codec) {
+				assert.Equal(t, tt.want, codec.MediaType())
+			}
+		})
+	}
+
+	assert.Nil(t, registry.ForAccept("text/html"))
+	assert.Nil(t, registry.ForAccept("application/xml;q=0"))
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	user := domain.User{ID: primitive.NewObjectID(), Age: 30, Email: "jane@example.com", Version: 3}
+
+	for _, codec := range []media.Codec{media.JSON{}, media.MsgPack{}, media.XML{}} {
+		t.Run(codec.MediaType(), func(t *testing.T) {
+			item := domain.UserItem{User: user, Links: &domain.Links{Self: "http://localhost/v2/users/" + user.ID.Hex()}}
+			data, err := codec.Marshal(domain.UserPage{Data: []domain.UserItem{item}, Page: 2, Limit: 10, Total: 11})
+			assert.NoError(t, err)
+
+			var page domain.UserPage
+			assert.NoError(t, codec.Unmarshal(data, &page))
+			assert.Equal(t, []domain.UserItem{item}, page.Data)
+			assert.Equal(t, 2, page.Page)
+			assert.Equal(t, int64(11), page.Total)
+		})
+	}
+
+	t.Run(media.TypeProtobuf, func(t *testing.T) {
+		codec := media.Protobuf{}
+
+		data, err := codec.Marshal(&user)
+		assert.NoError(t, err)
+
+		var decoded domain.User
+		assert.NoError(t, codec.Unmarshal(data, &decoded))
+		assert.Equal(t, user.Email, decoded.Email)
+		assert.Equal(t, user.Age, decoded.Age)
+		assert.Equal(t, user.Version, decoded.Version)
+
+		_, err = codec.Marshal(domain.BatchResponse{})
+		assert.ErrorIs(t, err, media.ErrUnsupported)
+
+		// The list message cannot carry links.
+		_, err = codec.Marshal(domain.UserPage{Data: []domain.UserItem{{User: user}}})
+		assert.ErrorIs(t, err, media.ErrUnsupported)
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.Errors(), media.Negotiate(media.Default()))
+	router.POST("/users", func(c *gin.Context) {
+		var user domain.User
+		if err := media.Bind(c, &user); err != nil {
+			c.Error(domain.NewProblem(http.StatusBadRequest, err.Error()))
+			return
+		}
+		media.Render(c, http.StatusCreated, domain.UserResponse{Message: "created", User: &user})
+	})
+	router.GET("/batch", func(c *gin.Context) {
+		media.Render(c, http.StatusOK, domain.BatchResponse{Results: []domain.BatchResult{}})
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("xml in, msgpack out", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`<user><age>30</age><email>jane@example.com</email></user>`))
+		req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+		req.Header.Set("Accept", media.TypeMsgPack)
+
+		w := serve(req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, media.TypeMsgPack, w.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+		var response domain.UserResponse
+		assert.NoError(t, media.MsgPack{}.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "jane@example.com", response.User.Email)
+		assert.Equal(t, 30, response.User.Age)
+	})
+
+	t.Run("binding validation", func(t *testing.T) {
+		body, _ := media.XML{}.Marshal(domain.User{Age: 30})
+		req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(body))
+		req.Header.Set("Content-Type", media.TypeXML)
+
+		w := serve(req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+		req.Header.Set("Accept", "text/html")
+
+		w := serve(req)
+
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+		assert.Equal(t, domain.ContentTypeProblem, w.Header().Get("Content-Type"))
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`email: jane@example.com`))
+		req.Header.Set("Content-Type", "application/yaml")
+
+		w := serve(req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), media.ErrUnsupported.Error())
+	})
+
+	t.Run("next acceptable codec", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/batch", nil)
+		req.Header.Set("Accept", media.TypeProtobuf+", "+media.TypeJSON+";q=0.5")
+
+		w := serve(req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, media.TypeJSON, w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"results":[]}`, w.Body.String())
+	})
+
+	t.Run("not representable", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/batch", nil)
+		req.Header.Set("Accept", media.TypeProtobuf)
+
+		w := serve(req)
+
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+		assert.Equal(t, domain.ContentTypeProblem, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `"code":"not_representable"`)
+	})
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// BearerToken admits requests carrying one of tokens in the Authorization
+// header. It guards the WebSocket and event streams. Clients such as browser EventSources and WebSockets that cannot
+// set headers instead redeem a ticket, passed in the ticket query parameter,
+// when tickets is not nil. Tokens are never read from the URL, where they
+// would end up in access logs. With no tokens configured every request
+// without a ticket is refused.
+func BearerToken(tokens []string, tickets domain.TicketUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		valid := false
+		if scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			valid = validToken(strings.TrimSpace(token), tokens)
+		} else if ticket := c.Query("ticket"); ticket != "" && tickets != nil {
+			var err error
+			valid, err = tickets.Redeem(c, ticket)
+			if err != nil {
+				AbortWithProblem(c, ProblemFor(err))
+				return
+			}
+		}
+
+		if !valid {
+			c.Header("WWW-Authenticate", `Bearer realm="user-manager"`)
+			AbortWithProblem(c, domain.NewProblem(http.StatusUnauthorized, "Missing or invalid access token").WithCode("invalid_token", nil))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// validToken reports whether token is one of tokens.
+func validToken(token string, tokens []string) bool {
+	valid := false
+	for _, candidate := range tokens {
+		// Every token is compared so the time taken does not tell which
+		// one was close.
+		if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(candidate)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
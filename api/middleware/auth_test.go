@@ -0,0 +1,75 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockTicketUsecase accepts each of its tickets once.
+type MockTicketUsecase struct {
+	domain.TicketUsecase
+	tickets map[string]bool
+}
+
+func (m *MockTicketUsecase) Redeem(ctx context.Context, ticket string) (bool, error) {
+	valid := m.tickets[ticket]
+	delete(m.tickets, ticket)
+	return valid, nil
+}
+
+func TestBearerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tickets := &MockTicketUsecase{tickets: map[string]bool{"once": true, "other": true}}
+
+	router := gin.New()
+	router.GET("/ws", middleware.BearerToken([]string{"first", "second"}, tickets), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/users", middleware.BearerToken([]string{"first"}, nil), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/closed", middleware.BearerToken(nil, nil), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(target, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/ws", "Bearer second").Code)
+	assert.Equal(t, http.StatusOK, serve("/ws", "bearer first").Code)
+	assert.Equal(t, http.StatusOK, serve("/users", "Bearer first").Code)
+	assert.Equal(t, http.StatusOK, serve("/ws?ticket=once", "").Code)
+
+	t.Run("rejected", func(t *testing.T) {
+		for _, w := range []*httptest.ResponseRecorder{
+			serve("/ws", ""),
+			serve("/ws", "Bearer third"),
+			serve("/ws", "Basic Zmlyc3Q6"),
+			serve("/ws?access_token=first", ""),
+			serve("/ws?ticket=once", ""),
+			serve("/ws?ticket=unknown", ""),
+			serve("/ws?ticket=other", "Bearer third"),
+			serve("/users?ticket=other", ""),
+			serve("/closed", "Bearer first"),
+		} {
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, domain.ContentTypeProblem, w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+		}
+	})
+}
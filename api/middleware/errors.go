@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nebojsaj1726/user-manager/api/i18n"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// Errors writes the last error a handler attached with c.Error as an RFC 7807
+// problem response, unless the handler already wrote a response. It must be
+// the innermost middleware so that outer ones, such as Idempotency, see the
+// response.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		AbortWithProblem(c, ProblemFor(c.Errors.Last().Err))
+	}
+}
+
+// ProblemFor maps an error to the problem reported to clients, including the
+// code and field errors of a domain.Error. Errors without a code of their
+// own get the code of their kind. Errors of no known kind are logged and
+// reported without details.
+func ProblemFor(err error) *domain.Problem {
+	var problem *domain.Problem
+	if errors.As(err, &problem) {
+		copied := *problem
+		return &copied
+	}
+
+	problem = problemForKind(err)
+
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		problem.Errors = domainErr.Fields
+		if domainErr.Code != "" {
+			problem.WithCode(domainErr.Code, domainErr.Params)
+		}
+	}
+
+	return problem
+}
+
+func problemForKind(err error) *domain.Problem {
+	switch {
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return domain.NewProblem(http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, domain.ErrVersionRequired):
+		return domain.NewProblem(http.StatusPreconditionRequired, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		return domain.NewProblem(http.StatusNotFound, err.Error()).WithCode("not_found", nil)
+	case errors.Is(err, domain.ErrConflict):
+		return domain.NewProblem(http.StatusConflict, err.Error()).WithCode("conflict", nil)
+	case errors.Is(err, domain.ErrValidation):
+		return domain.NewProblem(http.StatusBadRequest, err.Error()).WithCode("validation_failed", nil)
+	case errors.Is(err, domain.ErrInternal):
+		return domain.NewProblem(http.StatusInternalServerError, err.Error()).WithCode("internal_error", nil)
+	case errors.Is(err, context.DeadlineExceeded):
+		return domain.NewProblem(http.StatusGatewayTimeout, "The request timed out").WithCode("request_timeout", nil)
+	default:
+		log.Errorf("Request failed: %v", err)
+		return domain.NewProblem(http.StatusInternalServerError, "Something went wrong").WithCode("internal_error", nil)
+	}
+}
+
+// AbortWithProblem writes problem as application/problem+json, in the
+// language negotiated by i18n.Negotiate.
+func AbortWithProblem(c *gin.Context, problem *domain.Problem) {
+	problem = i18n.From(c).Problem(problem)
+	if problem.Instance == "" {
+		problem.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", domain.ContentTypeProblem)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
@@ -0,0 +1,119 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/i18n"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"not found", domain.ErrUserNotFound, http.StatusNotFound, "user_not_found", "user not found"},
+		{"version mismatch", domain.ErrVersionMismatch, http.StatusPreconditionFailed, "version_mismatch", "version mismatch: user has been modified"},
+		{"conflict", domain.ErrEmailNotUnique, http.StatusConflict, "email_not_unique", "email must be unique"},
+		{"wrapped validation", fmt.Errorf("create: %w", domain.NewValidationError("age must be greater than 18")), http.StatusBadRequest, "validation_failed", "create: age must be greater than 18"},
+		{"problem", domain.NewProblem(http.StatusUnsupportedMediaType, "Content-Type must be text/csv"), http.StatusUnsupportedMediaType, "", "Content-Type must be text/csv"},
+		{"unexpected", errors.New("connection reset"), http.StatusInternalServerError, "internal_error", "Something went wrong"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(middleware.Errors())
+			router.GET("/users/:id", func(c *gin.Context) {
+				c.Error(tt.err)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, domain.ContentTypeProblem, w.Header().Get("Content-Type"))
+
+			var problem domain.Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, "about:blank", problem.Type)
+			assert.Equal(t, http.StatusText(tt.status), problem.Title)
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.detail, problem.Detail)
+			assert.Equal(t, "/users/42", problem.Instance)
+		})
+	}
+
+	t.Run("field errors", func(t *testing.T) {
+		router := gin.New()
+		router.Use(middleware.Errors())
+		router.POST("/users", func(c *gin.Context) {
+			c.Error(domain.NewFieldError(domain.ErrValidation,
+				domain.FieldError{Field: "age", Code: "gt", Message: "age must be greater than 18", Params: map[string]interface{}{"gt": 18}},
+				domain.FieldError{Field: "email", Code: "required", Message: "email is required"},
+			))
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var problem domain.Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "age must be greater than 18; email is required", problem.Detail)
+		assert.Len(t, problem.Errors, 2)
+		assert.Equal(t, "age", problem.Errors[0].Field)
+		assert.Equal(t, float64(18), problem.Errors[0].Params["gt"])
+		assert.Equal(t, "required", problem.Errors[1].Code)
+	})
+
+	t.Run("localized", func(t *testing.T) {
+		router := gin.New()
+		router.Use(i18n.Negotiate(i18n.Default()), middleware.Errors())
+		router.POST("/users", func(c *gin.Context) {
+			c.Error(domain.ErrEmailNotUnique)
+		})
+
+		r := httptest.NewRequest(http.MethodPost, "/users", nil)
+		r.Header.Set("Accept-Language", "fr-FR, en;q=0.5")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "fr", w.Header().Get("Content-Language"))
+
+		var problem domain.Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "Conflit", problem.Title)
+		assert.Equal(t, "email_not_unique", problem.Code)
+		assert.Equal(t, "L'adresse e-mail est déjà utilisée", problem.Detail)
+		assert.Equal(t, "email doit être unique", problem.Errors[0].Message)
+		assert.Equal(t, "email must be unique", domain.ErrEmailNotUnique.Error())
+	})
+
+	t.Run("written response", func(t *testing.T) {
+		router := gin.New()
+		router.Use(middleware.Errors())
+		router.GET("/users", func(c *gin.Context) {
+			c.Error(errors.New("logged by the handler"))
+			c.JSON(http.StatusOK, gin.H{})
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
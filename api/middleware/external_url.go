@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyExternalURL holds the scheme and host clients reach the API at.
+const ContextKeyExternalURL = "external_url"
+
+// ExternalURL records the scheme and host the client addressed, so links in
+// responses work for it. Requests from trustedProxies, IPs or CIDRs as for
+// gin's SetTrustedProxies, are described by their X-Forwarded-Proto and
+// X-Forwarded-Host headers; others by the connection and Host header.
+func ExternalURL(trustedProxies []string) (gin.HandlerFunc, error) {
+	networks := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return func(c *gin.Context) {
+		external := connectionURL(c)
+
+		if ip := net.ParseIP(c.RemoteIP()); ip != nil && contains(networks, ip) {
+			if proto := firstValue(c.GetHeader("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+				external.Scheme = proto
+			}
+			if host := firstValue(c.GetHeader("X-Forwarded-Host")); host != "" {
+				external.Host = host
+			}
+		}
+
+		c.Set(ContextKeyExternalURL, external)
+		c.Next()
+	}, nil
+}
+
+// RequestURL returns the absolute URL of the request as the client sent it,
+// including its query.
+func RequestURL(c *gin.Context) *url.URL {
+	external, ok := c.Value(ContextKeyExternalURL).(*url.URL)
+	if !ok {
+		external = connectionURL(c)
+	}
+
+	requestURL := *c.Request.URL
+	requestURL.Scheme = external.Scheme
+	requestURL.Host = external.Host
+	return &requestURL
+}
+
+func connectionURL(c *gin.Context) *url.URL {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: c.Request.Host}
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstValue returns the value added by the proxy closest to the client
+// from a header that proxies append to.
+func firstValue(header string) string {
+	value, _, _ := strings.Cut(header, ",")
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,55 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	externalURL, err := middleware.ExternalURL([]string{"10.0.0.0/8", "127.0.0.1"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	router := gin.New()
+	router.Use(externalURL)
+	router.GET("/v2/users", func(c *gin.Context) {
+		c.String(http.StatusOK, middleware.RequestURL(c).String())
+	})
+
+	request := func(remoteAddr string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://api.internal:8080/v2/users?page=2&email=a%40b.c", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-Proto", "https")
+		r.Header.Set("X-Forwarded-Host", "users.example.com, proxy.internal")
+		return r
+	}
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request("10.1.2.3:40000"))
+		assert.Equal(t, "https://users.example.com/v2/users?page=2&email=a%40b.c", w.Body.String())
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, request("127.0.0.1:40000"))
+		assert.Equal(t, "https://users.example.com/v2/users?page=2&email=a%40b.c", w.Body.String())
+	})
+
+	t.Run("untrusted client", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request("203.0.113.7:40000"))
+		assert.Equal(t, "http://api.internal:8080/v2/users?page=2&email=a%40b.c", w.Body.String())
+	})
+
+	t.Run("invalid proxy", func(t *testing.T) {
+		_, err := middleware.ExternalURL([]string{"not-an-ip"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentBodySize bounds the request and response bodies kept in
+	// memory and stored for a key.
+	maxIdempotentBodySize = 1 << 20
+	// maxAcquireAttempts bounds the retries of acquiring a key whose record
+	// expires while it is looked up.
+	maxAcquireAttempts = 3
+	// idempotencyWriteTimeout bounds storing the outcome of a request, which
+	// happens even when the client has already gone away.
+	idempotencyWriteTimeout = 5 * time.Second
+)
+
+// replayedHeaders are the response headers stored alongside the body and
+// restored when a response is replayed.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// responseRecorder copies the response body while it is written, up to
+// maxIdempotentBodySize. Larger responses are still streamed to the client
+// but are not recorded.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *responseRecorder) record(n int) bool {
+	if !w.overflow && w.body.Len()+n > maxIdempotentBodySize {
+		w.overflow = true
+		w.body = bytes.Buffer{}
+	}
+	return !w.overflow
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.record(len(b)) {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	if w.record(len(s)) {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the first response for requests carrying an
+// Idempotency-Key header. Retries must send the same method, URL and body;
+// reusing a key for a different request is rejected with 422.
+//
+// A key is leased for lease while its request runs, so a request that never
+// finishes frees the key once the lease ends. A request that outlives its
+// lease stores nothing once another request has taken the key. Completed
+// responses are kept for ttl. Requests with bodies larger than
+// maxIdempotentBodySize are rejected with 413, since they cannot be
+// compared with their retries, and keys whose response is too large to
+// store are released like server errors.
+func Idempotency(repo domain.IdempotencyRepository, ttl, lease time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			AbortWithProblem(c, domain.NewProblem(http.StatusBadRequest, "Idempotency-Key is too long").WithCode("idempotency_key_too_long", nil))
+			return
+		}
+
+		tooLarge := domain.NewProblem(http.StatusRequestEntityTooLarge, "Requests with an Idempotency-Key can have a body of at most 1 MiB").
+			WithCode("idempotency_body_too_large", map[string]interface{}{"max": "1 MiB"})
+		if c.Request.ContentLength > maxIdempotentBodySize {
+			AbortWithProblem(c, tooLarge)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBodySize+1))
+		if err != nil {
+			AbortWithProblem(c, domain.NewProblem(http.StatusBadRequest, "Failed to read request body").WithCode("request_body_unreadable", nil))
+			return
+		}
+		if len(body) > maxIdempotentBodySize {
+			AbortWithProblem(c, tooLarge)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(c.Request, body)
+		ctx := c.Request.Context()
+
+		record := &domain.IdempotencyRecord{
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   time.Now().Add(lease),
+		}
+
+		var existing *domain.IdempotencyRecord
+		record.Lease, err = newLease()
+		if err == nil {
+			existing, err = acquire(ctx, repo, record)
+		}
+		if err != nil {
+			log.Errorf("Failed to acquire idempotency key: %v", err)
+			AbortWithProblem(c, domain.NewProblem(http.StatusInternalServerError, "Something went wrong").WithCode("internal_error", nil))
+			return
+		}
+		if existing != nil {
+			replay(c, existing, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		// The outcome is stored even if the client disconnected, otherwise
+		// its retries would find the key in progress until the lease ends.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyWriteTimeout)
+		defer cancel()
+
+		// Server errors are not cached so the client can retry with the same key.
+		if recorder.Status() >= http.StatusInternalServerError || recorder.overflow {
+			if err := repo.Release(ctx, record); err != nil {
+				log.Errorf("Failed to release idempotency key: %v", err)
+			}
+			return
+		}
+
+		record.Completed = true
+		record.ExpiresAt = time.Now().Add(ttl)
+		record.StatusCode = recorder.Status()
+		record.Body = recorder.body.Bytes()
+		record.Header = make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				record.Header[name] = value
+			}
+		}
+
+		if held, err := repo.Complete(ctx, record); err != nil {
+			log.Errorf("Failed to store idempotent response: %v", err)
+		} else if !held {
+			log.Warnf("Idempotent response not stored, the lease of its key ended before the request finished")
+		}
+	}
+}
+
+// acquire leases the key of record, or returns the live record that holds
+// it. A record that expires between the two lookups frees the key, so
+// acquiring it is tried again.
+func acquire(ctx context.Context, repo domain.IdempotencyRepository, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	for attempt := 1; ; attempt++ {
+		acquired, err := repo.Acquire(ctx, record)
+		if err != nil || acquired {
+			return nil, err
+		}
+
+		existing, err := repo.GetByKey(ctx, record.Key)
+		if errors.Is(err, domain.ErrNotFound) && attempt < maxAcquireAttempts {
+			continue
+		}
+		return existing, err
+	}
+}
+
+// newLease returns a token identifying the request holding a key.
+func newLease() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+func replay(c *gin.Context, record *domain.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		AbortWithProblem(c, domain.NewProblem(http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request").WithCode("idempotency_key_reused", nil))
+		return
+	}
+
+	if !record.Completed {
+		AbortWithProblem(c, domain.NewProblem(http.StatusConflict, "A request with this Idempotency-Key is still being processed").WithCode("idempotency_key_in_progress", nil))
+		return
+	}
+
+	for name, value := range record.Header {
+		c.Header(name, value)
+	}
+	c.Header(HeaderIdempotentReplayed, "true")
+	c.Status(record.StatusCode)
+	c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
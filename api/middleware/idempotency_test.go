@@ -0,0 +1,260 @@
+package middleware_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockIdempotencyRepository struct {
+	records map[string]domain.IdempotencyRecord
+}
+
+func (m *MockIdempotencyRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockIdempotencyRepository) Acquire(ctx context.Context, record *domain.IdempotencyRecord) (bool, error) {
+	if _, ok := m.records[record.Key]; ok {
+		return false, nil
+	}
+	m.records[record.Key] = *record
+	return true, nil
+}
+
+func (m *MockIdempotencyRepository) GetByKey(ctx context.Context, key string) (*domain.IdempotencyRecord, error) {
+	record, ok := m.records[key]
+	if !ok {
+		return nil, domain.NewNotFoundError("idempotency key not found")
+	}
+	return &record, nil
+}
+
+func (m *MockIdempotencyRepository) Complete(ctx context.Context, record *domain.IdempotencyRecord) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if m.records[record.Key].Lease != record.Lease {
+		return false, nil
+	}
+	m.records[record.Key] = *record
+	return true, nil
+}
+
+func (m *MockIdempotencyRepository) Release(ctx context.Context, record *domain.IdempotencyRecord) error {
+	if m.records[record.Key].Lease == record.Lease {
+		delete(m.records, record.Key)
+	}
+	return nil
+}
+
+// expiringIdempotencyRepository reports the key as taken the first time it
+// is acquired but has no record for it, as when the record expires between
+// the two lookups.
+type expiringIdempotencyRepository struct {
+	MockIdempotencyRepository
+	expired bool
+}
+
+func (m *expiringIdempotencyRepository) Acquire(ctx context.Context, record *domain.IdempotencyRecord) (bool, error) {
+	if !m.expired {
+		m.expired = true
+		return false, nil
+	}
+	return m.MockIdempotencyRepository.Acquire(ctx, record)
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repoMock := &MockIdempotencyRepository{records: map[string]domain.IdempotencyRecord{}}
+	calls := 0
+
+	router := gin.New()
+	router.Use(middleware.Idempotency(repoMock, time.Hour, time.Minute))
+	router.POST("/users", func(c *gin.Context) {
+		calls++
+		if c.Query("fail") != "" {
+			c.JSON(http.StatusInternalServerError, domain.NewProblem(http.StatusInternalServerError, "Something went wrong"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+
+	send := func(key, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set(middleware.HeaderIdempotencyKey, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("key-1", "/users", `{"email":"test@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"call":1}`, w.Body.String())
+
+	w = send("key-1", "/users", `{"email":"test@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"call":1}`, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(middleware.HeaderIdempotentReplayed))
+	assert.Equal(t, 1, calls)
+
+	w = send("key-1", "/users", `{"email":"other@example.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, 1, calls)
+
+	w = send("key-2", "/users?fail=1", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	_, stored := repoMock.records["key-2"]
+	assert.False(t, stored)
+
+	record := repoMock.records["key-1"]
+	record.Completed = false
+	repoMock.records["key-1"] = record
+	w = send("key-1", "/users", `{"email":"test@example.com"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestIdempotency_Lease(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repoMock := &MockIdempotencyRepository{records: map[string]domain.IdempotencyRecord{}}
+
+	var leased time.Time
+	router := gin.New()
+	router.Use(middleware.Idempotency(repoMock, time.Hour, time.Minute))
+	router.POST("/users", func(c *gin.Context) {
+		leased = repoMock.records["key-1"].ExpiresAt
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+	req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.WithinDuration(t, time.Now().Add(time.Minute), leased, 5*time.Second)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), repoMock.records["key-1"].ExpiresAt, 5*time.Second)
+}
+
+func TestIdempotency_ClientGone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repoMock := &MockIdempotencyRepository{records: map[string]domain.IdempotencyRecord{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	router := gin.New()
+	router.Use(middleware.Idempotency(repoMock, time.Hour, time.Minute))
+	router.POST("/users", func(c *gin.Context) {
+		cancel()
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`)).WithContext(ctx)
+	req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	record := repoMock.records["key-1"]
+	assert.True(t, record.Completed)
+	assert.Equal(t, http.StatusCreated, record.StatusCode)
+}
+
+func TestIdempotency_LargeBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repoMock := &MockIdempotencyRepository{records: map[string]domain.IdempotencyRecord{}}
+	large := strings.Repeat("a", 2<<20)
+
+	router := gin.New()
+	router.Use(middleware.Idempotency(repoMock, time.Hour, time.Minute))
+	router.POST("/import", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, "%d", len(body))
+	})
+	router.POST("/export", func(c *gin.Context) {
+		c.String(http.StatusOK, large)
+	})
+
+	t.Run("request", func(t *testing.T) {
+		for _, length := range []int64{-1, int64(len(large))} {
+			req := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(large))
+			req.ContentLength = length
+			req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			assert.Contains(t, w.Body.String(), "idempotency_body_too_large")
+			assert.NotContains(t, repoMock.records, "key-1")
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(large))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, strconv.Itoa(len(large)), w.Body.String())
+	})
+
+	t.Run("response", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/export", strings.NewReader(`{}`))
+		req.Header.Set(middleware.HeaderIdempotencyKey, "key-2")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, len(large), w.Body.Len())
+		assert.NotContains(t, repoMock.records, "key-2")
+	})
+}
+
+func TestIdempotency_LostLease(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repoMock := &MockIdempotencyRepository{records: map[string]domain.IdempotencyRecord{}}
+
+	router := gin.New()
+	router.Use(middleware.Idempotency(repoMock, time.Hour, time.Minute))
+	router.POST("/users", func(c *gin.Context) {
+		// The lease ended and a retry acquired the key meanwhile.
+		record := repoMock.records["key-1"]
+		record.Lease = "retry"
+		repoMock.records["key-1"] = record
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+	req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	record := repoMock.records["key-1"]
+	assert.Equal(t, "retry", record.Lease)
+	assert.False(t, record.Completed)
+}
+
+func TestIdempotency_ExpiredWhileAcquiring(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repoMock := &expiringIdempotencyRepository{MockIdempotencyRepository: MockIdempotencyRepository{records: map[string]domain.IdempotencyRecord{}}}
+
+	router := gin.New()
+	router.Use(middleware.Idempotency(repoMock, time.Hour, time.Minute))
+	router.POST("/users", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+	req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, repoMock.records["key-1"].Completed)
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics records request counts and latencies per API version.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by API version, method, route and status.",
+		}, []string{"version", "method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by API version, method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"version", "method", "route"}),
+	}
+
+	registerer.MustRegister(m.requests, m.duration)
+	return m
+}
+
+// Middleware observes every request served under version. The route label
+// is the matched route pattern, so IDs do not create new series.
+func (m *Metrics) Middleware(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		m.requests.WithLabelValues(version, c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		m.duration.WithLabelValues(version, c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ContextKeyAPIVersion holds the API version serving the request.
+	ContextKeyAPIVersion = "api_version"
+	// ContextKeyAPIBasePath holds the path prefix of that version, e.g.
+	// "/v2", for building links to other resources of the version.
+	ContextKeyAPIBasePath = "api_base_path"
+
+	HeaderAPIVersion  = "API-Version"
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+)
+
+// Deprecation announces that an API version is going away. Zero times leave
+// the corresponding header out.
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time
+	// Successor is the path prefix of the replacing version, e.g. "/v2".
+	Successor string
+}
+
+// APIVersion tags requests under prefix with version and, for deprecated
+// versions, sets the Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+// with a link to the same resource in the successor version.
+func APIVersion(version, prefix string, deprecation *Deprecation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ContextKeyAPIVersion, version)
+		c.Set(ContextKeyAPIBasePath, prefix)
+		c.Header(HeaderAPIVersion, version)
+
+		if deprecation != nil {
+			if !deprecation.Since.IsZero() {
+				c.Header(HeaderDeprecation, fmt.Sprintf("@%d", deprecation.Since.Unix()))
+			}
+			if !deprecation.Sunset.IsZero() {
+				c.Header(HeaderSunset, deprecation.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if deprecation.Successor != "" {
+				successor := deprecation.Successor + strings.TrimPrefix(c.Request.URL.Path, prefix)
+				c.Writer.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	deprecation := &middleware.Deprecation{
+		Since:     time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC),
+		Successor: "/v2",
+	}
+
+	router := gin.New()
+	v1 := router.Group("/v1", middleware.APIVersion("v1", "/v1", deprecation))
+	v1.GET("/users/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.ContextKeyAPIVersion))
+	})
+	v2 := router.Group("/v2", middleware.APIVersion("v2", "/v2", nil))
+	v2.GET("/users/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.ContextKeyAPIVersion))
+	})
+	v2.GET("/jobs", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.ContextKeyAPIBasePath))
+	})
+
+	t.Run("deprecated version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/users/42", nil))
+
+		assert.Equal(t, "v1", w.Body.String())
+		assert.Equal(t, "v1", w.Header().Get(middleware.HeaderAPIVersion))
+		assert.Equal(t, "@1790812800", w.Header().Get(middleware.HeaderDeprecation))
+		assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", w.Header().Get(middleware.HeaderSunset))
+		assert.Equal(t, `</v2/users/42>; rel="successor-version"`, w.Header().Get("Link"))
+	})
+
+	t.Run("current version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/users/42", nil))
+
+		assert.Equal(t, "v2", w.Body.String())
+		assert.Empty(t, w.Header().Get(middleware.HeaderDeprecation))
+		assert.Empty(t, w.Header().Get(middleware.HeaderSunset))
+		assert.Empty(t, w.Header().Get("Link"))
+	})
+	t.Run("base path", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/jobs", nil))
+
+		assert.Equal(t, "/v2", w.Body.String())
+	})
+}
@@ -0,0 +1,36 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed swagger.html
+var swaggerPage string
+
+// swaggerUI holds the vendored Swagger UI files, see swagger-ui/README.md.
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css
+var swaggerUI embed.FS
+
+// DocsHandler serves a Swagger UI page that loads the document from specURL
+// and the Swagger UI files from assetsURL, where DocsAssets is served.
+func DocsHandler(specURL, assetsURL string) gin.HandlerFunc {
+	page := []byte(strings.NewReplacer("{{SPEC_URL}}", specURL, "{{ASSETS_URL}}", assetsURL).Replace(swaggerPage))
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	}
+}
+
+// DocsAssets returns the Swagger UI files loaded by the page of DocsHandler.
+func DocsAssets() http.FileSystem {
+	assets, err := fs.Sub(swaggerUI, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(assets)
+}
@@ -0,0 +1,353 @@
+// Package openapi builds an OpenAPI 3.1 document from the registered gin
+// routes and the Go types they exchange, and optionally validates incoming
+// requests against it.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Version = "3.1.0"
+
+// Param describes a path or query parameter. Type is a JSON Schema type and
+// defaults to "string".
+type Param struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Enum        []string
+	Pattern     string
+	Description string
+}
+
+// Media documents a body that is not JSON, such as a CSV upload.
+type Media struct {
+	ContentType string
+	Schema      Schema
+}
+
+// Operation documents one route. RequestBody and the Responses values are
+// either example Go values, whose types are turned into JSON schemas, Media
+// for other content types, or nil for an empty body.
+type Operation struct {
+	Summary     string
+	Tags        []string
+	Deprecated  bool
+	Params      []Param
+	RequestBody interface{}
+	Responses   map[int]interface{}
+}
+
+// operation keeps the content objects generated when it was described, so
+// that the schema components are complete before the document is served.
+type operation struct {
+	Operation
+	body      Schema
+	responses map[int]Schema
+}
+
+// Registry collects operation descriptions keyed by method and path.
+type Registry struct {
+	title   string
+	version string
+
+	mu         sync.Mutex
+	generator  *schemaGenerator
+	operations map[string]*operation
+	document   Schema
+}
+
+func NewRegistry(title, version string) *Registry {
+	return &Registry{
+		title:      title,
+		version:    version,
+		generator:  newSchemaGenerator(),
+		operations: make(map[string]*operation),
+	}
+}
+
+// Describe documents the route registered for method and path. The path uses
+// gin syntax, e.g. "/users/:id"; custom methods dispatched by a shared route
+// are described with their literal path, e.g. "/users:batch".
+func (r *Registry) Describe(method, path string, op Operation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	described := &operation{Operation: op, responses: make(map[int]Schema)}
+	if op.RequestBody != nil {
+		described.body = r.content(op.RequestBody)
+	}
+	for status, body := range op.Responses {
+		described.responses[status] = nil
+		if body != nil {
+			described.responses[status] = r.content(body)
+		}
+	}
+
+	r.operations[operationKey(method, specPath(path))] = described
+	r.document = nil
+}
+
+// Schema returns the schema of v's type, registering named structs as
+// components. It is meant for Media bodies built from Go types.
+func (r *Registry) Schema(v interface{}) Schema {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generator.schemaOf(reflect.TypeOf(v))
+}
+
+// Document returns the OpenAPI document for routes. Routes without a
+// description are listed with a generic response so that the document
+// always covers the whole API.
+func (r *Registry) Document(routes gin.RoutesInfo) Schema {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.document != nil {
+		return r.document
+	}
+
+	paths := Schema{}
+	addOperation := func(method, path string, op *operation) {
+		item, ok := paths[path].(Schema)
+		if !ok {
+			item = Schema{}
+			paths[path] = item
+		}
+		item[strings.ToLower(method)] = r.operationObject(method, path, op)
+	}
+
+	for key, op := range r.operations {
+		method, path, _ := strings.Cut(key, " ")
+		addOperation(method, path, op)
+	}
+
+	for _, route := range routes {
+		path := specPath(route.Path)
+		if isDispatcher(path) || route.Method == http.MethodOptions {
+			continue
+		}
+		if _, ok := r.operations[operationKey(route.Method, path)]; !ok {
+			addOperation(route.Method, path, &operation{})
+		}
+	}
+
+	r.document = Schema{
+		"openapi": Version,
+		"info": Schema{
+			"title":   r.title,
+			"version": r.version,
+		},
+		"paths": paths,
+		"components": Schema{
+			"schemas": r.generator.components,
+		},
+	}
+	return r.document
+}
+
+// Undescribed returns the routes without a description, as "METHOD path".
+// Shared routes of custom methods are skipped, they are described by the
+// literal paths they dispatch.
+func (r *Registry) Undescribed(routes gin.RoutesInfo) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var missing []string
+	for _, route := range routes {
+		path := specPath(route.Path)
+		if isDispatcher(path) || route.Method == http.MethodOptions {
+			continue
+		}
+		if _, ok := r.operations[operationKey(route.Method, path)]; !ok {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	return missing
+}
+
+// Handler serves the document of the routes registered on router.
+func (r *Registry) Handler(router *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, r.Document(router.Routes()))
+	}
+}
+
+func (r *Registry) operationObject(method, path string, op *operation) Schema {
+	object := Schema{
+		"operationId": operationID(method, path),
+	}
+	if op.Summary != "" {
+		object["summary"] = op.Summary
+	}
+	if len(op.Tags) > 0 {
+		object["tags"] = op.Tags
+	}
+	if op.Deprecated {
+		object["deprecated"] = true
+	}
+
+	var parameters []Schema
+	for _, name := range pathParams(path) {
+		if param, ok := op.param(name, "path"); ok {
+			parameters = append(parameters, paramObject(param))
+			continue
+		}
+		parameters = append(parameters, paramObject(Param{Name: name, In: "path", Required: true}))
+	}
+	for _, param := range op.Params {
+		if param.In != "path" {
+			parameters = append(parameters, paramObject(param))
+		}
+	}
+	if len(parameters) > 0 {
+		object["parameters"] = parameters
+	}
+
+	if op.body != nil {
+		object["requestBody"] = Schema{
+			"required": true,
+			"content":  op.body,
+		}
+	}
+
+	responses := Schema{}
+	for status, content := range op.responses {
+		response := Schema{"description": http.StatusText(status)}
+		if content != nil {
+			response["content"] = content
+		}
+		responses[strconv.Itoa(status)] = response
+	}
+	if len(responses) == 0 {
+		responses["default"] = Schema{"description": "Response"}
+	}
+	object["responses"] = responses
+
+	return object
+}
+
+func (r *Registry) content(body interface{}) Schema {
+	if media, ok := body.(Media); ok {
+		return Schema{media.ContentType: Schema{"schema": orEmpty(media.Schema)}}
+	}
+	return Schema{"application/json": Schema{"schema": r.generator.schemaOf(reflect.TypeOf(body))}}
+}
+
+// jsonBody returns the schema of a JSON request body, or nil when the
+// operation takes another content type or no body at all.
+func (op *operation) jsonBody() Schema {
+	media, ok := op.body["application/json"].(Schema)
+	if !ok {
+		return nil
+	}
+	return media["schema"].(Schema)
+}
+
+func (op *operation) param(name, in string) (Param, bool) {
+	for _, param := range op.Params {
+		if param.Name == name && param.In == in {
+			return param, true
+		}
+	}
+	return Param{}, false
+}
+
+func paramObject(param Param) Schema {
+	schema := Schema{"type": paramType(param)}
+	if len(param.Enum) > 0 {
+		schema["enum"] = param.Enum
+	}
+	if param.Pattern != "" {
+		schema["pattern"] = param.Pattern
+	}
+
+	object := Schema{
+		"name":   param.Name,
+		"in":     param.In,
+		"schema": schema,
+	}
+	if param.Required || param.In == "path" {
+		object["required"] = true
+	}
+	if param.Description != "" {
+		object["description"] = param.Description
+	}
+	return object
+}
+
+func paramType(param Param) string {
+	if param.Type == "" {
+		return "string"
+	}
+	return param.Type
+}
+
+func orEmpty(schema Schema) Schema {
+	if schema == nil {
+		return Schema{}
+	}
+	return schema
+}
+
+// specPath converts gin path parameters to OpenAPI templates, "/users/:id"
+// becomes "/users/{id}".
+func specPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isDispatcher reports whether path is a shared route such as
+// "/users:action", whose operations are described individually.
+func isDispatcher(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if strings.Contains(segment, ":") {
+			return true
+		}
+	}
+	return false
+}
+
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, segment[1:len(segment)-1])
+		}
+	}
+	return names
+}
+
+func operationKey(method, path string) string {
+	return method + " " + path
+}
+
+// operationID derives a stable identifier, e.g. "get_users_id".
+func operationID(method, path string) string {
+	replacer := strings.NewReplacer("/", "_", "{", "", "}", "", ":", "_", "-", "_")
+	return strings.ToLower(method) + strings.TrimRight(replacer.Replace(path), "_")
+}
+
+// sortedKeys is used where deterministic iteration matters, e.g. in
+// validation error messages.
+func sortedKeys(m Schema) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
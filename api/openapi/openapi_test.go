@@ -0,0 +1,154 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/openapi"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(validate bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	registry := openapi.NewRegistry("Test", "1.0.0")
+	registry.Describe(http.MethodPost, "/users", openapi.Operation{
+		RequestBody: domain.User{},
+		Responses:   map[int]interface{}{http.StatusOK: domain.UserResponse{}},
+	})
+	registry.Describe(http.MethodGet, "/users/:id", openapi.Operation{
+		Params: []openapi.Param{{Name: "id", In: "path", Pattern: "^[0-9a-fA-F]{24}$"}},
+	})
+	registry.Describe(http.MethodPost, "/users:batch", openapi.Operation{
+		RequestBody: domain.BatchRequest{},
+	})
+
+	router.GET("/openapi.json", registry.Handler(router))
+
+	group := router.Group("")
+	if validate {
+		validator, err := registry.Validator()
+		if err != nil {
+			panic(err)
+		}
+		group.Use(validator)
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	group.POST("/users", ok)
+	group.GET("/users/:id", ok)
+	group.POST("/users:action", ok)
+	group.GET("/jobs/:id", ok)
+
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDocument(t *testing.T) {
+	w := serve(newTestRouter(false), http.MethodGet, "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var document map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+	assert.Equal(t, openapi.Version, document["openapi"])
+
+	paths := document["paths"].(map[string]interface{})
+	assert.Contains(t, paths, "/users/{id}")
+	assert.Contains(t, paths, "/users:batch")
+	assert.Contains(t, paths, "/jobs/{id}")
+	assert.NotContains(t, paths, "/users:action")
+
+	schemas := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	user := schemas["User"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"email"}, user["required"])
+	email := user["properties"].(map[string]interface{})["email"].(map[string]interface{})
+	assert.Equal(t, "email", email["format"])
+	assert.Contains(t, schemas, "BatchOperation")
+}
+
+func TestValidator(t *testing.T) {
+	router := newTestRouter(true)
+
+	t.Run("valid body", func(t *testing.T) {
+		w := serve(router, http.MethodPost, "/users", `{"email":"a@example.com","age":20}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("missing required field", func(t *testing.T) {
+		w := serve(router, http.MethodPost, "/users", `{"age":20}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "body.email is required")
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		w := serve(router, http.MethodPost, "/users", `{"email":"a@example.com","age":"20"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "body.age must be a number")
+	})
+
+	t.Run("path pattern", func(t *testing.T) {
+		w := serve(router, http.MethodGet, "/users/nope", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("custom method", func(t *testing.T) {
+		w := serve(router, http.MethodPost, "/users:batch", `{"mode":"sometimes","operations":[{"method":"create"}]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "body.mode must be one of atomic, best_effort")
+	})
+
+	t.Run("undescribed route", func(t *testing.T) {
+		w := serve(router, http.MethodGet, "/jobs/anything", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestValidator_InvalidPattern(t *testing.T) {
+	registry := openapi.NewRegistry("Test", "1.0.0")
+	registry.Describe(http.MethodGet, "/users/:id", openapi.Operation{
+		Params: []openapi.Param{{Name: "id", In: "path", Pattern: "^[0-9a-f"}},
+	})
+
+	_, err := registry.Validator()
+	assert.ErrorContains(t, err, `GET /users/{id} parameter "id"`)
+}
+
+func TestUndescribed(t *testing.T) {
+	router := newTestRouter(false)
+	registry := openapi.NewRegistry("Test", "1.0.0")
+	registry.Describe(http.MethodPost, "/users", openapi.Operation{})
+
+	assert.Equal(t, []string{"GET /openapi.json", "GET /users/:id", "GET /jobs/:id"}, registry.Undescribed(router.Routes()))
+}
+
+func TestDocs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/docs", openapi.DocsHandler("/openapi.json", "/docs/assets"))
+	router.StaticFS("/docs/assets", openapi.DocsAssets())
+
+	w := serve(router, http.MethodGet, "/docs", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `src="/docs/assets/swagger-ui-bundle.js"`)
+	assert.NotContains(t, w.Body.String(), "https://")
+
+	for _, asset := range []string{"swagger-ui-bundle.js", "swagger-ui.css"} {
+		w = serve(router, http.MethodGet, "/docs/assets/"+asset, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotZero(t, w.Body.Len())
+	}
+}
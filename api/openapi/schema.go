@@ -0,0 +1,169 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schema is a JSON Schema object as used by OpenAPI 3.1.
+type Schema = map[string]interface{}
+
+var (
+	objectIDType   = reflect.TypeOf(primitive.ObjectID{})
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaGenerator derives schemas from Go types, following json tags for
+// property names and gin binding tags for constraints. Named structs are
+// emitted once as components and referenced by name.
+type schemaGenerator struct {
+	components map[string]Schema
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{components: make(map[string]Schema)}
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case objectIDType:
+		return Schema{"type": "string", "pattern": "^[0-9a-fA-F]{24}$"}
+	case timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.components[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate.
+			g.components[t.Name()] = Schema{}
+			g.components[t.Name()] = g.structSchema(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return Schema{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			// Embedded structs are flattened like encoding/json does.
+			embedded := g.structSchema(field.Type)
+			for key, property := range embedded["properties"].(Schema) {
+				properties[key] = property
+			}
+			if names, ok := embedded["required"].([]string); ok {
+				required = append(required, names...)
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schemaOf(field.Type)
+		if applyBindingRules(property, field.Tag.Get("binding")) {
+			required = append(required, name)
+		}
+		properties[name] = property
+	}
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// applyBindingRules copies the validator rules the API relies on into the
+// schema and reports whether the field is required.
+func applyBindingRules(schema Schema, binding string) bool {
+	required := false
+
+	rules := strings.Split(binding, ",")
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			// The remaining rules apply to the items.
+			if items, ok := schema["items"].(Schema); ok {
+				applyBindingRules(items, strings.Join(rules[i+1:], ","))
+			}
+			return required
+		case "required":
+			required = true
+		case "email":
+			schema["format"] = "email"
+		case "oneof":
+			schema["enum"] = strings.Fields(param)
+		case "min":
+			if schema["type"] == "array" {
+				schema["minItems"] = atoi(param)
+			} else {
+				schema["minimum"] = atoi(param)
+			}
+		case "max":
+			if schema["type"] == "array" {
+				schema["maxItems"] = atoi(param)
+			} else {
+				schema["maximum"] = atoi(param)
+			}
+		}
+	}
+
+	return required
+}
+
+func atoi(s string) int {
+	n := 0
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return n
+		}
+		n = n*10 + int(r-'0')
+	}
+	return n
+}
@@ -0,0 +1,353 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// validator checks requests against the operations described before it was
+// built, with their patterns compiled once.
+type validator struct {
+	registry   *Registry
+	operations map[string]*operation
+	patterns   map[string]*regexp.Regexp
+}
+
+// Validator rejects requests whose parameters or JSON body do not match the
+// described operation with 400 Bad Request. Undescribed routes pass through,
+// and so do routes described after Validator was called. It returns an error
+// when a described pattern is not a valid regular expression.
+func (r *Registry) Validator() (gin.HandlerFunc, error) {
+	v, err := r.newValidator()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		op := v.lookup(c)
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		if err := v.validateRequest(c, op); err != nil {
+			middleware.AbortWithProblem(c, domain.NewProblem(http.StatusBadRequest, "Request validation failed: "+err.Error()))
+			return
+		}
+
+		c.Next()
+	}, nil
+}
+
+func (r *Registry) newValidator() (*validator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v := &validator{
+		registry:   r,
+		operations: make(map[string]*operation, len(r.operations)),
+		patterns:   make(map[string]*regexp.Regexp),
+	}
+
+	visited := make(map[string]bool)
+	for key, op := range r.operations {
+		v.operations[key] = op
+		for _, param := range op.Params {
+			if err := v.compile(param.Pattern); err != nil {
+				return nil, fmt.Errorf("%s parameter %q: %w", key, param.Name, err)
+			}
+		}
+		if err := v.compileSchema(op.jsonBody(), visited); err != nil {
+			return nil, fmt.Errorf("%s request body: %w", key, err)
+		}
+	}
+
+	return v, nil
+}
+
+func (v *validator) compile(pattern string) error {
+	if pattern == "" || v.patterns[pattern] != nil {
+		return nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	v.patterns[pattern] = compiled
+	return nil
+}
+
+// compileSchema compiles the patterns of schema and of the components it
+// references. It is called with the registry locked.
+func (v *validator) compileSchema(schema Schema, visited map[string]bool) error {
+	if schema == nil {
+		return nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if visited[name] {
+			return nil
+		}
+		visited[name] = true
+		return v.compileSchema(v.registry.generator.components[name], visited)
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		if err := v.compile(pattern); err != nil {
+			return err
+		}
+	}
+	if properties, ok := schema["properties"].(Schema); ok {
+		for _, name := range sortedKeys(properties) {
+			if err := v.compileSchema(properties[name].(Schema), visited); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if nested, ok := schema[key].(Schema); ok {
+			if err := v.compileSchema(nested, visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (v *validator) lookup(c *gin.Context) *operation {
+	path := specPath(c.FullPath())
+	if isDispatcher(path) {
+		path = c.Request.URL.Path
+	}
+	return v.operations[operationKey(c.Request.Method, path)]
+}
+
+func (v *validator) validateRequest(c *gin.Context, op *operation) error {
+	for _, param := range op.Params {
+		var value string
+		var present bool
+		switch param.In {
+		case "path":
+			value = c.Param(param.Name)
+			present = true
+		case "query":
+			value, present = c.GetQuery(param.Name)
+		default:
+			continue
+		}
+
+		if !present {
+			if param.Required {
+				return fmt.Errorf("%s parameter %q is required", param.In, param.Name)
+			}
+			continue
+		}
+		if err := v.validateParam(param, value); err != nil {
+			return fmt.Errorf("%s parameter %q %v", param.In, param.Name, err)
+		}
+	}
+
+	schema := op.jsonBody()
+	if schema == nil || c.ContentType() != "application/json" {
+		return nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return fmt.Errorf("request body could not be read")
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("request body is not valid JSON")
+	}
+
+	return v.validateValue(schema, document, "body")
+}
+
+func (v *validator) validateParam(param Param, value string) error {
+	switch paramType(param) {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("must be a number")
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+	}
+
+	if len(param.Enum) > 0 && !contains(param.Enum, value) {
+		return fmt.Errorf("must be one of %s", strings.Join(param.Enum, ", "))
+	}
+	if param.Pattern != "" && !v.patterns[param.Pattern].MatchString(value) {
+		return fmt.Errorf("must match %s", param.Pattern)
+	}
+
+	return nil
+}
+
+// validateValue checks the subset of JSON Schema produced by the schema
+// generator. The generator uses the API's binding rules, so this mirrors what
+// the handlers enforce without reaching them.
+func (v *validator) validateValue(schema Schema, value interface{}, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		v.registry.mu.Lock()
+		resolved := v.registry.generator.components[strings.TrimPrefix(ref, "#/components/schemas/")]
+		v.registry.mu.Unlock()
+		return v.validateValue(resolved, value, at)
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", at)
+		}
+		if required, ok := schema["required"].([]string); ok {
+			for _, name := range required {
+				if object[name] == nil {
+					return fmt.Errorf("%s.%s is required", at, name)
+				}
+			}
+		}
+		if properties, ok := schema["properties"].(Schema); ok {
+			for _, name := range sortedKeys(properties) {
+				if property, found := object[name]; found {
+					if err := v.validateValue(properties[name].(Schema), property, at+"."+name); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if additional, ok := schema["additionalProperties"].(Schema); ok {
+			for _, name := range sortedKeys(object) {
+				if err := v.validateValue(additional, object[name], at+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", at)
+		}
+		if min, ok := schema["minItems"].(int); ok && len(items) < min {
+			return fmt.Errorf("%s must have at least %d items", at, min)
+		}
+		if max, ok := schema["maxItems"].(int); ok && len(items) > max {
+			return fmt.Errorf("%s must have at most %d items", at, max)
+		}
+		if itemSchema, ok := schema["items"].(Schema); ok {
+			for i, item := range items {
+				if err := v.validateValue(itemSchema, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", at)
+		}
+		if err := v.validateString(schema, s); err != nil {
+			return fmt.Errorf("%s %v", at, err)
+		}
+
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s must be a number", at)
+		}
+		if err := validateNumber(schema, number); err != nil {
+			return fmt.Errorf("%s %v", at, err)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", at)
+		}
+	}
+
+	return nil
+}
+
+func (v *validator) validateString(schema Schema, s string) error {
+	switch schema["format"] {
+	case "email":
+		if _, err := mail.ParseAddress(s); err != nil {
+			return fmt.Errorf("must be a valid email address")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Errorf("must be an RFC 3339 date-time")
+		}
+	}
+
+	if enum, ok := schema["enum"].([]string); ok && len(enum) > 0 && !contains(enum, s) {
+		return fmt.Errorf("must be one of %s", strings.Join(enum, ", "))
+	}
+	if pattern, ok := schema["pattern"].(string); ok && !v.patterns[pattern].MatchString(s) {
+		return fmt.Errorf("must match %s", pattern)
+	}
+
+	return nil
+}
+
+func validateNumber(schema Schema, number json.Number) error {
+	if schema["type"] == "integer" {
+		if _, err := number.Int64(); err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+	}
+
+	f, err := number.Float64()
+	if err != nil {
+		return fmt.Errorf("must be a number")
+	}
+	if min, ok := schema["minimum"].(int); ok && f < float64(min) {
+		return fmt.Errorf("must be at least %d", min)
+	}
+	if max, ok := schema["maximum"].(int); ok && f > float64(max) {
+		return fmt.Errorf("must be at most %d", max)
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
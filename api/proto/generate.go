@@ -0,0 +1,5 @@
+// Package proto holds the protobuf definitions of the internal APIs. The Go
+// code next to each definition is generated, run go generate after editing.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative user/v1/user_service.proto
@@ -0,0 +1,28 @@
+// Package protoconv converts between the domain and the generated protobuf
+// messages, which are kept free of imports from the rest of the module.
+package protoconv
+
+import (
+	userv1 "github.com/nebojsaj1726/user-manager/api/proto/user/v1"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// UserToProto converts a user to its protobuf message.
+func UserToProto(user *domain.User) *userv1.User {
+	return &userv1.User{
+		Id:      user.ID.Hex(),
+		Age:     int32(user.Age),
+		Email:   user.Email,
+		Version: user.Version,
+	}
+}
+
+// UserFromProto converts the message to a user. The ID is ignored because
+// callers take it from the request path or generate it.
+func UserFromProto(message *userv1.User) *domain.User {
+	return &domain.User{
+		Age:     int(message.GetAge()),
+		Email:   message.GetEmail(),
+		Version: message.GetVersion(),
+	}
+}
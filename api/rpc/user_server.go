@@ -0,0 +1,165 @@
+// Package rpc serves the gRPC API on top of the same use cases as the HTTP
+// controllers.
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userv1 "github.com/nebojsaj1726/user-manager/api/proto/user/v1"
+	"github.com/nebojsaj1726/user-manager/api/protoconv"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+type UserServer struct {
+	userv1.UnimplementedUserServiceServer
+	UserUsecase domain.UserUsecase
+	// RequireIfMatch rejects updates and deletes without a version, as the
+	// HTTP API rejects them without an If-Match header.
+	RequireIfMatch bool
+}
+
+func (s *UserServer) CreateUser(ctx context.Context, req *userv1.CreateUserRequest) (*userv1.User, error) {
+	if req.GetUser() == nil {
+		return nil, status.Error(codes.InvalidArgument, "user is required")
+	}
+
+	user := protoconv.UserFromProto(req.GetUser())
+	user.ID = primitive.NewObjectID()
+
+	if err := s.UserUsecase.Create(ctx, user); err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return protoconv.UserToProto(user), nil
+}
+
+func (s *UserServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.User, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserUsecase.GetByID(ctx, req.GetId())
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return protoconv.UserToProto(user), nil
+}
+
+func (s *UserServer) ListUsers(req *userv1.ListUsersRequest, stream userv1.UserService_ListUsersServer) error {
+	err := s.UserUsecase.Export(stream.Context(), filterFromProto(req.GetFilter()), func(user *domain.User) error {
+		return stream.Send(protoconv.UserToProto(user))
+	})
+	if err != nil {
+		return statusFromError(err)
+	}
+
+	return nil
+}
+
+func (s *UserServer) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.User, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, err
+	}
+	if req.GetUser() == nil {
+		return nil, status.Error(codes.InvalidArgument, "user is required")
+	}
+
+	update := protoconv.UserFromProto(req.GetUser())
+	if req.GetVersion() != 0 {
+		update.Version = req.GetVersion()
+	}
+	if err := s.checkVersion(update.Version); err != nil {
+		return nil, err
+	}
+
+	if err := s.UserUsecase.Update(ctx, req.GetId(), update); err != nil {
+		return nil, statusFromError(err)
+	}
+
+	user, err := s.UserUsecase.GetByID(ctx, req.GetId())
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return protoconv.UserToProto(user), nil
+}
+
+func (s *UserServer) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*userv1.DeleteUserResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkVersion(req.GetVersion()); err != nil {
+		return nil, err
+	}
+
+	if err := s.UserUsecase.Delete(ctx, req.GetId(), req.GetVersion()); err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return &userv1.DeleteUserResponse{}, nil
+}
+
+func (s *UserServer) CountUsers(ctx context.Context, req *userv1.CountUsersRequest) (*userv1.CountUsersResponse, error) {
+	count, err := s.UserUsecase.Count(ctx, filterFromProto(req.GetFilter()))
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return &userv1.CountUsersResponse{Count: count}, nil
+}
+
+func validateID(id string) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid ID")
+	}
+	return nil
+}
+
+// checkVersion rejects writes without a version when they are required.
+func (s *UserServer) checkVersion(version int64) error {
+	if s.RequireIfMatch && version == 0 {
+		return status.Error(codes.FailedPrecondition, "version is required")
+	}
+	return nil
+}
+
+// statusFromError maps use case errors to the gRPC codes matching the HTTP
+// statuses UserController answers with.
+func statusFromError(err error) error {
+	message := err.Error()
+
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, message)
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, message)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, message)
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return status.Error(codes.Aborted, message)
+	case errors.Is(err, domain.ErrConflict):
+		return status.Error(codes.AlreadyExists, message)
+	case errors.Is(err, domain.ErrValidation):
+		return status.Error(codes.InvalidArgument, message)
+	default:
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.Internal, "something went wrong")
+	}
+}
+
+func filterFromProto(filter *userv1.UserFilter) domain.UserFilter {
+	return domain.UserFilter{
+		Email:  filter.GetEmail(),
+		MinAge: int(filter.GetMinAge()),
+		MaxAge: int(filter.GetMaxAge()),
+	}
+}
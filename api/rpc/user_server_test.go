@@ -0,0 +1,148 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userv1 "github.com/nebojsaj1726/user-manager/api/proto/user/v1"
+	"github.com/nebojsaj1726/user-manager/api/rpc"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// MockUserUsecase implements the methods used by UserServer; the embedded
+// interface panics on anything else.
+type MockUserUsecase struct {
+	domain.UserUsecase
+	CreateFunc  func(ctx context.Context, user *domain.User) error
+	GetByIDFunc func(ctx context.Context, id string) (*domain.User, error)
+	UpdateFunc  func(ctx context.Context, id string, user *domain.User) error
+	DeleteFunc  func(ctx context.Context, id string, version int64) error
+}
+
+func (m *MockUserUsecase) Create(ctx context.Context, user *domain.User) error {
+	return m.CreateFunc(ctx, user)
+}
+
+func (m *MockUserUsecase) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *MockUserUsecase) Update(ctx context.Context, id string, user *domain.User) error {
+	return m.UpdateFunc(ctx, id, user)
+}
+
+func (m *MockUserUsecase) Delete(ctx context.Context, id string, version int64) error {
+	return m.DeleteFunc(ctx, id, version)
+}
+
+func TestCreateUser(t *testing.T) {
+	mockUsecase := &MockUserUsecase{
+		CreateFunc: func(ctx context.Context, user *domain.User) error {
+			if user.Age < 18 {
+				return domain.NewValidationError("age must be greater than 18")
+			}
+			user.Version = 1
+			return nil
+		},
+	}
+	server := &rpc.UserServer{UserUsecase: mockUsecase}
+
+	user, err := server.CreateUser(context.Background(), &userv1.CreateUserRequest{User: &userv1.User{Age: 30, Email: "a@example.com"}})
+	assert.NoError(t, err)
+	assert.Len(t, user.Id, 24)
+	assert.Equal(t, int64(1), user.Version)
+
+	_, err = server.CreateUser(context.Background(), &userv1.CreateUserRequest{User: &userv1.User{Age: 10, Email: "a@example.com"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = server.CreateUser(context.Background(), &userv1.CreateUserRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestStatusMapping(t *testing.T) {
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"not found", domain.ErrUserNotFound, codes.NotFound},
+		{"version mismatch", domain.ErrVersionMismatch, codes.Aborted},
+		{"duplicate email", domain.ErrEmailNotUnique, codes.AlreadyExists},
+		{"invalid email", domain.NewValidationError("email must be a valid address"), codes.InvalidArgument},
+		{"timeout", context.DeadlineExceeded, codes.DeadlineExceeded},
+		{"unexpected", errors.New("connection reset"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &rpc.UserServer{UserUsecase: &MockUserUsecase{
+				DeleteFunc: func(ctx context.Context, id string, version int64) error {
+					return tt.err
+				},
+			}}
+
+			_, err := server.DeleteUser(context.Background(), &userv1.DeleteUserRequest{Id: id, Version: 2})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+
+	t.Run("invalid id", func(t *testing.T) {
+		server := &rpc.UserServer{UserUsecase: &MockUserUsecase{}}
+		_, err := server.GetUser(context.Background(), &userv1.GetUserRequest{Id: "nope"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestVersionPreconditions(t *testing.T) {
+	id := primitive.NewObjectID().Hex()
+
+	var updated, deleted int64
+	mockUsecase := &MockUserUsecase{
+		GetByIDFunc: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{Version: updated + 1}, nil
+		},
+		UpdateFunc: func(ctx context.Context, id string, user *domain.User) error {
+			updated = user.Version
+			return nil
+		},
+		DeleteFunc: func(ctx context.Context, id string, version int64) error {
+			deleted = version
+			return nil
+		},
+	}
+
+	t.Run("version takes precedence", func(t *testing.T) {
+		server := &rpc.UserServer{UserUsecase: mockUsecase}
+
+		_, err := server.UpdateUser(context.Background(), &userv1.UpdateUserRequest{Id: id, User: &userv1.User{Age: 30, Version: 1}, Version: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), updated)
+
+		_, err = server.UpdateUser(context.Background(), &userv1.UpdateUserRequest{Id: id, User: &userv1.User{Age: 30, Version: 4}})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), updated)
+	})
+
+	t.Run("versions required", func(t *testing.T) {
+		server := &rpc.UserServer{UserUsecase: mockUsecase, RequireIfMatch: true}
+		updated, deleted = 0, 0
+
+		_, err := server.UpdateUser(context.Background(), &userv1.UpdateUserRequest{Id: id, User: &userv1.User{Age: 30}})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		_, err = server.DeleteUser(context.Background(), &userv1.DeleteUserRequest{Id: id})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Zero(t, updated)
+
+		_, err = server.DeleteUser(context.Background(), &userv1.DeleteUserRequest{Id: id, Version: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+	})
+}
@@ -0,0 +1,119 @@
+package scim
+
+// The discovery resources describe what this service provider implements
+// (RFC 7644, section 4). They are static apart from the filter limit.
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type filterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type bulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type ServiceProviderConfig struct {
+	Schemas               []string      `json:"schemas"`
+	Patch                 supported     `json:"patch"`
+	Bulk                  bulkSupport   `json:"bulk"`
+	Filter                filterSupport `json:"filter"`
+	ChangePassword        supported     `json:"changePassword"`
+	Sort                  supported     `json:"sort"`
+	ETag                  supported     `json:"etag"`
+	AuthenticationSchemes []interface{} `json:"authenticationSchemes"`
+	Meta                  Meta          `json:"meta"`
+}
+
+func NewServiceProviderConfig(maxResults int) ServiceProviderConfig {
+	return ServiceProviderConfig{
+		Schemas:               []string{SchemaServiceProviderConfig},
+		Patch:                 supported{Supported: true},
+		Filter:                filterSupport{Supported: true, MaxResults: maxResults},
+		ETag:                  supported{Supported: true},
+		AuthenticationSchemes: []interface{}{},
+		Meta:                  Meta{ResourceType: "ServiceProviderConfig"},
+	}
+}
+
+type SchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+type ResourceType struct {
+	Schemas          []string          `json:"schemas"`
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Endpoint         string            `json:"endpoint"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions"`
+	Meta             Meta              `json:"meta"`
+}
+
+var ResourceTypes = []ResourceType{{
+	Schemas:          []string{SchemaResourceType},
+	ID:               "User",
+	Name:             "User",
+	Endpoint:         "/Users",
+	Schema:           SchemaUser,
+	SchemaExtensions: []SchemaExtension{{Schema: SchemaUserExtension, Required: true}},
+	Meta:             Meta{ResourceType: "ResourceType"},
+}}
+
+type Attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []Attribute `json:"subAttributes,omitempty"`
+}
+
+type Schema struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Attributes  []Attribute `json:"attributes"`
+	Meta        Meta        `json:"meta"`
+}
+
+// Schemas lists the attributes of the core User schema that users have,
+// followed by the extension for the rest.
+var Schemas = []Schema{
+	{
+		Schemas:     []string{SchemaSchema},
+		ID:          SchemaUser,
+		Name:        "User",
+		Description: "User Account",
+		Attributes: []Attribute{
+			{Name: "userName", Type: "string", Required: true, Mutability: "readWrite", Returned: "default", Uniqueness: "server"},
+			{Name: "emails", Type: "complex", MultiValued: true, Mutability: "readWrite", Returned: "default", Uniqueness: "none",
+				SubAttributes: []Attribute{
+					{Name: "value", Type: "string", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+					{Name: "type", Type: "string", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+					{Name: "primary", Type: "boolean", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+				}},
+		},
+		Meta: Meta{ResourceType: "Schema"},
+	},
+	{
+		Schemas:     []string{SchemaSchema},
+		ID:          SchemaUserExtension,
+		Name:        "UserManagerUser",
+		Description: "User attributes specific to the user manager",
+		Attributes: []Attribute{
+			{Name: "age", Type: "integer", Required: true, Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+		},
+		Meta: Meta{ResourceType: "Schema"},
+	},
+}
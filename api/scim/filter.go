@@ -0,0 +1,228 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// ParseFilter parses the subset of the SCIM filter grammar the user store
+// supports: the eq, co and sw operators combined with and, or and
+// parentheses, where and binds tighter than or.
+func ParseFilter(filter string) (*domain.FilterExpression, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, filterError("unexpected %q", p.tokens[p.pos].text)
+	}
+
+	return expr, nil
+}
+
+// attributes maps filterable SCIM attribute paths, compared without regard
+// to case, to domain attributes.
+var attributes = map[string]string{
+	"id":           domain.FilterAttributeID,
+	"username":     domain.FilterAttributeEmail,
+	"emails":       domain.FilterAttributeEmail,
+	"emails.value": domain.FilterAttributeEmail,
+	"age":          domain.FilterAttributeAge,
+	strings.ToLower(SchemaUserExtension) + ":age": domain.FilterAttributeAge,
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) or() (*domain.FilterExpression, error) {
+	return p.logical(domain.FilterOr, p.and)
+}
+
+func (p *filterParser) and() (*domain.FilterExpression, error) {
+	return p.logical(domain.FilterAnd, p.factor)
+}
+
+func (p *filterParser) logical(operator string, operand func() (*domain.FilterExpression, error)) (*domain.FilterExpression, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []domain.FilterExpression{*first}
+	for p.keyword(operator) {
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, *next)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return &domain.FilterExpression{Operator: operator, Operands: operands}, nil
+}
+
+func (p *filterParser) factor() (*domain.FilterExpression, error) {
+	if p.symbol("(") {
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, filterError("missing closing parenthesis")
+		}
+		return expr, nil
+	}
+
+	path, ok := p.next()
+	if !ok || path.quoted {
+		return nil, filterError("expected an attribute")
+	}
+	attribute, ok := attributes[strings.ToLower(path.text)]
+	if !ok {
+		return nil, filterError("attribute %q cannot be filtered", path.text)
+	}
+
+	op, ok := p.next()
+	if !ok {
+		return nil, filterError("expected an operator after %q", path.text)
+	}
+	operator := strings.ToLower(op.text)
+	switch operator {
+	case domain.FilterEqual, domain.FilterContains, domain.FilterStartsWith:
+	default:
+		return nil, filterError("operator %q is not supported", op.text)
+	}
+
+	raw, ok := p.next()
+	if !ok {
+		return nil, filterError("expected a value after %q", op.text)
+	}
+
+	value, err := filterValue(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch attribute {
+	case domain.FilterAttributeAge:
+		number, ok := value.(float64)
+		if !ok || number != float64(int(number)) || operator != domain.FilterEqual {
+			return nil, filterError("age only supports eq with an integer")
+		}
+		value = int(number)
+	default:
+		if _, ok := value.(string); !ok {
+			return nil, filterError("%s must be compared with a string", path.text)
+		}
+	}
+
+	return &domain.FilterExpression{Operator: operator, Attribute: attribute, Value: value}, nil
+}
+
+func (p *filterParser) next() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	p.pos++
+	return p.tokens[p.pos-1], true
+}
+
+func (p *filterParser) keyword(word string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) symbol(s string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && p.tokens[p.pos].text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// filterValue decodes a comparison value, which is JSON: a string, number,
+// boolean or null.
+func filterValue(raw token) (interface{}, error) {
+	text := raw.text
+	if raw.quoted {
+		var s string
+		if err := json.Unmarshal([]byte(text), &s); err != nil {
+			return nil, filterError("invalid string %s", text)
+		}
+		return s, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, filterError("invalid value %q", text)
+	}
+	return value, nil
+}
+
+func tokenize(filter string) ([]token, error) {
+	var tokens []token
+	runes := []rune(filter)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, token{text: string(r)})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, filterError("unterminated string")
+			}
+			tokens = append(tokens, token{text: string(runes[i : end+1]), quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+			tokens = append(tokens, token{text: string(runes[i:end])})
+			i = end
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, filterError("filter is empty")
+	}
+	return tokens, nil
+}
+
+func filterError(format string, args ...interface{}) *Error {
+	return NewError(http.StatusBadRequest, ErrInvalidFilter, fmt.Sprintf(format, args...))
+}
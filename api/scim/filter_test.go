@@ -0,0 +1,59 @@
+package scim_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/nebojsaj1726/user-manager/api/scim"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Run("comparison", func(t *testing.T) {
+		expr, err := scim.ParseFilter(`userName eq "john@example.com"`)
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.FilterExpression{
+			Operator:  domain.FilterEqual,
+			Attribute: domain.FilterAttributeEmail,
+			Value:     "john@example.com",
+		}, expr)
+	})
+
+	t.Run("precedence and grouping", func(t *testing.T) {
+		expr, err := scim.ParseFilter(`emails.value SW "a" OR (age eq 30 and id eq "64b7f0c2a1b2c3d4e5f60718")`)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.FilterOr, expr.Operator)
+		assert.Len(t, expr.Operands, 2)
+		assert.Equal(t, domain.FilterStartsWith, expr.Operands[0].Operator)
+		assert.Equal(t, domain.FilterAnd, expr.Operands[1].Operator)
+		assert.Equal(t, 30, expr.Operands[1].Operands[0].Value)
+	})
+
+	t.Run("extension attribute", func(t *testing.T) {
+		expr, err := scim.ParseFilter(scim.SchemaUserExtension + `:age eq 21`)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.FilterAttributeAge, expr.Attribute)
+	})
+
+	for name, filter := range map[string]string{
+		"empty":                "  ",
+		"unknown attribute":    `displayName eq "x"`,
+		"unsupported operator": `age gt 20`,
+		"age with a string":    `age eq "20"`,
+		"email with a number":  `userName eq 5`,
+		"missing parenthesis":  `(userName eq "a"`,
+		"unterminated string":  `userName eq "a`,
+		"trailing tokens":      `userName eq "a" "b"`,
+		"missing value":        `userName eq`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := scim.ParseFilter(filter)
+			var scimErr *scim.Error
+			assert.True(t, errors.As(err, &scimErr))
+			assert.Equal(t, http.StatusBadRequest, scimErr.StatusCode())
+			assert.Equal(t, scim.ErrInvalidFilter, scimErr.ScimType)
+		})
+	}
+}
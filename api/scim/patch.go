@@ -0,0 +1,119 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// Apply applies the operations to user in order. Every attribute of a user
+// is required, so add and replace are supported but remove is not.
+func (r *PatchRequest) Apply(user *domain.User) error {
+	if !contains(r.Schemas, SchemaPatchOp) {
+		return NewError(http.StatusBadRequest, ErrInvalidSyntax, "schemas must contain "+SchemaPatchOp)
+	}
+	if len(r.Operations) == 0 {
+		return NewError(http.StatusBadRequest, ErrInvalidSyntax, "Operations must not be empty")
+	}
+
+	for i, op := range r.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		case "remove":
+			return NewError(http.StatusBadRequest, ErrNoTarget, fmt.Sprintf("Operations[%d]: attributes cannot be removed", i))
+		default:
+			return NewError(http.StatusBadRequest, ErrInvalidSyntax, fmt.Sprintf("Operations[%d]: unknown op %q", i, op.Op))
+		}
+
+		if err := apply(user, op.Path, op.Value); err != nil {
+			err.Detail = fmt.Sprintf("Operations[%d]: %s", i, err.Detail)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func apply(user *domain.User, path string, value json.RawMessage) *Error {
+	switch attributePath(path) {
+	case "":
+		var resource struct {
+			UserName  *string        `json:"userName"`
+			Emails    []Email        `json:"emails"`
+			Extension *UserExtension `json:"urn:ietf:params:scim:schemas:extension:usermanager:2.0:User"`
+		}
+		if err := json.Unmarshal(value, &resource); err != nil {
+			return NewError(http.StatusBadRequest, ErrInvalidValue, "value must be an object of attributes")
+		}
+		if email := primaryEmail(resource.Emails); email != "" {
+			user.Email = email
+		}
+		if resource.UserName != nil {
+			user.Email = *resource.UserName
+		}
+		if resource.Extension != nil {
+			user.Age = resource.Extension.Age
+		}
+	case "username", "emails.value":
+		var email string
+		if err := json.Unmarshal(value, &email); err != nil {
+			return NewError(http.StatusBadRequest, ErrInvalidValue, path+" must be a string")
+		}
+		user.Email = email
+	case "emails":
+		var emails []Email
+		if err := json.Unmarshal(value, &emails); err != nil || len(emails) == 0 {
+			return NewError(http.StatusBadRequest, ErrInvalidValue, "emails must be a non-empty array")
+		}
+		user.Email = primaryEmail(emails)
+	case "age":
+		var age int
+		if err := json.Unmarshal(value, &age); err != nil {
+			return NewError(http.StatusBadRequest, ErrInvalidValue, path+" must be an integer")
+		}
+		user.Age = age
+	default:
+		return NewError(http.StatusBadRequest, ErrInvalidPath, fmt.Sprintf("path %q is not supported", path))
+	}
+
+	return nil
+}
+
+// attributePath normalizes a patch path: it is lower cased, the extension
+// URN prefix is dropped and a value filter such as emails[type eq "work"]
+// is ignored, since a user has a single email.
+func attributePath(path string) string {
+	path = strings.ToLower(strings.TrimSpace(path))
+	path = strings.TrimPrefix(path, strings.ToLower(SchemaUserExtension)+":")
+
+	if start := strings.Index(path, "["); start >= 0 {
+		if end := strings.Index(path, "]"); end > start {
+			path = path[:start] + path[end+1:]
+		}
+	}
+
+	return path
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
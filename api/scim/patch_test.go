@@ -0,0 +1,64 @@
+package scim_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nebojsaj1726/user-manager/api/scim"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func patchRequest(t *testing.T, body string) *scim.PatchRequest {
+	var request scim.PatchRequest
+	assert.NoError(t, json.Unmarshal([]byte(body), &request))
+	return &request
+}
+
+func TestPatchRequestApply(t *testing.T) {
+	t.Run("paths", func(t *testing.T) {
+		user := &domain.User{Email: "old@example.com", Age: 20}
+		request := patchRequest(t, `{
+			"schemas": ["`+scim.SchemaPatchOp+`"],
+			"Operations": [
+				{"op": "Replace", "path": "emails[type eq \"work\"].value", "value": "new@example.com"},
+				{"op": "add", "path": "`+scim.SchemaUserExtension+`:age", "value": 31}
+			]
+		}`)
+
+		assert.NoError(t, request.Apply(user))
+		assert.Equal(t, "new@example.com", user.Email)
+		assert.Equal(t, 31, user.Age)
+	})
+
+	t.Run("no path", func(t *testing.T) {
+		user := &domain.User{Email: "old@example.com", Age: 20}
+		request := patchRequest(t, `{
+			"schemas": ["`+scim.SchemaPatchOp+`"],
+			"Operations": [{"op": "replace", "value": {"userName": "new@example.com"}}]
+		}`)
+
+		assert.NoError(t, request.Apply(user))
+		assert.Equal(t, "new@example.com", user.Email)
+		assert.Equal(t, 20, user.Age)
+	})
+
+	for name, tc := range map[string]struct {
+		operations string
+		scimType   string
+	}{
+		"remove":       {`[{"op": "remove", "path": "userName"}]`, scim.ErrNoTarget},
+		"unknown op":   {`[{"op": "move", "path": "userName"}]`, scim.ErrInvalidSyntax},
+		"unknown path": {`[{"op": "add", "path": "nickName", "value": "x"}]`, scim.ErrInvalidPath},
+		"wrong type":   {`[{"op": "add", "path": "age", "value": "x"}]`, scim.ErrInvalidValue},
+		"no ops":       {`[]`, scim.ErrInvalidSyntax},
+	} {
+		t.Run(name, func(t *testing.T) {
+			request := patchRequest(t, `{"schemas": ["`+scim.SchemaPatchOp+`"], "Operations": `+tc.operations+`}`)
+			var scimErr *scim.Error
+			assert.True(t, errors.As(request.Apply(&domain.User{}), &scimErr))
+			assert.Equal(t, tc.scimType, scimErr.ScimType)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Package scim maps users to SCIM 2.0 resources (RFC 7643) and parses the
+// filters and patch requests of the SCIM protocol (RFC 7644).
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const (
+	ContentType = "application/scim+json"
+
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaUserExtension         = "urn:ietf:params:scim:schemas:extension:usermanager:2.0:User"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+)
+
+// scimType values of error responses.
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrInvalidPath   = "invalidPath"
+	ErrInvalidValue  = "invalidValue"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrNoTarget      = "noTarget"
+	ErrUniqueness    = "uniqueness"
+)
+
+// Error is the SCIM error response body.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+func NewError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) StatusCode() int {
+	status, err := strconv.Atoi(e.Status)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// UserExtension holds the attributes of domain.User without a SCIM core
+// counterpart.
+type UserExtension struct {
+	Age int `json:"age"`
+}
+
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Version      string `json:"version,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+// User is the SCIM representation of domain.User. userName and the single
+// email are both the user's email address.
+type User struct {
+	Schemas   []string       `json:"schemas"`
+	ID        string         `json:"id,omitempty"`
+	UserName  string         `json:"userName"`
+	Emails    []Email        `json:"emails,omitempty"`
+	Extension *UserExtension `json:"urn:ietf:params:scim:schemas:extension:usermanager:2.0:User,omitempty"`
+	Meta      *Meta          `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []User   `json:"Resources"`
+}
+
+// FromUser builds the resource served at location.
+func FromUser(user *domain.User, location string) User {
+	return User{
+		Schemas:   []string{SchemaUser, SchemaUserExtension},
+		ID:        user.ID.Hex(),
+		UserName:  user.Email,
+		Emails:    []Email{{Value: user.Email, Type: "work", Primary: true}},
+		Extension: &UserExtension{Age: user.Age},
+		Meta: &Meta{
+			ResourceType: "User",
+			Version:      fmt.Sprintf(`W/"%d"`, user.Version),
+			Location:     location,
+		},
+	}
+}
+
+// ToUser maps a created or replaced resource. The email is taken from
+// userName, or from the primary email when userName is empty.
+func (u *User) ToUser() (*domain.User, error) {
+	email := u.UserName
+	if email == "" {
+		email = primaryEmail(u.Emails)
+	}
+	if email == "" {
+		return nil, NewError(http.StatusBadRequest, ErrInvalidValue, "userName is required")
+	}
+
+	user := &domain.User{Email: email}
+	if u.Extension != nil {
+		user.Age = u.Extension.Age
+	}
+
+	return user, nil
+}
+
+func primaryEmail(emails []Email) string {
+	for _, email := range emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
@@ -0,0 +1,256 @@
+package socket
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const (
+	// writeWait bounds a single write, so a stalled client cannot hold its
+	// writer forever.
+	writeWait = 10 * time.Second
+	// maxMessageSize bounds the messages clients send, which are small.
+	maxMessageSize = 4096
+	// maxSubscriptions bounds the subscriptions of one connection.
+	maxSubscriptions = 32
+)
+
+// Message types sent by clients.
+const (
+	MessageSubscribe   = "subscribe"
+	MessageUnsubscribe = "unsubscribe"
+	MessagePing        = "ping"
+)
+
+// Message types sent by the server.
+const (
+	MessageSubscribed   = "subscribed"
+	MessageUnsubscribed = "unsubscribed"
+	MessageEvent        = "event"
+	MessagePong         = "pong"
+	MessageError        = "error"
+)
+
+// Message is a frame in either direction. ID names the subscription a
+// message is about; events list every subscription they matched.
+type Message struct {
+	Type          string            `json:"type"`
+	ID            string            `json:"id,omitempty"`
+	Filter        *Filter           `json:"filter,omitempty"`
+	Subscriptions []string          `json:"subscriptions,omitempty"`
+	Event         *domain.UserEvent `json:"event,omitempty"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// Filter selects the events of a single user or of the users in an age
+// range. Either bound of the range may be left out.
+type Filter struct {
+	UserID string `json:"user_id,omitempty"`
+	MinAge *int   `json:"min_age,omitempty"`
+	MaxAge *int   `json:"max_age,omitempty"`
+}
+
+type subscription struct {
+	filter Filter
+	userID primitive.ObjectID
+}
+
+func newSubscription(filter Filter) (*subscription, string) {
+	if filter.UserID != "" {
+		if filter.MinAge != nil || filter.MaxAge != nil {
+			return nil, "filter by either user_id or age, not both"
+		}
+		userID, err := primitive.ObjectIDFromHex(filter.UserID)
+		if err != nil {
+			return nil, "invalid user_id"
+		}
+		return &subscription{filter: filter, userID: userID}, ""
+	}
+
+	if filter.MinAge == nil && filter.MaxAge == nil {
+		return nil, "filter requires user_id, min_age or max_age"
+	}
+	if (filter.MinAge != nil && *filter.MinAge < 0) || (filter.MaxAge != nil && *filter.MaxAge < 0) {
+		return nil, "ages must not be negative"
+	}
+	if filter.MinAge != nil && filter.MaxAge != nil && *filter.MinAge > *filter.MaxAge {
+		return nil, "min_age must not exceed max_age"
+	}
+	return &subscription{filter: filter}, ""
+}
+
+// matches reports whether event concerns a user in the age range before or
+// after the change, so entering and leaving the range, and the deletion of
+// a user in it, are delivered however long ago the user got there.
+func (s *subscription) matches(event domain.UserEvent) bool {
+	if s.filter.UserID != "" {
+		return event.UserID == s.userID
+	}
+
+	return (event.User != nil && s.inRange(event.User.Age)) ||
+		(event.Previous != nil && s.inRange(event.Previous.Age))
+}
+
+func (s *subscription) inRange(age int) bool {
+	return (s.filter.MinAge == nil || age >= *s.filter.MinAge) &&
+		(s.filter.MaxAge == nil || age <= *s.filter.MaxAge)
+}
+
+// client is one connection. Its reader handles subscriptions and its
+// writer drains the send queue, which the hub fills without blocking.
+type client struct {
+	conn    *websocket.Conn
+	options Options
+	send    chan Message
+
+	mu            sync.Mutex
+	subscriptions map[string]*subscription
+
+	closeOnce   sync.Once
+	done        chan struct{}
+	closeCode   int
+	closeReason string
+}
+
+func newClient(conn *websocket.Conn, options Options) *client {
+	return &client{
+		conn:          conn,
+		options:       options,
+		send:          make(chan Message, options.SendBuffer),
+		subscriptions: make(map[string]*subscription),
+		done:          make(chan struct{}),
+	}
+}
+
+// close makes the writer send a close frame with code and reason and
+// close the connection. Only the first call has an effect.
+func (c *client) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode, c.closeReason = code, reason
+		close(c.done)
+	})
+}
+
+// enqueue queues message without blocking. A full queue means the client
+// reads slower than changes happen; it is disconnected and may reconnect
+// once it caught up.
+func (c *client) enqueue(message Message) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	select {
+	case c.send <- message:
+	default:
+		c.close(websocket.CloseTryAgainLater, "too many pending messages")
+	}
+}
+
+func (c *client) deliver(event domain.UserEvent) {
+	c.mu.Lock()
+	var ids []string
+	for id, s := range c.subscriptions {
+		if s.matches(event) {
+			ids = append(ids, id)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(ids) == 0 {
+		return
+	}
+	sort.Strings(ids)
+	c.enqueue(Message{Type: MessageEvent, Subscriptions: ids, Event: &event})
+}
+
+func (c *client) readLoop() error {
+	pongWait := 2 * c.options.PingInterval
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		var message Message
+		if err := json.Unmarshal(data, &message); err != nil {
+			c.enqueue(Message{Type: MessageError, Error: "invalid message"})
+			continue
+		}
+		c.enqueue(c.handle(message))
+	}
+}
+
+func (c *client) handle(message Message) Message {
+	switch message.Type {
+	case MessagePing:
+		return Message{Type: MessagePong, ID: message.ID}
+	case MessageSubscribe:
+		if message.ID == "" || message.Filter == nil {
+			return Message{Type: MessageError, ID: message.ID, Error: "subscribe requires an id and a filter"}
+		}
+		s, problem := newSubscription(*message.Filter)
+		if s == nil {
+			return Message{Type: MessageError, ID: message.ID, Error: problem}
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subscriptions[message.ID]; !ok && len(c.subscriptions) >= maxSubscriptions {
+			return Message{Type: MessageError, ID: message.ID, Error: "too many subscriptions"}
+		}
+		// Subscribing with an existing ID replaces its filter.
+		c.subscriptions[message.ID] = s
+		return Message{Type: MessageSubscribed, ID: message.ID}
+	case MessageUnsubscribe:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subscriptions[message.ID]; !ok {
+			return Message{Type: MessageError, ID: message.ID, Error: "unknown subscription"}
+		}
+		delete(c.subscriptions, message.ID)
+		return Message{Type: MessageUnsubscribed, ID: message.ID}
+	default:
+		return Message{Type: MessageError, ID: message.ID, Error: "unknown message type"}
+	}
+}
+
+func (c *client) writeLoop() {
+	defer c.conn.Close()
+
+	ping := time.NewTicker(c.options.PingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(message); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeReason), time.Now().Add(writeWait))
+			return
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package socket pushes user changes to WebSocket clients. Clients subscribe
+// to filters and receive the events of the user event log that match them.
+package socket
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+type Options struct {
+	// PollInterval is how often the event log is checked for new events.
+	PollInterval time.Duration
+	// PingInterval is how often clients are pinged. A client that answers
+	// neither a ping nor sends anything for two intervals is disconnected.
+	PingInterval time.Duration
+	// SendBuffer is the number of messages queued for a client. A client
+	// that falls further behind is disconnected rather than slowing down
+	// the others.
+	SendBuffer int
+}
+
+// Hub reads the event log once for all connections of this instance and
+// hands every event to the connections subscribed to it.
+type Hub struct {
+	eventUsecase domain.UserEventUsecase
+	options      Options
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+}
+
+func NewHub(eventUsecase domain.UserEventUsecase, options Options) *Hub {
+	return &Hub{
+		eventUsecase: eventUsecase,
+		options:      options,
+		clients:      make(map[*client]struct{}),
+	}
+}
+
+// Run polls the event log until ctx is done. Events recorded while nobody
+// is connected are skipped.
+func (h *Hub) Run(ctx context.Context) {
+	poll := time.NewTicker(h.options.PollInterval)
+	defer poll.Stop()
+
+	lastEventID := h.lastEventID(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		}
+
+		if h.count() == 0 || lastEventID == "" {
+			lastEventID = h.lastEventID(ctx)
+			continue
+		}
+
+		for {
+			events, err := h.eventUsecase.Since(ctx, lastEventID, domain.UserEventBatchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Errorf("Failed to read user events: %v", err)
+				}
+				break
+			}
+
+			for _, event := range events {
+				h.broadcast(event)
+				lastEventID = strconv.FormatInt(event.Seq, 10)
+			}
+			if len(events) < domain.UserEventBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// lastEventID returns the position of the newest event, or "" when it cannot
+// be read, in which case the next poll tries again.
+func (h *Hub) lastEventID(ctx context.Context) string {
+	id, err := h.eventUsecase.LastEventID(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Errorf("Failed to read user events: %v", err)
+		}
+		return ""
+	}
+	return id
+}
+
+// Serve talks to an upgraded connection until either side closes it.
+func (h *Hub) Serve(conn *websocket.Conn) {
+	c := newClient(conn, h.options)
+
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.writeLoop()
+	}()
+
+	err := c.readLoop()
+	if err != nil && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		log.Debugf("WebSocket connection closed: %v", err)
+	}
+
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+
+	c.close(websocket.CloseNormalClosure, "")
+	<-written
+}
+
+func (h *Hub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+func (h *Hub) broadcast(event domain.UserEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		c.deliver(event)
+	}
+}
@@ -0,0 +1,176 @@
+package socket_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nebojsaj1726/user-manager/api/socket"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MockUserEventUsecase struct {
+	mu     sync.Mutex
+	events []domain.UserEvent
+	users  map[primitive.ObjectID]*domain.User
+}
+
+func (m *MockUserEventUsecase) Since(c context.Context, lastEventID string, limit int) ([]domain.UserEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seq, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []domain.UserEvent
+	for _, event := range m.events {
+		if event.Seq > seq && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockUserEventUsecase) LastEventID(c context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return strconv.Itoa(len(m.events)), nil
+}
+
+func (m *MockUserEventUsecase) record(eventType string, userID primitive.ObjectID, age int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.users == nil {
+		m.users = make(map[primitive.ObjectID]*domain.User)
+	}
+
+	event := domain.UserEvent{ID: primitive.NewObjectID(), Seq: int64(len(m.events) + 1), Type: eventType, UserID: userID, Previous: m.users[userID]}
+	if eventType != domain.UserEventDeleted {
+		event.User = &domain.User{ID: userID, Age: age, Email: "jane@example.com"}
+	}
+	m.users[userID] = event.User
+	m.events = append(m.events, event)
+}
+
+func TestHub(t *testing.T) {
+	eventMock := &MockUserEventUsecase{}
+	// Mary joins the age range before anyone subscribes.
+	mary := primitive.NewObjectID()
+	eventMock.record(domain.UserEventCreated, mary, 22)
+
+	hub := socket.NewHub(eventMock, socket.Options{PollInterval: 10 * time.Millisecond, PingInterval: time.Minute, SendBuffer: 16})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Serve(conn)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	exchange := func(request socket.Message) socket.Message {
+		assert.NoError(t, conn.WriteJSON(request))
+		return receive(t, conn)
+	}
+
+	jane, john := primitive.NewObjectID(), primitive.NewObjectID()
+	minAge, maxAge := 18, 30
+
+	t.Run("ping", func(t *testing.T) {
+		assert.Equal(t, socket.Message{Type: socket.MessagePong, ID: "1"}, exchange(socket.Message{Type: socket.MessagePing, ID: "1"}))
+	})
+
+	t.Run("invalid subscriptions", func(t *testing.T) {
+		reply := exchange(socket.Message{Type: socket.MessageSubscribe, ID: "bad", Filter: &socket.Filter{UserID: "42"}})
+		assert.Equal(t, socket.MessageError, reply.Type)
+		assert.Equal(t, "invalid user_id", reply.Error)
+
+		reply = exchange(socket.Message{Type: socket.MessageSubscribe, ID: "bad", Filter: &socket.Filter{MinAge: &maxAge, MaxAge: &minAge}})
+		assert.Equal(t, socket.MessageError, reply.Type)
+
+		reply = exchange(socket.Message{Type: socket.MessageUnsubscribe, ID: "bad"})
+		assert.Equal(t, "unknown subscription", reply.Error)
+	})
+
+	t.Run("filtered events", func(t *testing.T) {
+		reply := exchange(socket.Message{Type: socket.MessageSubscribe, ID: "jane", Filter: &socket.Filter{UserID: jane.Hex()}})
+		assert.Equal(t, socket.MessageSubscribed, reply.Type)
+		reply = exchange(socket.Message{Type: socket.MessageSubscribe, ID: "adults", Filter: &socket.Filter{MinAge: &minAge, MaxAge: &maxAge}})
+		assert.Equal(t, socket.MessageSubscribed, reply.Type)
+
+		eventMock.record(domain.UserEventCreated, john, 12)
+		eventMock.record(domain.UserEventCreated, jane, 25)
+		eventMock.record(domain.UserEventUpdated, jane, 40)
+		eventMock.record(domain.UserEventUpdated, john, 20)
+		eventMock.record(domain.UserEventDeleted, john, 0)
+		eventMock.record(domain.UserEventUpdated, mary, 50)
+
+		expected := []struct {
+			eventType     string
+			userID        primitive.ObjectID
+			subscriptions []string
+		}{
+			{domain.UserEventCreated, jane, []string{"adults", "jane"}},
+			// Leaving the range is reported to the range subscription.
+			{domain.UserEventUpdated, jane, []string{"adults", "jane"}},
+			{domain.UserEventUpdated, john, []string{"adults"}},
+			{domain.UserEventDeleted, john, []string{"adults"}},
+			{domain.UserEventUpdated, mary, []string{"adults"}},
+		}
+		for _, want := range expected {
+			message := receive(t, conn)
+			assert.Equal(t, socket.MessageEvent, message.Type)
+			assert.Equal(t, want.subscriptions, message.Subscriptions)
+			if assert.NotNil(t, message.Event) {
+				assert.Equal(t, want.eventType, message.Event.Type)
+				assert.Equal(t, want.userID, message.Event.UserID)
+			}
+		}
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		assert.Equal(t, socket.MessageUnsubscribed, exchange(socket.Message{Type: socket.MessageUnsubscribe, ID: "adults"}).Type)
+
+		eventMock.record(domain.UserEventCreated, john, 20)
+		eventMock.record(domain.UserEventDeleted, jane, 0)
+
+		message := receive(t, conn)
+		assert.Equal(t, []string{"jane"}, message.Subscriptions)
+		assert.Equal(t, domain.UserEventDeleted, message.Event.Type)
+	})
+}
+
+func receive(t *testing.T, conn *websocket.Conn) socket.Message {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message socket.Message
+	if !assert.NoError(t, conn.ReadJSON(&message)) {
+		t.FailNow()
+	}
+	return message
+}
@@ -3,12 +3,16 @@ package bootstrap
 import (
 	log "github.com/sirupsen/logrus"
 
+	"github.com/nebojsaj1726/user-manager/events"
 	"github.com/nebojsaj1726/user-manager/mongo"
 )
 
 type Application struct {
-	Env   *Env
-	Mongo mongo.Client
+	Env    *Env
+	Mongo  mongo.Client
+	Events *events.Bus
+
+	eventSink *events.WriterPublisher
 }
 
 func App() Application {
@@ -20,6 +24,7 @@ func App() Application {
 	app := &Application{}
 	app.Env = NewEnv()
 	app.Mongo = NewMongoDatabase(app.Env)
+	app.Events, app.eventSink = NewEventBus(app.Env)
 
 	log.Infof("Server is now running at %s:%s", app.Env.ServerHost, app.Env.ServerPort)
 	return *app
@@ -28,3 +33,12 @@ func App() Application {
 func (app *Application) CloseDBConnection() {
 	CloseMongoDBConnection(app.Mongo)
 }
+
+func (app *Application) CloseEventSink() {
+	if app.eventSink == nil {
+		return
+	}
+	if err := app.eventSink.Close(); err != nil {
+		log.Errorf("Failed to close event sink: %v", err)
+	}
+}
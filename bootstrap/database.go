@@ -24,6 +24,11 @@ func NewMongoDatabase(env *Env) mongo.Client {
 	if dbUser == "" || dbPass == "" {
 		mongodbURI = fmt.Sprintf("mongodb://%s:%s", dbHost, dbPort)
 	}
+	// A direct connection talks to the host as given, rather than to the
+	// members it names, which may not resolve from here.
+	if env.DBDirectConnection {
+		mongodbURI += "/?directConnection=true"
+	}
 
 	client, err := mongo.NewClient(mongodbURI)
 	if err != nil {
@@ -7,19 +7,75 @@ import (
 )
 
 type Env struct {
-	ServerHost     string `mapstructure:"SERVER_HOST"`
-	ServerPort     string `mapstructure:"SERVER_PORT"`
-	ContextTimeout int    `mapstructure:"CONTEXT_TIMEOUT"`
-	DBHost         string `mapstructure:"DB_HOST"`
-	DBPort         string `mapstructure:"DB_PORT"`
-	DBUser         string `mapstructure:"DB_USER"`
-	DBPass         string `mapstructure:"DB_PASS"`
-	DBName         string `mapstructure:"DB_NAME"`
+	ServerHost           string `mapstructure:"SERVER_HOST"`
+	ServerPort           string `mapstructure:"SERVER_PORT"`
+	GRPCPort             string `mapstructure:"GRPC_PORT"`
+	MetricsPort          string `mapstructure:"METRICS_PORT"`
+	ContextTimeout       int    `mapstructure:"CONTEXT_TIMEOUT"`
+	DBHost               string `mapstructure:"DB_HOST"`
+	DBPort               string `mapstructure:"DB_PORT"`
+	DBUser               string `mapstructure:"DB_USER"`
+	DBPass               string `mapstructure:"DB_PASS"`
+	DBName               string `mapstructure:"DB_NAME"`
+	DBDirectConnection   bool   `mapstructure:"DB_DIRECT_CONNECTION"`
+	RequireIfMatch       bool   `mapstructure:"REQUIRE_IF_MATCH"`
+	IdempotencyTTL       int    `mapstructure:"IDEMPOTENCY_TTL"`
+	IdempotencyLease     int    `mapstructure:"IDEMPOTENCY_LEASE"`
+	BatchMaxOperations   int    `mapstructure:"BATCH_MAX_OPERATIONS"`
+	JobWorkers           int    `mapstructure:"JOB_WORKERS"`
+	OpenAPIValidation    bool   `mapstructure:"OPENAPI_VALIDATION"`
+	APIV1DeprecatedAt    string `mapstructure:"API_V1_DEPRECATED_AT"`
+	APIV1Sunset          string `mapstructure:"API_V1_SUNSET"`
+	GraphQLMaxDepth      int    `mapstructure:"GRAPHQL_MAX_DEPTH"`
+	GraphQLMaxComplexity int    `mapstructure:"GRAPHQL_MAX_COMPLEXITY"`
+	ScimMaxResults       int    `mapstructure:"SCIM_MAX_RESULTS"`
+	LookupMaxIDs         int    `mapstructure:"LOOKUP_MAX_IDS"`
+	UserEventRetention   int    `mapstructure:"USER_EVENT_RETENTION"`
+	UserEventPoll        int    `mapstructure:"USER_EVENT_POLL"`
+	UserEventHeartbeat   int    `mapstructure:"USER_EVENT_HEARTBEAT"`
+	AccessTokens         string `mapstructure:"ACCESS_TOKENS"`
+	TicketTTL            int    `mapstructure:"TICKET_TTL"`
+	WSPingInterval       int    `mapstructure:"WS_PING_INTERVAL"`
+	WSSendBuffer         int    `mapstructure:"WS_SEND_BUFFER"`
+	WebhookWorkers       int    `mapstructure:"WEBHOOK_WORKERS"`
+	WebhookTimeout       int    `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts   int    `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookDisableAfter  int    `mapstructure:"WEBHOOK_DISABLE_AFTER"`
+	WebhookRetention     int    `mapstructure:"WEBHOOK_RETENTION"`
+	WebhookAllowPrivate  bool   `mapstructure:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+	EventSink            string `mapstructure:"EVENT_SINK"`
+	EventFile            string `mapstructure:"EVENT_FILE"`
+	OutboxRetention      int    `mapstructure:"OUTBOX_RETENTION"`
+	JobRetention         int    `mapstructure:"JOB_RETENTION"`
 }
 
 func NewEnv() *Env {
 	env := Env{}
 	viper.SetConfigFile(".env")
+	viper.SetDefault("GRPC_PORT", "9090")
+	viper.SetDefault("METRICS_PORT", "9100")
+	viper.SetDefault("IDEMPOTENCY_TTL", 24)
+	viper.SetDefault("IDEMPOTENCY_LEASE", 60)
+	viper.SetDefault("BATCH_MAX_OPERATIONS", 500)
+	viper.SetDefault("JOB_WORKERS", 2)
+	viper.SetDefault("GRAPHQL_MAX_DEPTH", 8)
+	viper.SetDefault("GRAPHQL_MAX_COMPLEXITY", 500)
+	viper.SetDefault("SCIM_MAX_RESULTS", 200)
+	viper.SetDefault("LOOKUP_MAX_IDS", 100)
+	viper.SetDefault("USER_EVENT_RETENTION", 24)
+	viper.SetDefault("USER_EVENT_POLL", 1)
+	viper.SetDefault("USER_EVENT_HEARTBEAT", 15)
+	viper.SetDefault("TICKET_TTL", 30)
+	viper.SetDefault("WS_PING_INTERVAL", 30)
+	viper.SetDefault("WS_SEND_BUFFER", 256)
+	viper.SetDefault("WEBHOOK_WORKERS", 2)
+	viper.SetDefault("WEBHOOK_TIMEOUT", 10)
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_DISABLE_AFTER", 20)
+	viper.SetDefault("WEBHOOK_RETENTION", 30)
+	viper.SetDefault("EVENT_FILE", "events.ndjson")
+	viper.SetDefault("OUTBOX_RETENTION", 24)
+	viper.SetDefault("JOB_RETENTION", 24)
 
 	err := viper.ReadInConfig()
 	if err != nil {
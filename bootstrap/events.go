@@ -0,0 +1,36 @@
+package bootstrap
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nebojsaj1726/user-manager/events"
+)
+
+// NewEventBus returns the bus domain events are published to, with the sink
+// chosen by EVENT_SINK subscribed. Broker adapters subscribe to the bus
+// through events.NewBrokerPublisher.
+func NewEventBus(env *Env) (*events.Bus, *events.WriterPublisher) {
+	bus := events.NewBus()
+
+	var sink *events.WriterPublisher
+	switch env.EventSink {
+	case "":
+		return bus, nil
+	case "stdout":
+		sink = events.NewWriterPublisher(os.Stdout)
+	case "file":
+		var err error
+		sink, err = events.OpenFile(env.EventFile)
+		if err != nil {
+			log.Fatalf("Failed to open event file: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown event sink %q", env.EventSink)
+	}
+
+	bus.Subscribe(sink)
+	log.Infof("Publishing domain events to %s", env.EventSink)
+	return bus, sink
+}
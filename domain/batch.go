@@ -0,0 +1,52 @@
+package domain
+
+const (
+	BatchMethodCreate = "create"
+	BatchMethodUpdate = "update"
+	BatchMethodDelete = "delete"
+
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+)
+
+// BatchOperation is a single create, update or delete inside a batch. ID and
+// Version are used by update and delete, User by create and update.
+type BatchOperation struct {
+	Method  string `json:"method" xml:"method"`
+	ID      string `json:"id,omitempty" xml:"id,omitempty"`
+	Version int64  `json:"version,omitempty" xml:"version,omitempty"`
+	User    *User  `json:"user,omitempty" xml:"user,omitempty"`
+}
+
+// BatchOptions control how a batch is applied.
+type BatchOptions struct {
+	// Atomic applies either every operation or none of them.
+	Atomic bool
+	// RequireVersion rejects updates and deletes without a version, as
+	// single writes are rejected without If-Match.
+	RequireVersion bool
+}
+
+type BatchRequest struct {
+	Mode       string           `json:"mode" xml:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []BatchOperation `json:"operations" xml:"operations>operation" binding:"required,min=1"`
+}
+
+type BatchItemResult struct {
+	Index int
+	User  *User
+	Err   error
+}
+
+type BatchResult struct {
+	Index  int    `json:"index" xml:"index"`
+	Status int    `json:"status" xml:"status"`
+	User   *User  `json:"user,omitempty" xml:"user,omitempty"`
+	Code   string `json:"code,omitempty" xml:"code,omitempty"`
+	Error  string `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Message string        `json:"message,omitempty" xml:"message,omitempty"`
+	Results []BatchResult `json:"results" xml:"results>result"`
+}
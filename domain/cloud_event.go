@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	ContentTypeCloudEvents = "application/cloudevents+json"
+)
+
+// CloudEvent is a CloudEvents 1.0 envelope in its JSON format.
+type CloudEvent struct {
+	SpecVersion     string          `bson:"specversion" json:"specversion"`
+	ID              string          `bson:"id" json:"id"`
+	Source          string          `bson:"source" json:"source"`
+	Type            string          `bson:"type" json:"type"`
+	Subject         string          `bson:"subject,omitempty" json:"subject,omitempty"`
+	Time            time.Time       `bson:"time" json:"time"`
+	DataContentType string          `bson:"datacontenttype,omitempty" json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `bson:"data,omitempty" json:"data,omitempty"`
+}
+
+// EventPublisher hands domain events to whoever integrates with them.
+// Events are published after the changes they describe were stored.
+type EventPublisher interface {
+	Publish(c context.Context, events []CloudEvent) error
+}
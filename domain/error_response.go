@@ -1,5 +0,0 @@
-package domain
-
-type ErrorResponse struct {
-	Message string `json:"message"`
-}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of failure reported by usecases and repositories. Transports test
+// for them with errors.Is and choose their own status codes.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	// ErrInternal is a failure whose cause is not meant for clients. It is
+	// logged where it is reported.
+	ErrInternal = errors.New("internal error")
+)
+
+// Error is a failure of one of the kinds above, with a message that can be
+// shown to clients. Fields is set when the failure can be pinned to input
+// fields. Code, when set, is a stable identifier clients can rely on and
+// translations are keyed by; Params holds the values the message was built
+// from.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Params  map[string]interface{}
+	Fields  []FieldError
+}
+
+// FieldError explains why one input field was rejected. Code is a stable
+// identifier such as "required" or "unique", and Params holds the values the
+// message was built from, such as the minimum of a range.
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func NewNotFoundError(format string, args ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func NewConflictError(format string, args ...interface{}) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+func NewValidationError(format string, args ...interface{}) error {
+	return &Error{Kind: ErrValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+func NewCodedError(kind error, code string, params map[string]interface{}, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Code: code, Message: fmt.Sprintf(format, args...), Params: params}
+}
+
+// NewFieldError reports rejected fields as an error of kind, with their
+// messages joined into one.
+func NewFieldError(kind error, fields ...FieldError) error {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	return &Error{Kind: kind, Message: strings.Join(messages, "; "), Fields: fields}
+}
+
+var (
+	ErrUserNotFound            = NewCodedError(ErrNotFound, "user_not_found", nil, "user not found")
+	ErrJobNotFound             = NewCodedError(ErrNotFound, "job_not_found", nil, "job not found")
+	ErrWebhookNotFound         = NewCodedError(ErrNotFound, "webhook_not_found", nil, "webhook not found")
+	ErrWebhookDeliveryNotFound = NewCodedError(ErrNotFound, "webhook_delivery_not_found", nil, "webhook delivery not found")
+	// ErrVersionMismatch is a conflict with the version the client expected,
+	// which HTTP reports as a failed If-Match precondition.
+	ErrVersionMismatch = NewCodedError(ErrConflict, "version_mismatch", nil, "version mismatch: user has been modified")
+	// ErrVersionRequired rejects a write without the version it expects
+	// when versions are required, which HTTP reports as a missing If-Match.
+	ErrVersionRequired = NewCodedError(ErrValidation, "version_required", nil, "version is required")
+	ErrEmailNotUnique  = &Error{
+		Kind:    ErrConflict,
+		Code:    "email_not_unique",
+		Message: "email must be unique",
+		Fields:  []FieldError{{Field: "email", Code: "unique", Message: "email must be unique"}},
+	}
+	// ErrBatchAborted is reported for the valid operations of an atomic batch
+	// that was not applied because another operation failed.
+	ErrBatchAborted = NewCodedError(ErrConflict, "batch_aborted", nil, "batch aborted: another operation failed")
+	// ErrOperationFailed is reported for a batch operation that failed for
+	// an unexpected reason.
+	ErrOperationFailed = NewCodedError(ErrInternal, "internal_error", nil, "the operation failed unexpectedly")
+)
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	CollectionIdempotency = "idempotency_keys"
+)
+
+// IdempotencyRecord holds the first response produced for an Idempotency-Key
+// so that retries of the same request can be answered without re-running it.
+// Lease identifies the request holding the key while it runs.
+type IdempotencyRecord struct {
+	Key         string            `bson:"_id"`
+	Lease       string            `bson:"lease"`
+	Fingerprint string            `bson:"fingerprint"`
+	Completed   bool              `bson:"completed"`
+	StatusCode  int               `bson:"status_code,omitempty"`
+	Header      map[string]string `bson:"header,omitempty"`
+	Body        []byte            `bson:"body,omitempty"`
+	ExpiresAt   time.Time         `bson:"expires_at"`
+}
+
+type IdempotencyRepository interface {
+	EnsureIndexes(c context.Context) error
+	// Acquire stores an in-progress record and reports false when a live
+	// record for the same key already exists.
+	Acquire(c context.Context, record *IdempotencyRecord) (bool, error)
+	// GetByKey returns an ErrNotFound error when there is no live record
+	// for key.
+	GetByKey(c context.Context, key string) (*IdempotencyRecord, error)
+	// Complete stores the response of record and reports false when its
+	// lease ended and another request has acquired the key since.
+	Complete(c context.Context, record *IdempotencyRecord) (bool, error)
+	// Release frees the key of record unless another request has acquired
+	// it since.
+	Release(c context.Context, record *IdempotencyRecord) error
+}
@@ -0,0 +1,28 @@
+package domain
+
+// ImportRow is one parsed record of an import file. Err is set when the
+// record could not be turned into a user, e.g. a non-numeric age.
+type ImportRow struct {
+	Line int
+	User *User
+	Err  error
+}
+
+// UserSource yields import rows one at a time and returns io.EOF once the
+// input is exhausted, so imports never hold the whole file in memory.
+type UserSource interface {
+	Next() (*ImportRow, error)
+}
+
+type ImportRowResult struct {
+	Line     int    `json:"line"`
+	Accepted bool   `json:"accepted"`
+	User     *User  `json:"user,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ImportSummary struct {
+	DryRun   bool `json:"dry_run"`
+	Accepted int  `json:"accepted"`
+	Rejected int  `json:"rejected"`
+}
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionJob = "jobs"
+	BucketJobFile = "job_files"
+)
+
+const (
+	JobTypeImport = "import"
+	JobTypeExport = "export"
+	JobTypeBatch  = "batch"
+)
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCanceled  = "canceled"
+)
+
+// Error codes of failed jobs, besides the codes of domain errors.
+const (
+	JobErrorInterrupted = "job_interrupted"
+	JobErrorInternal    = "internal_error"
+)
+
+// Parameters of the user jobs.
+const (
+	JobParamDryRun = "dry_run"
+	JobParamReport = "report"
+	JobParamFormat = "format"
+	// JobParamRequireVersion rejects the updates and deletes of a batch job
+	// without a version.
+	JobParamRequireVersion = "require_version"
+	// JobParamMapping prefixes the header mapping of import jobs.
+	JobParamMapping = "map."
+)
+
+// Job is a long-running operation processed in the background by the
+// worker pool. Its input and result files are kept by the
+// JobFileRepository. BasePath is the path prefix of the API version the
+// job was submitted under, e.g. "/v2". A failed job reports the code and
+// message of a domain error in ErrorCode and Error. Finished jobs and their
+// files are deleted once ExpiresAt has passed.
+type Job struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type              string             `bson:"type" json:"type"`
+	Status            string             `bson:"status" json:"status"`
+	Params            map[string]string  `bson:"params,omitempty" json:"params,omitempty"`
+	Processed         int64              `bson:"processed" json:"processed"`
+	Total             int64              `bson:"total,omitempty" json:"total,omitempty"`
+	ResultContentType string             `bson:"result_content_type,omitempty" json:"-"`
+	ResultLocation    string             `bson:"result_location,omitempty" json:"result_location,omitempty"`
+	BasePath          string             `bson:"base_path,omitempty" json:"-"`
+	ErrorCode         string             `bson:"error_code,omitempty" json:"error_code,omitempty"`
+	Error             string             `bson:"error,omitempty" json:"error,omitempty"`
+	CancelRequested   bool               `bson:"cancel_requested" json:"cancel_requested"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+	StartedAt         *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt        *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	ExpiresAt         *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// JobProgress is updated by a JobHandler while it runs and persisted
+// periodically by the worker pool.
+type JobProgress interface {
+	Add(n int64)
+	SetTotal(total int64)
+}
+
+// JobHandler executes one job type. input is nil for jobs submitted without
+// a payload; whatever is written to output becomes the job result.
+type JobHandler func(ctx context.Context, job *Job, input io.Reader, output io.Writer, progress JobProgress) error
+
+// JobFileRepository stores the input and result files of jobs where every
+// instance sharing the job queue can read them.
+type JobFileRepository interface {
+	// Create returns a writer for the file name, replacing any earlier file
+	// of that name. The file is stored once the writer is closed.
+	Create(c context.Context, name string) (io.WriteCloser, error)
+	// Open returns os.ErrNotExist when there is no file name.
+	Open(c context.Context, name string) (io.ReadCloser, error)
+	Delete(c context.Context, name string) error
+}
+
+type JobRepository interface {
+	// EnsureIndexes creates the indexes the queue is polled with and the
+	// TTL index that removes jobs left behind once they have expired.
+	EnsureIndexes(c context.Context) error
+	Create(c context.Context, job *Job) error
+	GetByID(c context.Context, id string) (*Job, error)
+	// Claim marks the oldest queued job as running and returns it, or
+	// returns mongo.ErrNoDocuments when the queue is empty.
+	Claim(c context.Context) (*Job, error)
+	// Heartbeat stores progress and reports whether cancellation was requested.
+	Heartbeat(c context.Context, id primitive.ObjectID, processed, total int64) (bool, error)
+	Finish(c context.Context, job *Job) error
+	// Cancel cancels a queued job, to expire at expiresAt, or asks the
+	// worker running it to stop.
+	Cancel(c context.Context, id string, expiresAt time.Time) error
+	// FailStale fails running jobs whose last heartbeat is older than before,
+	// e.g. because the process running them crashed.
+	FailStale(c context.Context, before, expiresAt time.Time) (int64, error)
+	// Expired returns up to limit jobs whose expiry has passed.
+	Expired(c context.Context, limit int64) ([]Job, error)
+	Delete(c context.Context, id primitive.ObjectID) error
+}
+
+type JobUsecase interface {
+	Submit(c context.Context, job *Job, input io.Reader) error
+	GetByID(c context.Context, id string) (*Job, error)
+	Cancel(c context.Context, id string) (*Job, error)
+	OpenResult(c context.Context, id string) (*Job, io.ReadCloser, error)
+	Claim(c context.Context) (*Job, io.ReadCloser, io.WriteCloser, error)
+	Heartbeat(c context.Context, job *Job, processed, total int64) (bool, error)
+	Finish(c context.Context, job *Job, err error) error
+	FailStale(c context.Context, staleAfter time.Duration) (int64, error)
+	// Expire deletes the expired jobs along with their files.
+	Expire(c context.Context) (int64, error)
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "encoding/json"
+
+const (
+	ContentTypeJSONPatch = "application/json-patch+json"
+)
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
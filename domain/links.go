@@ -0,0 +1,11 @@
+package domain
+
+// Links are the hypermedia links of a resource or a page of resources,
+// named by their RFC 8288 relation types.
+type Links struct {
+	Self  string `json:"self,omitempty" xml:"self,omitempty"`
+	First string `json:"first,omitempty" xml:"first,omitempty"`
+	Prev  string `json:"prev,omitempty" xml:"prev,omitempty"`
+	Next  string `json:"next,omitempty" xml:"next,omitempty"`
+	Last  string `json:"last,omitempty" xml:"last,omitempty"`
+}
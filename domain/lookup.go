@@ -0,0 +1,15 @@
+package domain
+
+// UserLookupRequest resolves users by ID and by email in one request.
+type UserLookupRequest struct {
+	IDs    []string `json:"ids" xml:"ids>id"`
+	Emails []string `json:"emails" xml:"emails>email" binding:"dive,email"`
+}
+
+// UserLookup is the result of a lookup: the users that were found and the
+// requested IDs and emails that matched none.
+type UserLookup struct {
+	Users         []User   `json:"users" xml:"users>user"`
+	MissingIDs    []string `json:"missing_ids" xml:"missing_ids>id"`
+	MissingEmails []string `json:"missing_emails" xml:"missing_emails>email"`
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionOutbox = "outbox"
+)
+
+// OutboxEntry is an event waiting to be published. It is written in the
+// transaction of the change it describes, so no change is stored without
+// it, and stays pending until a publisher accepted it.
+type OutboxEntry struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Event     CloudEvent         `bson:"event"`
+	Attempts  int                `bson:"attempts"`
+	LastError string             `bson:"last_error,omitempty"`
+	// LockedUntil keeps other relays from publishing an entry while one
+	// holds it; Lock identifies that relay's claim.
+	LockedUntil time.Time          `bson:"locked_until"`
+	Lock        primitive.ObjectID `bson:"lock,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	PublishedAt *time.Time         `bson:"published_at,omitempty"`
+}
+
+// Transactor runs fn in a transaction. Repositories called with the context
+// passed to fn take part in it; fn may run more than once when the
+// transaction is retried.
+type Transactor interface {
+	WithTransaction(c context.Context, fn func(c context.Context) error) error
+}
+
+type OutboxRepository interface {
+	// EnsureIndexes creates the indexes of the outbox, including the one
+	// removing entries published longer than retention ago.
+	EnsureIndexes(c context.Context, retention time.Duration) error
+	Add(c context.Context, entries []OutboxEntry) error
+	// Claim locks up to limit pending entries for lease, oldest first.
+	Claim(c context.Context, limit int, lease time.Duration) ([]OutboxEntry, error)
+	MarkPublished(c context.Context, ids []primitive.ObjectID) error
+	// Release unlocks entries that failed to publish after retryAfter.
+	Release(c context.Context, ids []primitive.ObjectID, reason string, retryAfter time.Duration) error
+}
+
+type OutboxUsecase interface {
+	// Relay publishes a batch of pending entries and returns how many it
+	// published.
+	Relay(c context.Context) (int, error)
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "net/http"
+
+const ContentTypeProblem = "application/problem+json"
+
+// Problem is an RFC 7807 problem details response. It is also an error, so
+// handlers can report failures of the HTTP exchange itself, such as a
+// missing header, with a status no domain error kind maps to. Code and
+// Errors are extension members: a stable identifier of the problem, as for
+// Error, and the rejected fields of invalid input.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Code     string       `json:"code,omitempty"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// Params holds the values the detail was built from, for translations.
+	Params map[string]interface{} `json:"-"`
+}
+
+// NewProblem returns a problem whose type carries no meaning beyond the
+// status code.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WithCode sets the code of the problem and the values its detail was built
+// from.
+func (p *Problem) WithCode(code string, params map[string]interface{}) *Problem {
+	p.Code = code
+	p.Params = params
+	return p
+}
+
+func (p *Problem) Error() string {
+	return p.Detail
+}
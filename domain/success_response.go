@@ -1,5 +1,5 @@
 package domain
 
 type SuccessResponse struct {
-	Message string `json:"message"`
+	Message string `json:"message" xml:"message"`
 }
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	CollectionTicket = "tickets"
+)
+
+// Ticket lets a client that cannot send an Authorization header, such as a
+// browser EventSource or WebSocket, authenticate one request. Only the hash
+// of the ticket is stored.
+type Ticket struct {
+	Hash      string    `bson:"_id"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// TicketResponse carries a newly issued ticket to the client.
+type TicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TicketRepository interface {
+	EnsureIndexes(c context.Context) error
+	Create(c context.Context, ticket *Ticket) error
+	// Consume deletes the ticket and reports whether it existed and had not
+	// expired.
+	Consume(c context.Context, hash string) (bool, error)
+}
+
+type TicketUsecase interface {
+	Issue(c context.Context) (*TicketResponse, error)
+	// Redeem reports whether the ticket is valid; a ticket is accepted once.
+	Redeem(c context.Context, ticket string) (bool, error)
+}
@@ -10,27 +10,120 @@ const (
 	CollectionUser = "users"
 )
 
+// AnyVersion disables the optimistic concurrency check on writes. Any other
+// User.Version passed to a write must match the stored version. Stored
+// versions start at 1, so AnyVersion never names a real version.
+const AnyVersion int64 = 0
+
 type User struct {
-	ID    primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Age   int                `bson:"age" form:"age" json:"age"`
-	Email string             `bson:"email" form:"email" binding:"required,email" json:"email"`
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty" xml:"id,omitempty"`
+	Age     int                `bson:"age" form:"age" json:"age" xml:"age"`
+	Email   string             `bson:"email" form:"email" binding:"required,email" json:"email" xml:"email"`
+	Version int64              `bson:"version,omitempty" form:"-" json:"version" xml:"version"`
+}
+
+// UserFilter narrows the users returned by list and export endpoints. Zero
+// values leave the corresponding field unfiltered.
+type UserFilter struct {
+	Email  string
+	MinAge int
+	MaxAge int
+	// Text matches users with an email word starting with each of its
+	// terms, so fragments such as "jan" find "jane@example.com".
+	Text string
+	// Expression further restricts the users when set.
+	Expression *FilterExpression
+}
+
+// UserSearchHit is a user matched by a search; higher scores rank first.
+// Text searches score by how many terms are whole words of the email, fuzzy
+// searches by similarity and also report the edit Distance to the text.
+type UserSearchHit struct {
+	User       `bson:",inline"`
+	Score      float64           `bson:"score" json:"score" xml:"score"`
+	Distance   *int              `bson:"-" json:"distance,omitempty" xml:"distance,omitempty"`
+	Highlights []SearchHighlight `bson:"-" json:"highlights,omitempty" xml:"highlights>highlight,omitempty"`
+}
+
+// SearchHighlight is the value of a matched field with the matched terms
+// wrapped in <em> tags.
+type SearchHighlight struct {
+	Field    string `json:"field" xml:"field"`
+	Fragment string `json:"fragment" xml:"fragment"`
 }
 
 type UserRepository interface {
+	// EnsureIndexes creates the indexes the queries rely on, including the
+	// index on email words used by Search.
+	EnsureIndexes(c context.Context) error
+	// BackfillVersions sets version 1 on users stored before versions were
+	// tracked and returns how many users it updated.
+	BackfillVersions(c context.Context) (int64, error)
 	Create(c context.Context, user *User) error
-	Fetch(c context.Context, offset, limit int) ([]User, error)
+	Fetch(c context.Context, filter UserFilter, offset, limit int) ([]User, error)
+	Stream(c context.Context, filter UserFilter, fn func(*User) error) error
 	FetchByEmail(c context.Context, email string) ([]User, error)
+	FetchByIDs(c context.Context, ids []string) ([]User, error)
+	// FetchByIDsOrEmails returns the users matching any of ids or emails
+	// with a single query.
+	FetchByIDsOrEmails(c context.Context, ids, emails []string) ([]User, error)
 	GetByID(c context.Context, id string) (*User, error)
+	// Search returns the users matching filter.Text, most relevant first.
+	Search(c context.Context, filter UserFilter, offset, limit int) ([]UserSearchHit, error)
+	// StreamSimilar calls fn for the users whose emails may be within
+	// maxEdits typos of filter.Text, judged by the trigrams they share, for
+	// typo-tolerant search. The users are not in any particular order.
+	StreamSimilar(c context.Context, filter UserFilter, maxEdits int, fn func(*User) error) error
+	// Reindex stores the search words and trigrams of users written before
+	// they were maintained and returns how many users it updated.
+	Reindex(c context.Context) (int64, error)
 	Update(c context.Context, id string, user *User) error
-	Delete(c context.Context, id string) error
-	Count(ctx context.Context) (int64, error)
+	Delete(c context.Context, id string, version int64) error
+	// ApplyBatch writes the operations with a single bulk write in a
+	// transaction, so either all operations are applied or none are.
+	ApplyBatch(c context.Context, ops []BatchOperation) error
+	Count(ctx context.Context, filter UserFilter) (int64, error)
 }
 
 type UserUsecase interface {
 	Create(c context.Context, user *User) error
-	Fetch(c context.Context, page, limit int) ([]User, error)
+	Fetch(c context.Context, filter UserFilter, page, limit int) ([]User, error)
+	// FetchRange is Fetch for callers that page by position, such as SCIM
+	// startIndex or GraphQL cursors, rather than by page number.
+	FetchRange(c context.Context, filter UserFilter, offset, limit int) ([]User, error)
+	// Export calls fn for every user matching filter. It is not bound by the
+	// usecase timeout so large collections can be streamed to completion.
+	Export(c context.Context, filter UserFilter, fn func(*User) error) error
 	GetByID(c context.Context, id string) (*User, error)
+	// Lookup resolves many users at once. Duplicate IDs and emails are
+	// looked up once and users matching both an ID and an email are returned
+	// once, in the order of the request.
+	Lookup(c context.Context, ids, emails []string) (*UserLookup, error)
+	// Search ranks the users matching filter.Text by relevance and
+	// highlights the matched terms.
+	Search(c context.Context, filter UserFilter, page, limit int) ([]UserSearchHit, error)
+	// FuzzySearch ranks the users whose emails are within a few typos of
+	// filter.Text by edit distance and returns the page with the number of
+	// matches.
+	FuzzySearch(c context.Context, filter UserFilter, page, limit int) ([]UserSearchHit, int64, error)
+	// Suggest returns up to limit emails of the users matching filter that
+	// are close to filter.Text, closest first, for "did you mean" hints.
+	Suggest(c context.Context, filter UserFilter, limit int) ([]string, error)
 	Update(c context.Context, id string, user *User) error
-	Delete(c context.Context, id string) error
-	Count(c context.Context) (int64, error)
+	// Validate checks user against the rules Create and Update enforce,
+	// without writing it. id is the user being replaced, or empty for a new
+	// user.
+	Validate(c context.Context, id string, user *User) error
+	Patch(c context.Context, id string, version int64, ops []PatchOperation) (*User, error)
+	Delete(c context.Context, id string, version int64) error
+	Batch(c context.Context, ops []BatchOperation, options BatchOptions) ([]BatchItemResult, error)
+	// RunBatch is Batch without the usecase timeout, for batches applied by
+	// background jobs.
+	RunBatch(c context.Context, ops []BatchOperation, options BatchOptions) ([]BatchItemResult, error)
+	// Import creates the users read from source and reports every row as
+	// soon as its outcome is known. With dryRun nothing is written, so an
+	// email used twice is only reported when both rows fall in the same
+	// chunk of 100 rows.
+	Import(c context.Context, source UserSource, dryRun bool, report func(ImportRowResult) error) (*ImportSummary, error)
+	Count(c context.Context, filter UserFilter) (int64, error)
 }
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionUserEvent = "user_events"
+	// CollectionCounter holds the sequences of the event logs, one document
+	// per log.
+	CollectionCounter = "counters"
+)
+
+const (
+	UserEventCreated = "created"
+	UserEventUpdated = "updated"
+	UserEventDeleted = "deleted"
+)
+
+// UserEventBatchSize is the number of events the streams read from the log
+// at once.
+const UserEventBatchSize = 100
+
+// UserEvent records a change to a user. Seq numbers the events in the order
+// their transactions commit, so the Seq of the last event a client saw is
+// where it resumes. User is the user after the change and Previous before
+// it, so deleted events carry no User and created events no Previous.
+type UserEvent struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	Seq      int64              `bson:"seq" json:"seq"`
+	Type     string             `bson:"type" json:"type"`
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	User     *User              `bson:"user,omitempty" json:"user,omitempty"`
+	Previous *User              `bson:"previous,omitempty" json:"previous,omitempty"`
+	Time     time.Time          `bson:"time" json:"time"`
+}
+
+type UserEventRepository interface {
+	// EnsureIndexes creates the indexes of the log, including the one
+	// expiring events older than retention.
+	EnsureIndexes(c context.Context, retention time.Duration) error
+	// Append assigns the next sequence numbers to events and writes them.
+	// Run in a transaction, it holds back other appends until the
+	// transaction ends, so events commit in the order of their numbers.
+	Append(c context.Context, events []UserEvent) error
+	// After returns up to limit events following the event numbered seq,
+	// oldest first.
+	After(c context.Context, seq int64, limit int) ([]UserEvent, error)
+	// Last returns the number of the latest event, or 0 when there is none.
+	Last(c context.Context) (int64, error)
+}
+
+type UserEventUsecase interface {
+	// Since returns up to limit events recorded after the event numbered
+	// lastEventID, oldest first.
+	Since(c context.Context, lastEventID string, limit int) ([]UserEvent, error)
+	// LastEventID returns the position of a stream that starts now.
+	LastEventID(c context.Context) (string, error)
+}
@@ -0,0 +1,27 @@
+package domain
+
+const (
+	FilterAnd = "and"
+	FilterOr  = "or"
+
+	FilterEqual      = "eq"
+	FilterContains   = "co"
+	FilterStartsWith = "sw"
+)
+
+// Attributes a FilterExpression can compare.
+const (
+	FilterAttributeID    = "id"
+	FilterAttributeEmail = "email"
+	FilterAttributeAge   = "age"
+)
+
+// FilterExpression is a boolean expression over user attributes, such as a
+// parsed SCIM filter. FilterAnd and FilterOr combine Operands; the other
+// operators compare Attribute with Value. String comparisons ignore case.
+type FilterExpression struct {
+	Operator  string
+	Attribute string
+	Value     interface{}
+	Operands  []FilterExpression
+}
@@ -0,0 +1,56 @@
+package domain
+
+type UserResponse struct {
+	Message string `json:"message" xml:"message"`
+	User    *User  `json:"user" xml:"user"`
+}
+
+// UserItem is a user in a list response, with the link to the user.
+type UserItem struct {
+	User
+	Links *Links `json:"links,omitempty" xml:"links,omitempty"`
+}
+
+// UserSearchItem is UserItem for search results.
+type UserSearchItem struct {
+	UserSearchHit
+	Links *Links `json:"links,omitempty" xml:"links,omitempty"`
+}
+
+// UserListResponse lists users. Suggestions holds emails close to an email
+// filter that matched nothing.
+type UserListResponse struct {
+	Users       []UserItem `json:"users" xml:"users>user"`
+	Total       int64      `json:"total" xml:"total"`
+	Suggestions []string   `json:"suggestions,omitempty" xml:"suggestions>suggestion,omitempty"`
+	Links       *Links     `json:"links,omitempty" xml:"links,omitempty"`
+}
+
+// UserSearchResponse lists search results. Suggestions holds close matches
+// when nothing matched.
+type UserSearchResponse struct {
+	Users       []UserSearchItem `json:"users" xml:"users>user"`
+	Total       int64            `json:"total" xml:"total"`
+	Suggestions []string         `json:"suggestions,omitempty" xml:"suggestions>suggestion,omitempty"`
+	Links       *Links           `json:"links,omitempty" xml:"links,omitempty"`
+}
+
+// UserPage is the list envelope of API v2.
+type UserPage struct {
+	Data        []UserItem `json:"data" xml:"data>user"`
+	Page        int        `json:"page" xml:"page"`
+	Limit       int        `json:"limit" xml:"limit"`
+	Total       int64      `json:"total" xml:"total"`
+	Suggestions []string   `json:"suggestions,omitempty" xml:"suggestions>suggestion,omitempty"`
+	Links       *Links     `json:"links,omitempty" xml:"links,omitempty"`
+}
+
+// UserSearchPage is UserPage for search results.
+type UserSearchPage struct {
+	Data        []UserSearchItem `json:"data" xml:"data>user"`
+	Page        int              `json:"page" xml:"page"`
+	Limit       int              `json:"limit" xml:"limit"`
+	Total       int64            `json:"total" xml:"total"`
+	Suggestions []string         `json:"suggestions,omitempty" xml:"suggestions>suggestion,omitempty"`
+	Links       *Links           `json:"links,omitempty" xml:"links,omitempty"`
+}
@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionWebhook         = "webhooks"
+	CollectionWebhookDelivery = "webhook_deliveries"
+)
+
+// Headers of webhook requests, following the Standard Webhooks
+// specification. The signature is "v1," followed by the base64 HMAC-SHA256
+// of "<id>.<timestamp>.<body>", keyed with the decoded secret.
+const (
+	HeaderWebhookID        = "Webhook-Id"
+	HeaderWebhookTimestamp = "Webhook-Timestamp"
+	HeaderWebhookSignature = "Webhook-Signature"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook subscribes a URL to user events. It receives the events recorded
+// after LastEventSeq; a webhook that was disabled catches up with the events
+// still in the log once it is enabled again.
+type Webhook struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL    string             `bson:"url" json:"url"`
+	Events []string           `bson:"events" json:"events"`
+	// Secret is only returned when it is set.
+	Secret       string `bson:"secret" json:"secret,omitempty"`
+	Active       bool   `bson:"active" json:"active"`
+	LastEventSeq int64  `bson:"last_event_seq" json:"-"`
+	// Failures counts the failed attempts since the last successful one.
+	Failures       int       `bson:"failures" json:"failures"`
+	DisabledReason string    `bson:"disabled_reason,omitempty" json:"disabled_reason,omitempty"`
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// WebhookRequest creates or replaces a webhook. A secret is generated when
+// none is given; given secrets use the "whsec_" prefix followed by 24 to 64
+// base64 encoded bytes.
+type WebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=created updated deleted"`
+	Secret string   `json:"secret,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+type WebhookPage struct {
+	Data  []Webhook `json:"data"`
+	Page  int       `json:"page"`
+	Limit int       `json:"limit"`
+	Total int64     `json:"total"`
+}
+
+// WebhookPayload is the body of a webhook request.
+type WebhookPayload struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      UserEvent `json:"data"`
+}
+
+// WebhookDelivery sends one event to one webhook. Its ID is the Webhook-Id
+// of every attempt, so receivers can drop repeated deliveries.
+type WebhookDelivery struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	WebhookID primitive.ObjectID `bson:"webhook_id" json:"webhook_id"`
+	EventID   primitive.ObjectID `bson:"event_id" json:"event_id"`
+	EventType string             `bson:"event_type" json:"event_type"`
+	Payload   json.RawMessage    `bson:"payload" json:"payload"`
+	Status    string             `bson:"status" json:"status"`
+	// Tries counts the attempts since the delivery was queued or replayed.
+	Tries         int              `bson:"tries" json:"tries"`
+	Attempts      []WebhookAttempt `bson:"attempts" json:"attempts"`
+	NextAttemptAt *time.Time       `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time        `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time        `bson:"updated_at" json:"updated_at"`
+}
+
+type WebhookAttempt struct {
+	Time       time.Time `bson:"time" json:"time"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMS int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+type WebhookDeliveryPage struct {
+	Data  []WebhookDelivery `json:"data"`
+	Page  int               `json:"page"`
+	Limit int               `json:"limit"`
+	Total int64             `json:"total"`
+}
+
+type WebhookRepository interface {
+	Create(c context.Context, webhook *Webhook) error
+	GetByID(c context.Context, id string) (*Webhook, error)
+	Fetch(c context.Context, offset, limit int) ([]Webhook, int64, error)
+	FetchActive(c context.Context) ([]Webhook, error)
+	Update(c context.Context, webhook *Webhook) error
+	Delete(c context.Context, id string) error
+	// Advance moves the position of a webhook in the event log forward.
+	Advance(c context.Context, id primitive.ObjectID, lastEventSeq int64) error
+	// Succeeded resets the failures of a webhook.
+	Succeeded(c context.Context, id primitive.ObjectID) error
+	// Failed counts a failed attempt and returns the failures since the
+	// last successful one.
+	Failed(c context.Context, id primitive.ObjectID) (int, error)
+	Disable(c context.Context, id primitive.ObjectID, reason string) error
+}
+
+type WebhookDeliveryRepository interface {
+	// EnsureIndexes creates the indexes of the log, including the one
+	// expiring deliveries older than retention.
+	EnsureIndexes(c context.Context, retention time.Duration) error
+	// Insert stores deliveries, skipping those of an event already queued
+	// for the same webhook.
+	Insert(c context.Context, deliveries []WebhookDelivery) error
+	// Claim returns a pending delivery that is due and postpones it by
+	// lease, so no other worker sends it meanwhile. It returns
+	// mongo.ErrNoDocuments when none is due.
+	Claim(c context.Context, lease time.Duration) (*WebhookDelivery, error)
+	// Finish stores the outcome of the last attempt of a delivery.
+	Finish(c context.Context, delivery *WebhookDelivery) error
+	GetByID(c context.Context, webhookID, id string) (*WebhookDelivery, error)
+	FetchByWebhook(c context.Context, webhookID string, offset, limit int) ([]WebhookDelivery, int64, error)
+	// Replay queues a delivery again, keeping its attempts.
+	Replay(c context.Context, webhookID, id string) (*WebhookDelivery, error)
+}
+
+type WebhookUsecase interface {
+	Create(c context.Context, request *WebhookRequest) (*Webhook, error)
+	GetByID(c context.Context, id string) (*Webhook, error)
+	Fetch(c context.Context, page, limit int) ([]Webhook, int64, error)
+	Update(c context.Context, id string, request *WebhookRequest) (*Webhook, error)
+	Delete(c context.Context, id string) error
+	Deliveries(c context.Context, webhookID string, page, limit int) ([]WebhookDelivery, int64, error)
+	Delivery(c context.Context, webhookID, id string) (*WebhookDelivery, error)
+	Replay(c context.Context, webhookID, id string) (*WebhookDelivery, error)
+	// Dispatch queues deliveries of the events recorded since the last
+	// call and returns how many it queued.
+	Dispatch(c context.Context) (int, error)
+	// Deliver sends one due delivery and reports whether there was one.
+	Deliver(c context.Context) (bool, error)
+}
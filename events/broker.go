@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// Message is a record for a message broker: a NATS subject or Kafka topic,
+// a key that brokers partitioning by key use to keep the events of a user
+// in order, a value and headers.
+type Message struct {
+	Topic   string
+	Key     string
+	Value   []byte
+	Headers map[string]string
+}
+
+// Broker is implemented by adapters of broker clients such as NATS or
+// Kafka. Send returns once the broker accepted the messages.
+type Broker interface {
+	Send(c context.Context, messages []Message) error
+}
+
+// BrokerPublisher publishes events to a broker in the structured content
+// mode of the CloudEvents protocol bindings: the value is the whole event
+// as JSON.
+type BrokerPublisher struct {
+	broker Broker
+	topic  string
+}
+
+func NewBrokerPublisher(broker Broker, topic string) *BrokerPublisher {
+	return &BrokerPublisher{broker: broker, topic: topic}
+}
+
+func (p *BrokerPublisher) Publish(c context.Context, events []domain.CloudEvent) error {
+	messages := make([]Message, len(events))
+	for i, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		messages[i] = Message{
+			Topic:   p.topic,
+			Key:     event.Subject,
+			Value:   value,
+			Headers: map[string]string{"content-type": domain.ContentTypeCloudEvents},
+		}
+	}
+
+	return p.broker.Send(c, messages)
+}
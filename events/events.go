@@ -0,0 +1,87 @@
+// Package events publishes domain events as CloudEvents, in process and
+// through adapters for message brokers and files.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const (
+	// Source identifies this service as the producer of its events.
+	Source = "/user-manager/users"
+	// TypePrefix is followed by the kind of user change, e.g.
+	// "user-manager.user.created".
+	TypePrefix = "user-manager.user."
+)
+
+// FromUserEvent wraps a user change in a CloudEvent. The data is the user
+// after the change, or only its ID for deletions; the event ID is the one
+// of the event log, so consumers of several channels can drop repeats.
+func FromUserEvent(event domain.UserEvent) (domain.CloudEvent, error) {
+	var data interface{} = event.User
+	if event.User == nil {
+		data = map[string]string{"id": event.UserID.Hex()}
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return domain.CloudEvent{}, err
+	}
+
+	return domain.CloudEvent{
+		SpecVersion:     domain.CloudEventsSpecVersion,
+		ID:              event.ID.Hex(),
+		Source:          Source,
+		Type:            TypePrefix + event.Type,
+		Subject:         event.UserID.Hex(),
+		Time:            event.Time.UTC(),
+		DataContentType: "application/json",
+		Data:            encoded,
+	}, nil
+}
+
+// Bus is the in-process publisher. It passes events to its subscribers in
+// the order they subscribed and returns once all of them are done, so
+// subscribers doing slow work should queue it.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []domain.EventPublisher
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(subscriber domain.EventPublisher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+// Publish hands events to every subscriber, also when one of them fails,
+// and returns the errors of all that failed.
+func (b *Bus) Publish(c context.Context, events []domain.CloudEvent) error {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, subscriber := range subscribers {
+		if err := subscriber.Publish(c, events); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PublisherFunc adapts a function to an EventPublisher.
+type PublisherFunc func(c context.Context, events []domain.CloudEvent) error
+
+func (f PublisherFunc) Publish(c context.Context, events []domain.CloudEvent) error {
+	return f(c, events)
+}
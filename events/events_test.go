@@ -0,0 +1,93 @@
+package events_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/events"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MockBroker struct {
+	Messages []events.Message
+}
+
+func (m *MockBroker) Send(ctx context.Context, messages []events.Message) error {
+	m.Messages = append(m.Messages, messages...)
+	return nil
+}
+
+func userEvents() []domain.CloudEvent {
+	userID := primitive.NewObjectID()
+	created, _ := events.FromUserEvent(domain.UserEvent{
+		ID:     primitive.NewObjectID(),
+		Type:   domain.UserEventCreated,
+		UserID: userID,
+		User:   &domain.User{ID: userID, Email: "jane@example.com", Age: 30},
+		Time:   time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+	})
+	deleted, _ := events.FromUserEvent(domain.UserEvent{
+		ID:     primitive.NewObjectID(),
+		Type:   domain.UserEventDeleted,
+		UserID: userID,
+		Time:   time.Date(2026, 10, 19, 12, 5, 0, 0, time.UTC),
+	})
+	return []domain.CloudEvent{created, deleted}
+}
+
+func TestWriterPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	published := userEvents()
+
+	assert.NoError(t, events.NewWriterPublisher(&buf).Publish(context.Background(), published))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		var envelope map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &envelope))
+		assert.Equal(t, "1.0", envelope["specversion"])
+		assert.Equal(t, "user-manager.user.created", envelope["type"])
+		assert.Equal(t, events.Source, envelope["source"])
+		assert.Equal(t, published[0].Subject, envelope["subject"])
+		assert.Equal(t, "2026-10-19T12:00:00Z", envelope["time"])
+		assert.Equal(t, "jane@example.com", envelope["data"].(map[string]interface{})["email"])
+
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &envelope))
+		assert.Equal(t, map[string]interface{}{"id": published[1].Subject}, envelope["data"])
+	}
+}
+
+func TestBus(t *testing.T) {
+	broker := &MockBroker{}
+	failing := events.PublisherFunc(func(c context.Context, events []domain.CloudEvent) error {
+		return errors.New("unavailable")
+	})
+
+	bus := events.NewBus()
+	bus.Subscribe(failing)
+	bus.Subscribe(events.NewBrokerPublisher(broker, "users"))
+
+	published := userEvents()
+	err := bus.Publish(context.Background(), published)
+
+	assert.EqualError(t, err, "unavailable")
+	if assert.Len(t, broker.Messages, 2) {
+		message := broker.Messages[0]
+		assert.Equal(t, "users", message.Topic)
+		assert.Equal(t, published[0].Subject, message.Key)
+		assert.Equal(t, domain.ContentTypeCloudEvents, message.Headers["content-type"])
+
+		var decoded domain.CloudEvent
+		assert.NoError(t, json.Unmarshal(message.Value, &decoded))
+		assert.Equal(t, published[0].ID, decoded.ID)
+	}
+
+	assert.NoError(t, events.NewBus().Publish(context.Background(), published))
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// WriterPublisher writes events as JSON lines, for local use with stdout or
+// a file.
+type WriterPublisher struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{encoder: json.NewEncoder(w)}
+}
+
+// OpenFile appends events to the file at path, creating it if needed.
+func OpenFile(path string) (*WriterPublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher := NewWriterPublisher(file)
+	publisher.closer = file
+	return publisher, nil
+}
+
+func (p *WriterPublisher) Publish(c context.Context, events []domain.CloudEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, event := range events {
+		if err := p.encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the file opened by OpenFile.
+func (p *WriterPublisher) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/nebojsaj1726/user-manager/api/i18n"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
 	"github.com/nebojsaj1726/user-manager/bootstrap"
 	"github.com/nebojsaj1726/user-manager/route"
+	log "github.com/sirupsen/logrus"
 )
 
 func main() {
@@ -18,6 +28,7 @@ func main() {
 
 	db := app.Mongo.Database(env.DBName)
 	defer app.CloseDBConnection()
+	defer app.CloseEventSink()
 
 	timeout := time.Duration(env.ContextTimeout) * time.Second
 
@@ -25,20 +36,71 @@ func main() {
 
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", "Idempotency-Key"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "Idempotent-Replayed", "Location", "API-Version", "Deprecation", "Sunset", "Link"},
 		AllowCredentials: true,
 	}))
 
-	router.SetTrustedProxies([]string{"127.0.0.1"})
+	trustedProxies := []string{"127.0.0.1"}
+	router.SetTrustedProxies(trustedProxies)
+	externalURL, err := middleware.ExternalURL(trustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	router.Use(externalURL, i18n.Negotiate(i18n.Default()))
 
 	router.OPTIONS("/*path", func(c *gin.Context) {
 		c.Status(204)
 	})
-	route.Setup(env, timeout, db, router)
+	// The workers and servers stop on the first signal; a second one ends
+	// the process right away.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	waitWorkers := route.Setup(ctx, env, timeout, db, app.Events, router)
+
+	grpcServer := route.SetupGRPC(env, timeout, db)
+	go func() {
+		grpcAddr := fmt.Sprintf("%s:%s", env.ServerHost, env.GRPCPort)
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC on %s: %v", grpcAddr, err)
+		}
+		log.Infof("gRPC server is now running at %s", grpcAddr)
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Errorf("gRPC server stopped: %v", err)
+		}
+	}()
+
+	go func() {
+		metricsAddr := fmt.Sprintf("%s:%s", env.ServerHost, env.MetricsPort)
+		log.Infof("Metrics are now served at %s", metricsAddr)
+		if err := http.ListenAndServe(metricsAddr, route.NewMetricsHandler()); err != nil {
+			log.Errorf("Metrics server stopped: %v", err)
+		}
+	}()
 
 	addr := fmt.Sprintf("%s:%s", env.ServerHost, env.ServerPort)
-	router.Run(addr)
+	server := &http.Server{Addr: addr, Handler: router}
+	go func() {
+		log.Infof("HTTP server is now running at %s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("HTTP server stopped: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Info("Shutting down")
 
+	// Requests in flight get the usual timeout, plus some time to write
+	// their responses.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout+5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("HTTP server did not shut down cleanly: %v", err)
+	}
+	grpcServer.GracefulStop()
+	waitWorkers()
 }
@@ -2,25 +2,45 @@ package mongo
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 type Database interface {
 	Collection(string) Collection
+	Bucket(string) (Bucket, error)
 	Client() Client
 }
 
+// ErrFileNotFound is returned by Bucket for files that do not exist.
+var ErrFileNotFound = gridfs.ErrFileNotFound
+
+// Bucket stores files in GridFS. Streams stop at the deadline of the context
+// they were opened with, since GridFS streams take no contexts themselves.
+type Bucket interface {
+	OpenUploadStream(ctx context.Context, id interface{}, name string) (io.WriteCloser, error)
+	OpenDownloadStream(ctx context.Context, id interface{}) (io.ReadCloser, error)
+	Delete(ctx context.Context, id interface{}) error
+}
+
 type Collection interface {
 	FindOne(context.Context, interface{}) SingleResult
 	InsertOne(context.Context, interface{}) (interface{}, error)
 	DeleteOne(context.Context, interface{}) (int64, error)
 	Find(context.Context, interface{}, ...*options.FindOptions) (Cursor, error)
+	Aggregate(context.Context, interface{}, ...*options.AggregateOptions) (Cursor, error)
 	UpdateOne(context.Context, interface{}, interface{}, ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(context.Context, interface{}, interface{}, ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	FindOneAndUpdate(context.Context, interface{}, interface{}, ...*options.FindOneAndUpdateOptions) SingleResult
 	CountDocuments(context.Context, interface{}) (int64, error)
+	CreateIndexes(context.Context, []mongo.IndexModel) ([]string, error)
+	DropIndex(context.Context, string) error
+	BulkWrite(context.Context, []mongo.WriteModel, ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
 }
 
 type SingleResult interface {
@@ -32,6 +52,7 @@ type Cursor interface {
 	Next(context.Context) bool
 	Decode(interface{}) error
 	All(context.Context, interface{}) error
+	Err() error
 }
 
 type Client interface {
@@ -52,6 +73,10 @@ type mongoCollection struct {
 	coll *mongo.Collection
 }
 
+type mongoBucket struct {
+	b *gridfs.Bucket
+}
+
 type mongoSingleResult struct {
 	sr *mongo.SingleResult
 }
@@ -100,6 +125,11 @@ func (md *mongoDatabase) Collection(colName string) Collection {
 	return &mongoCollection{coll: collection}
 }
 
+func (md *mongoDatabase) Bucket(name string) (Bucket, error) {
+	bucket, err := gridfs.NewBucket(md.db, options.GridFSBucket().SetName(name))
+	return &mongoBucket{b: bucket}, err
+}
+
 func (md *mongoDatabase) Client() Client {
 	client := md.db.Client()
 	return &mongoClient{cl: client}
@@ -114,6 +144,15 @@ func (mc *mongoCollection) UpdateOne(ctx context.Context, filter interface{}, up
 	return mc.coll.UpdateOne(ctx, filter, update, opts[:]...)
 }
 
+func (mc *mongoCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return mc.coll.UpdateMany(ctx, filter, update, opts...)
+}
+
+func (mc *mongoCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) SingleResult {
+	singleResult := mc.coll.FindOneAndUpdate(ctx, filter, update, opts...)
+	return &mongoSingleResult{sr: singleResult}
+}
+
 func (mc *mongoCollection) InsertOne(ctx context.Context, document interface{}) (interface{}, error) {
 	id, err := mc.coll.InsertOne(ctx, document)
 	return id.InsertedID, err
@@ -129,10 +168,28 @@ func (mc *mongoCollection) Find(ctx context.Context, filter interface{}, opts ..
 	return &mongoCursor{mc: findResult}, err
 }
 
+func (mc *mongoCollection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (Cursor, error) {
+	cursor, err := mc.coll.Aggregate(ctx, pipeline, opts...)
+	return &mongoCursor{mc: cursor}, err
+}
+
 func (mc *mongoCollection) CountDocuments(ctx context.Context, filter interface{}) (int64, error) {
 	return mc.coll.CountDocuments(ctx, filter)
 }
 
+func (mc *mongoCollection) CreateIndexes(ctx context.Context, models []mongo.IndexModel) ([]string, error) {
+	return mc.coll.Indexes().CreateMany(ctx, models)
+}
+
+func (mc *mongoCollection) DropIndex(ctx context.Context, name string) error {
+	_, err := mc.coll.Indexes().DropOne(ctx, name)
+	return err
+}
+
+func (mc *mongoCollection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	return mc.coll.BulkWrite(ctx, models, opts...)
+}
+
 func (sr *mongoSingleResult) Decode(v interface{}) error {
 	return sr.sr.Decode(v)
 }
@@ -149,6 +206,32 @@ func (mr *mongoCursor) Decode(v interface{}) error {
 	return mr.mc.Decode(v)
 }
 
+func (mr *mongoCursor) Err() error {
+	return mr.mc.Err()
+}
+
 func (mr *mongoCursor) All(ctx context.Context, result interface{}) error {
 	return mr.mc.All(ctx, result)
 }
+
+func (mb *mongoBucket) OpenUploadStream(ctx context.Context, id interface{}, name string) (io.WriteCloser, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := mb.b.SetWriteDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	return mb.b.OpenUploadStreamWithID(id, name)
+}
+
+func (mb *mongoBucket) OpenDownloadStream(ctx context.Context, id interface{}) (io.ReadCloser, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := mb.b.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	return mb.b.OpenDownloadStream(id)
+}
+
+func (mb *mongoBucket) Delete(ctx context.Context, id interface{}) error {
+	return mb.b.DeleteContext(ctx, id)
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type idempotencyRepository struct {
+	database   mongo.Database
+	collection string
+}
+
+func NewIdempotencyRepository(db mongo.Database, collection string) domain.IdempotencyRepository {
+	return &idempotencyRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+func (ir *idempotencyRepository) EnsureIndexes(c context.Context) error {
+	collection := ir.database.Collection(ir.collection)
+
+	_, err := collection.CreateIndexes(c, []mongodriver.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+func (ir *idempotencyRepository) Acquire(c context.Context, record *domain.IdempotencyRecord) (bool, error) {
+	collection := ir.database.Collection(ir.collection)
+
+	// Expired records may linger until the TTL monitor removes them, so they
+	// are overwritten here instead of blocking the key.
+	filter := bson.M{"_id": record.Key, "expires_at": bson.M{"$lte": time.Now()}}
+	update := bson.M{"$set": record}
+
+	_, err := collection.UpdateOne(c, filter, update, options.Update().SetUpsert(true))
+	if mongodriver.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (ir *idempotencyRepository) GetByKey(c context.Context, key string) (*domain.IdempotencyRecord, error) {
+	collection := ir.database.Collection(ir.collection)
+
+	var record domain.IdempotencyRecord
+
+	filter := bson.M{"_id": key, "expires_at": bson.M{"$gt": time.Now()}}
+	err := collection.FindOne(c, filter).Decode(&record)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return nil, domain.NewNotFoundError("idempotency key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (ir *idempotencyRepository) Complete(c context.Context, record *domain.IdempotencyRecord) (bool, error) {
+	collection := ir.database.Collection(ir.collection)
+
+	result, err := collection.UpdateOne(c, bson.M{"_id": record.Key, "lease": record.Lease}, bson.M{"$set": record})
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+func (ir *idempotencyRepository) Release(c context.Context, record *domain.IdempotencyRecord) error {
+	collection := ir.database.Collection(ir.collection)
+
+	_, err := collection.DeleteOne(c, bson.M{"_id": record.Key, "lease": record.Lease})
+	return err
+}
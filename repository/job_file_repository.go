@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+)
+
+// jobFileRepository keeps job files in a GridFS bucket with their name as
+// file ID, so they can be opened and deleted without looking them up first.
+type jobFileRepository struct {
+	database mongo.Database
+	bucket   string
+}
+
+func NewJobFileRepository(db mongo.Database, bucket string) domain.JobFileRepository {
+	return &jobFileRepository{
+		database: db,
+		bucket:   bucket,
+	}
+}
+
+func (jf *jobFileRepository) Create(c context.Context, name string) (io.WriteCloser, error) {
+	bucket, err := jf.database.Bucket(jf.bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	// A file left behind by an earlier attempt would make the upload fail
+	// when it is closed.
+	if err := jf.delete(c, bucket, name); err != nil {
+		return nil, err
+	}
+
+	return bucket.OpenUploadStream(c, name, name)
+}
+
+func (jf *jobFileRepository) Open(c context.Context, name string) (io.ReadCloser, error) {
+	bucket, err := jf.database.Bucket(jf.bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := bucket.OpenDownloadStream(c, name)
+	if errors.Is(err, mongo.ErrFileNotFound) {
+		return nil, os.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+func (jf *jobFileRepository) Delete(c context.Context, name string) error {
+	bucket, err := jf.database.Bucket(jf.bucket)
+	if err != nil {
+		return err
+	}
+	return jf.delete(c, bucket, name)
+}
+
+func (jf *jobFileRepository) delete(c context.Context, bucket mongo.Bucket, name string) error {
+	err := bucket.Delete(c, name)
+	if errors.Is(err, mongo.ErrFileNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// jobExpiryGrace delays the TTL index past the expiry of jobs. Expired jobs
+// are deleted by JobUsecase.Expire together with their files; the index only
+// removes the ones it missed, e.g. while no worker was running.
+const jobExpiryGrace = 24 * time.Hour
+
+type jobRepository struct {
+	database   mongo.Database
+	collection string
+}
+
+func NewJobRepository(db mongo.Database, collection string) domain.JobRepository {
+	return &jobRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+func (jr *jobRepository) EnsureIndexes(c context.Context) error {
+	collection := jr.database.Collection(jr.collection)
+
+	_, err := collection.CreateIndexes(c, []mongodriver.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(jobExpiryGrace.Seconds())),
+		},
+	})
+	return err
+}
+
+func (jr *jobRepository) Create(c context.Context, job *domain.Job) error {
+	collection := jr.database.Collection(jr.collection)
+	_, err := collection.InsertOne(c, job)
+	return err
+}
+
+func (jr *jobRepository) GetByID(c context.Context, id string) (*domain.Job, error) {
+	collection := jr.database.Collection(jr.collection)
+
+	var job domain.Job
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = collection.FindOne(c, bson.M{"_id": objID}).Decode(&job)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return nil, domain.ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (jr *jobRepository) Claim(c context.Context) (*domain.Job, error) {
+	collection := jr.database.Collection(jr.collection)
+
+	var job domain.Job
+
+	now := time.Now()
+	filter := bson.M{"status": domain.JobStatusQueued}
+	update := bson.M{"$set": bson.M{
+		"status":     domain.JobStatusRunning,
+		"started_at": now,
+		"updated_at": now,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	err := collection.FindOneAndUpdate(c, filter, update, opts).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (jr *jobRepository) Heartbeat(c context.Context, id primitive.ObjectID, processed, total int64) (bool, error) {
+	collection := jr.database.Collection(jr.collection)
+
+	var job domain.Job
+
+	update := bson.M{"$set": bson.M{
+		"processed":  processed,
+		"total":      total,
+		"updated_at": time.Now(),
+	}}
+
+	err := collection.FindOneAndUpdate(c, bson.M{"_id": id}, update).Decode(&job)
+	if err != nil {
+		return false, err
+	}
+
+	return job.CancelRequested, nil
+}
+
+func (jr *jobRepository) Finish(c context.Context, job *domain.Job) error {
+	collection := jr.database.Collection(jr.collection)
+
+	update := bson.M{"$set": bson.M{
+		"status":          job.Status,
+		"processed":       job.Processed,
+		"total":           job.Total,
+		"result_location": job.ResultLocation,
+		"error_code":      job.ErrorCode,
+		"error":           job.Error,
+		"updated_at":      job.UpdatedAt,
+		"finished_at":     job.FinishedAt,
+		"expires_at":      job.ExpiresAt,
+	}}
+
+	_, err := collection.UpdateOne(c, bson.M{"_id": job.ID}, update)
+	return err
+}
+
+func (jr *jobRepository) Cancel(c context.Context, id string, expiresAt time.Time) error {
+	collection := jr.database.Collection(jr.collection)
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	// Queued jobs are canceled right away; running ones are flagged and
+	// stopped by their worker on the next heartbeat.
+	result, err := collection.UpdateOne(c,
+		bson.M{"_id": objID, "status": domain.JobStatusQueued},
+		bson.M{"$set": bson.M{
+			"status":      domain.JobStatusCanceled,
+			"updated_at":  now,
+			"finished_at": now,
+			"expires_at":  expiresAt,
+		}},
+	)
+	if err != nil || result.MatchedCount > 0 {
+		return err
+	}
+
+	result, err = collection.UpdateOne(c,
+		bson.M{"_id": objID, "status": domain.JobStatusRunning},
+		bson.M{"$set": bson.M{"cancel_requested": true, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := collection.CountDocuments(c, bson.M{"_id": objID})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return domain.ErrJobNotFound
+		}
+	}
+
+	return nil
+}
+
+func (jr *jobRepository) FailStale(c context.Context, before, expiresAt time.Time) (int64, error) {
+	collection := jr.database.Collection(jr.collection)
+
+	now := time.Now()
+	filter := bson.M{"status": domain.JobStatusRunning, "updated_at": bson.M{"$lt": before}}
+	update := bson.M{"$set": bson.M{
+		"status":      domain.JobStatusFailed,
+		"error_code":  domain.JobErrorInterrupted,
+		"error":       "job was interrupted",
+		"updated_at":  now,
+		"finished_at": now,
+		"expires_at":  expiresAt,
+	}}
+
+	result, err := collection.UpdateMany(c, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+func (jr *jobRepository) Expired(c context.Context, limit int64) ([]domain.Job, error) {
+	collection := jr.database.Collection(jr.collection)
+
+	var jobs []domain.Job
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "expires_at", Value: 1}})
+	findOptions.SetLimit(limit)
+
+	cursor, err := collection.Find(c, bson.M{"expires_at": bson.M{"$lte": time.Now()}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cursor.All(c, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (jr *jobRepository) Delete(c context.Context, id primitive.ObjectID) error {
+	collection := jr.database.Collection(jr.collection)
+	_, err := collection.DeleteOne(c, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type outboxRepository struct {
+	database   mongo.Database
+	collection string
+}
+
+func NewOutboxRepository(db mongo.Database, collection string) domain.OutboxRepository {
+	return &outboxRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+func (ob *outboxRepository) EnsureIndexes(c context.Context, retention time.Duration) error {
+	collection := ob.database.Collection(ob.collection)
+
+	_, err := collection.CreateIndexes(c, []mongodriver.IndexModel{
+		{
+			Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			// Pending entries have no published_at, so only published ones
+			// expire.
+			Keys:    bson.D{{Key: "published_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())).SetName("published_at_ttl"),
+		},
+	})
+	return err
+}
+
+func (ob *outboxRepository) Add(c context.Context, entries []domain.OutboxEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	collection := ob.database.Collection(ob.collection)
+
+	models := make([]mongodriver.WriteModel, len(entries))
+	for i := range entries {
+		models[i] = mongodriver.NewInsertOneModel().SetDocument(entries[i])
+	}
+
+	_, err := collection.BulkWrite(c, models)
+	return err
+}
+
+// Claim locks the entries in two steps, since no single write both limits
+// and returns several documents: it locks the oldest pending ones it finds
+// with a token of its own and reads back those it got, which are fewer
+// when another relay was faster.
+func (ob *outboxRepository) Claim(c context.Context, limit int, lease time.Duration) ([]domain.OutboxEntry, error) {
+	collection := ob.database.Collection(ob.collection)
+
+	now := time.Now()
+	pending := bson.M{
+		"published_at": nil,
+		"locked_until": bson.M{"$lte": now},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"_id": 1})
+
+	cursor, err := collection.Find(c, pending, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(c, &found); err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(found))
+	for i := range found {
+		ids[i] = found[i].ID
+	}
+
+	lock := primitive.NewObjectID()
+	pending["_id"] = bson.M{"$in": ids}
+	update := bson.M{"$set": bson.M{"lock": lock, "locked_until": now.Add(lease)}}
+	if _, err := collection.UpdateMany(c, pending, update); err != nil {
+		return nil, err
+	}
+
+	cursor, err = collection.Find(c, bson.M{"lock": lock}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []domain.OutboxEntry
+	if err := cursor.All(c, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (ob *outboxRepository) MarkPublished(c context.Context, ids []primitive.ObjectID) error {
+	collection := ob.database.Collection(ob.collection)
+
+	update := bson.M{
+		"$set":   bson.M{"published_at": time.Now()},
+		"$inc":   bson.M{"attempts": 1},
+		"$unset": bson.M{"lock": "", "last_error": ""},
+	}
+	_, err := collection.UpdateMany(c, bson.M{"_id": bson.M{"$in": ids}}, update)
+	return err
+}
+
+func (ob *outboxRepository) Release(c context.Context, ids []primitive.ObjectID, reason string, retryAfter time.Duration) error {
+	collection := ob.database.Collection(ob.collection)
+
+	update := bson.M{
+		"$set":   bson.M{"locked_until": time.Now().Add(retryAfter), "last_error": reason},
+		"$inc":   bson.M{"attempts": 1},
+		"$unset": bson.M{"lock": ""},
+	}
+	_, err := collection.UpdateMany(c, bson.M{"_id": bson.M{"$in": ids}}, update)
+	return err
+}
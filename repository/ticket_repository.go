@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ticketRepository struct {
+	database   mongo.Database
+	collection string
+}
+
+func NewTicketRepository(db mongo.Database, collection string) domain.TicketRepository {
+	return &ticketRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+func (tr *ticketRepository) EnsureIndexes(c context.Context) error {
+	collection := tr.database.Collection(tr.collection)
+
+	_, err := collection.CreateIndexes(c, []mongodriver.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+func (tr *ticketRepository) Create(c context.Context, ticket *domain.Ticket) error {
+	collection := tr.database.Collection(tr.collection)
+
+	_, err := collection.InsertOne(c, ticket)
+	return err
+}
+
+func (tr *ticketRepository) Consume(c context.Context, hash string) (bool, error) {
+	collection := tr.database.Collection(tr.collection)
+
+	// Expired tickets may linger until the TTL monitor removes them.
+	filter := bson.M{"_id": hash, "expires_at": bson.M{"$gt": time.Now()}}
+	deleted, err := collection.DeleteOne(c, filter)
+	if err != nil {
+		return false, err
+	}
+
+	return deleted == 1, nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+)
+
+type transactor struct {
+	database mongo.Database
+}
+
+// NewTransactor runs transactions on the client of db, which requires a
+// replica set or a sharded cluster.
+func NewTransactor(db mongo.Database) domain.Transactor {
+	return &transactor{database: db}
+}
+
+func (t *transactor) WithTransaction(c context.Context, fn func(c context.Context) error) error {
+	return withTransaction(c, t.database, fn)
+}
+
+// withTransaction runs fn in a new transaction, or in the one c already
+// belongs to.
+func withTransaction(c context.Context, db mongo.Database, fn func(c context.Context) error) error {
+	if mongodriver.SessionFromContext(c) != nil {
+		return fn(c)
+	}
+
+	return db.Client().UseSession(c, func(sc mongodriver.SessionContext) error {
+		_, err := sc.WithTransaction(sc, func(tc mongodriver.SessionContext) (interface{}, error) {
+			return nil, fn(tc)
+		})
+		return err
+	})
+}
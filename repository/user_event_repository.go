@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type userEventRepository struct {
+	database   mongo.Database
+	collection string
+}
+
+func NewUserEventRepository(db mongo.Database, collection string) domain.UserEventRepository {
+	return &userEventRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+func (er *userEventRepository) EnsureIndexes(c context.Context, retention time.Duration) error {
+	collection := er.database.Collection(er.collection)
+
+	_, err := collection.CreateIndexes(c, []mongodriver.IndexModel{
+		{
+			Keys:    bson.D{{Key: "time", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		},
+		{
+			Keys: bson.D{{Key: "seq", Value: 1}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Transactions cannot create collections on servers before 4.4, so the
+	// counter is created here rather than by the first append.
+	counters := er.database.Collection(domain.CollectionCounter)
+	_, err = counters.UpdateOne(c, bson.M{"_id": er.collection}, bson.M{"$setOnInsert": bson.M{"seq": int64(0)}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// Append takes the sequence numbers from the counter of the log. The
+// counter is written in the transaction of the events, so a concurrent
+// append conflicts with it and is retried once the transaction ends.
+// Numbers are therefore taken in commit order, and a reader that saw an
+// event cannot later see one numbered lower.
+func (er *userEventRepository) Append(c context.Context, events []domain.UserEvent) error {
+	return withTransaction(c, er.database, func(tc context.Context) error {
+		counters := er.database.Collection(domain.CollectionCounter)
+
+		var counter struct {
+			Seq int64 `bson:"seq"`
+		}
+		update := bson.M{"$inc": bson.M{"seq": int64(len(events))}}
+		findOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+		if err := counters.FindOneAndUpdate(tc, bson.M{"_id": er.collection}, update, findOptions).Decode(&counter); err != nil {
+			return err
+		}
+
+		first := counter.Seq - int64(len(events)) + 1
+		models := make([]mongodriver.WriteModel, len(events))
+		for i := range events {
+			events[i].Seq = first + int64(i)
+			models[i] = mongodriver.NewInsertOneModel().SetDocument(events[i])
+		}
+
+		collection := er.database.Collection(er.collection)
+		_, err := collection.BulkWrite(tc, models)
+		return err
+	})
+}
+
+func (er *userEventRepository) After(c context.Context, seq int64, limit int) ([]domain.UserEvent, error) {
+	collection := er.database.Collection(er.collection)
+
+	var events []domain.UserEvent
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "seq", Value: 1}})
+	findOptions.SetLimit(int64(limit))
+
+	filter := bson.M{"seq": bson.M{"$gt": seq}}
+
+	cursor, err := collection.Find(c, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(c, &events)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (er *userEventRepository) Last(c context.Context) (int64, error) {
+	counters := er.database.Collection(domain.CollectionCounter)
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := counters.FindOne(c, bson.M{"_id": er.collection}).Decode(&counter)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Seq, nil
+}
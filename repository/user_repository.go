@@ -2,11 +2,17 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/nebojsaj1726/user-manager/domain"
 	"github.com/nebojsaj1726/user-manager/mongo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -22,22 +28,169 @@ func NewUserRepository(db mongo.Database, collection string) domain.UserReposito
 	}
 }
 
+func (ur *userRepository) EnsureIndexes(c context.Context) error {
+	collection := ur.database.Collection(ur.collection)
+
+	_, err := collection.CreateIndexes(c, []mongodriver.IndexModel{
+		{
+			Keys: bson.D{{Key: "words", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "trigrams", Value: 1}},
+		},
+		{
+			// Emails are checked before writing; the index rejects the
+			// duplicates of concurrent writes that passed the check.
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Searches used to go through a text index, which only matches whole
+	// words. Searches now use the index on words, so the text index is only
+	// a cost on writes; see the Search section of the README.
+	var commandErr mongodriver.CommandError
+	if err := collection.DropIndex(c, "user_text"); err != nil && !(errors.As(err, &commandErr) && commandErr.Name == "IndexNotFound") {
+		return err
+	}
+	return nil
+}
+
+func (ur *userRepository) BackfillVersions(c context.Context) (int64, error) {
+	collection := ur.database.Collection(ur.collection)
+
+	result, err := collection.UpdateMany(c, bson.M{"version": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"version": 1}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
 func (ur *userRepository) Create(c context.Context, user *domain.User) error {
 	collection := ur.database.Collection(ur.collection)
-	_, err := collection.InsertOne(c, user)
+	user.Version = 1
+	_, err := collection.InsertOne(c, newUserDocument(*user))
+	if mongodriver.IsDuplicateKeyError(err) {
+		return domain.ErrEmailNotUnique
+	}
 	return err
 }
 
-func (ur *userRepository) Fetch(c context.Context, offset, limit int) ([]domain.User, error) {
+func (ur *userRepository) Fetch(c context.Context, filter domain.UserFilter, offset, limit int) ([]domain.User, error) {
 	collection := ur.database.Collection(ur.collection)
 
 	var users []domain.User
 
+	// Sorting by _id keeps positions stable for paging by offset.
 	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "_id", Value: 1}})
 	findOptions.SetSkip(int64(offset))
 	findOptions.SetLimit(int64(limit))
 
-	cursor, err := collection.Find(c, bson.M{}, findOptions)
+	cursor, err := collection.Find(c, filterQuery(filter), findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(c, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Search ranks the users by how many of the terms are whole words of their
+// email, with terms that only start a word counting half.
+func (ur *userRepository) Search(c context.Context, filter domain.UserFilter, offset, limit int) ([]domain.UserSearchHit, error) {
+	collection := ur.database.Collection(ur.collection)
+
+	var hits []domain.UserSearchHit
+
+	terms, _ := searchTerms(filter.Text)
+	exact := bson.M{"$size": bson.M{"$setIntersection": bson.A{"$words", terms}}}
+	pipeline := bson.A{
+		bson.M{"$match": filterQuery(filter)},
+		bson.M{"$addFields": bson.M{"score": bson.M{"$add": bson.A{
+			exact,
+			bson.M{"$multiply": bson.A{0.5, bson.M{"$subtract": bson.A{len(terms), exact}}}},
+		}}}},
+		bson.M{"$sort": bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$skip": offset},
+		bson.M{"$limit": limit},
+	}
+
+	cursor, err := collection.Aggregate(c, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(c, &hits)
+	if err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+func (ur *userRepository) FetchByIDs(c context.Context, ids []string) ([]domain.User, error) {
+	collection := ur.database.Collection(ur.collection)
+
+	var users []domain.User
+
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		objIDs = append(objIDs, objID)
+	}
+
+	cursor, err := collection.Find(c, bson.M{"_id": bson.M{"$in": objIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(c, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (ur *userRepository) FetchByIDsOrEmails(c context.Context, ids, emails []string) ([]domain.User, error) {
+	collection := ur.database.Collection(ur.collection)
+
+	var users []domain.User
+
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		objIDs = append(objIDs, objID)
+	}
+
+	// A nil slice is encoded as null, which $in rejects, so only the lists
+	// that have values are matched.
+	var clauses bson.A
+	if len(objIDs) > 0 {
+		clauses = append(clauses, bson.M{"_id": bson.M{"$in": objIDs}})
+	}
+	if len(emails) > 0 {
+		clauses = append(clauses, bson.M{"email": bson.M{"$in": emails}})
+	}
+	if len(clauses) == 0 {
+		return []domain.User{}, nil
+	}
+
+	cursor, err := collection.Find(c, bson.M{"$or": clauses})
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +214,9 @@ func (ur *userRepository) GetByID(c context.Context, id string) (*domain.User, e
 	}
 
 	err = collection.FindOne(c, bson.M{"_id": objID}).Decode(&user)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return nil, domain.ErrUserNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -76,14 +232,22 @@ func (ur *userRepository) Update(c context.Context, id string, user *domain.User
 		return err
 	}
 
-	filter := bson.M{"_id": objID}
-	update := bson.M{"$set": user}
+	result, err := collection.UpdateOne(c, versionFilter(objID, user.Version), updateDocument(user))
+	if mongodriver.IsDuplicateKeyError(err) {
+		return domain.ErrEmailNotUnique
+	}
+	if err != nil {
+		return err
+	}
 
-	_, err = collection.UpdateOne(c, filter, update)
-	return err
+	if result.MatchedCount == 0 {
+		return ur.notFoundOrModified(c, objID)
+	}
+
+	return nil
 }
 
-func (ur *userRepository) Delete(c context.Context, id string) error {
+func (ur *userRepository) Delete(c context.Context, id string, version int64) error {
 	collection := ur.database.Collection(ur.collection)
 
 	objID, err := primitive.ObjectIDFromHex(id)
@@ -91,8 +255,77 @@ func (ur *userRepository) Delete(c context.Context, id string) error {
 		return err
 	}
 
-	_, err = collection.DeleteOne(c, bson.M{"_id": objID})
-	return err
+	deleted, err := collection.DeleteOne(c, versionFilter(objID, version))
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ur.notFoundOrModified(c, objID)
+	}
+
+	return nil
+}
+
+func (ur *userRepository) ApplyBatch(c context.Context, ops []domain.BatchOperation) error {
+	collection := ur.database.Collection(ur.collection)
+
+	models := make([]mongodriver.WriteModel, 0, len(ops))
+	expectedMatches := int64(0)
+
+	for _, op := range ops {
+		switch op.Method {
+		case domain.BatchMethodCreate:
+			op.User.Version = 1
+			models = append(models, mongodriver.NewInsertOneModel().SetDocument(newUserDocument(*op.User)))
+		case domain.BatchMethodUpdate, domain.BatchMethodDelete:
+			objID, err := primitive.ObjectIDFromHex(op.ID)
+			if err != nil {
+				return err
+			}
+			expectedMatches++
+			if op.Method == domain.BatchMethodUpdate {
+				user := *op.User
+				user.Version = op.Version
+				models = append(models, mongodriver.NewUpdateOneModel().
+					SetFilter(versionFilter(objID, op.Version)).
+					SetUpdate(updateDocument(&user)))
+			} else {
+				models = append(models, mongodriver.NewDeleteOneModel().SetFilter(versionFilter(objID, op.Version)))
+			}
+		default:
+			return fmt.Errorf("unknown batch method %q", op.Method)
+		}
+	}
+
+	return withTransaction(c, ur.database, func(tc context.Context) error {
+		result, err := collection.BulkWrite(tc, models)
+		if err != nil {
+			return err
+		}
+		// A concurrent write changed a version between validation and the
+		// bulk write, so roll everything back.
+		if result.MatchedCount+result.DeletedCount < expectedMatches {
+			return domain.ErrVersionMismatch
+		}
+		return nil
+	})
+}
+
+// notFoundOrModified explains why a conditional write matched nothing.
+func (ur *userRepository) notFoundOrModified(c context.Context, objID primitive.ObjectID) error {
+	collection := ur.database.Collection(ur.collection)
+
+	count, err := collection.CountDocuments(c, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return domain.ErrVersionMismatch
 }
 
 func (ur *userRepository) FetchByEmail(c context.Context, email string) ([]domain.User, error) {
@@ -113,8 +346,166 @@ func (ur *userRepository) FetchByEmail(c context.Context, email string) ([]domai
 	return users, nil
 }
 
-func (ur *userRepository) Count(c context.Context) (int64, error) {
+func (ur *userRepository) Stream(c context.Context, filter domain.UserFilter, fn func(*domain.User) error) error {
+	collection := ur.database.Collection(ur.collection)
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(c, filterQuery(filter), findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(c)
+
+	for cursor.Next(c) {
+		var user domain.User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func (ur *userRepository) Count(c context.Context, filter domain.UserFilter) (int64, error) {
 	collection := ur.database.Collection(ur.collection)
-	count, err := collection.CountDocuments(c, bson.M{})
+	count, err := collection.CountDocuments(c, filterQuery(filter))
 	return count, err
 }
+
+func filterQuery(filter domain.UserFilter) bson.M {
+	query := bson.M{}
+
+	if filter.Email != "" {
+		query["email"] = filter.Email
+	}
+	if filter.Text != "" {
+		query["$and"] = textQuery(filter.Text)
+	}
+
+	age := bson.M{}
+	if filter.MinAge > 0 {
+		age["$gte"] = filter.MinAge
+	}
+	if filter.MaxAge > 0 {
+		age["$lte"] = filter.MaxAge
+	}
+	if len(age) > 0 {
+		query["age"] = age
+	}
+
+	if filter.Expression != nil {
+		if len(query) == 0 {
+			return expressionQuery(*filter.Expression)
+		}
+		return bson.M{"$and": bson.A{query, expressionQuery(*filter.Expression)}}
+	}
+
+	return query
+}
+
+// textQuery matches the users with a word of their email starting with every
+// term of text, and none starting with a term negated with a leading "-".
+// The prefixes are anchored so they can use the index on words.
+func textQuery(text string) bson.A {
+	terms, negated := searchTerms(text)
+
+	clauses := bson.A{}
+	for _, term := range terms {
+		clauses = append(clauses, bson.M{"words": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(term)}})
+	}
+	for _, term := range negated {
+		clauses = append(clauses, bson.M{"words": bson.M{"$not": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(term)}}})
+	}
+	if len(terms) == 0 {
+		// A search without terms matches nothing.
+		clauses = append(clauses, bson.M{"_id": bson.M{"$exists": false}})
+	}
+	return clauses
+}
+
+// searchTerms splits text into the distinct words to match and those to
+// exclude.
+func searchTerms(text string) (terms, negated []string) {
+	for _, field := range strings.Fields(text) {
+		if rest, ok := strings.CutPrefix(field, "-"); ok {
+			negated = append(negated, words(rest)...)
+			continue
+		}
+		terms = append(terms, words(field)...)
+	}
+	return slices.Compact(slices.Sorted(slices.Values(terms))), slices.Compact(slices.Sorted(slices.Values(negated)))
+}
+
+// expressionQuery translates a filter expression. Comparisons that cannot
+// match, such as a malformed id, become a query matching nothing.
+func expressionQuery(expr domain.FilterExpression) bson.M {
+	switch expr.Operator {
+	case domain.FilterAnd, domain.FilterOr:
+		operands := bson.A{}
+		for _, operand := range expr.Operands {
+			operands = append(operands, expressionQuery(operand))
+		}
+		return bson.M{"$" + expr.Operator: operands}
+	}
+
+	switch expr.Attribute {
+	case domain.FilterAttributeID:
+		id, ok := expr.Value.(string)
+		objID, err := primitive.ObjectIDFromHex(id)
+		if !ok || err != nil || expr.Operator != domain.FilterEqual {
+			return matchNothing
+		}
+		return bson.M{"_id": objID}
+
+	case domain.FilterAttributeEmail:
+		value, ok := expr.Value.(string)
+		if !ok {
+			return matchNothing
+		}
+		pattern := regexp.QuoteMeta(value)
+		switch expr.Operator {
+		case domain.FilterEqual:
+			pattern = "^" + pattern + "$"
+		case domain.FilterStartsWith:
+			pattern = "^" + pattern
+		}
+		return bson.M{"email": primitive.Regex{Pattern: pattern, Options: "i"}}
+
+	case domain.FilterAttributeAge:
+		age, ok := expr.Value.(int)
+		if !ok || expr.Operator != domain.FilterEqual {
+			return matchNothing
+		}
+		return bson.M{"age": age}
+	}
+
+	return matchNothing
+}
+
+var matchNothing = bson.M{"_id": bson.M{"$exists": false}}
+
+// versionFilter matches the user by id and, unless version is
+// domain.AnyVersion, by its current version.
+func versionFilter(objID primitive.ObjectID, version int64) bson.M {
+	filter := bson.M{"_id": objID}
+	if version != domain.AnyVersion {
+		filter["version"] = version
+	}
+	return filter
+}
+
+func updateDocument(user *domain.User) bson.M {
+	fields := *user
+	fields.ID = primitive.NilObjectID
+	fields.Version = 0
+
+	return bson.M{
+		"$set": newUserDocument(fields),
+		"$inc": bson.M{"version": 1},
+	}
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// matchesText evaluates the clauses of textQuery against the stored words
+// of a user, as the database would.
+func matchesText(t *testing.T, text string, user domain.User) bool {
+	t.Helper()
+	words := newUserDocument(user).Words
+
+	anyWord := func(pattern string) bool {
+		re := regexp.MustCompile(pattern)
+		for _, word := range words {
+			if re.MatchString(word) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, clause := range textQuery(text) {
+		condition, ok := clause.(bson.M)["words"]
+		if !ok {
+			return false
+		}
+		switch condition := condition.(type) {
+		case primitive.Regex:
+			if !anyWord(condition.Pattern) {
+				return false
+			}
+		case bson.M:
+			if anyWord(condition["$not"].(primitive.Regex).Pattern) {
+				return false
+			}
+		default:
+			t.Fatalf("unexpected condition %v", condition)
+		}
+	}
+	return true
+}
+
+func TestTextQuery(t *testing.T) {
+	jane := domain.User{Email: "Jane.Doe@example.com"}
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "jan", want: true},
+		{text: "JANE", want: true},
+		{text: "jane doe", want: true},
+		{text: "do exam", want: true},
+		{text: "ane", want: false},
+		{text: "jane smith", want: false},
+		{text: "jane -doe", want: false},
+		{text: "jane -smith", want: true},
+		{text: "ja.*", want: true},
+		{text: "-jane", want: false},
+		{text: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchesText(t, tt.text, jane))
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// userDocument is the stored form of a user. Words holds the lowercase words
+// of the email so that searches for the start of a word use an index, and
+// Trigrams their trigrams so that misspelled searches still find candidates.
+// Both are rewritten with every write of the email.
+type userDocument struct {
+	domain.User `bson:",inline"`
+	Words       []string `bson:"words"`
+	Trigrams    []string `bson:"trigrams"`
+}
+
+func newUserDocument(user domain.User) userDocument {
+	return userDocument{User: user, Words: words(user.Email), Trigrams: trigrams(user.Email)}
+}
+
+// words splits text into its distinct lowercase words, separated by anything
+// that is not a letter or a digit.
+func words(text string) []string {
+	seen := make(map[string]bool)
+	var result []string
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[word] {
+			seen[word] = true
+			result = append(result, word)
+		}
+	}
+
+	return result
+}
+
+// trigrams returns the distinct three-letter sequences of every word of text
+// padded with two leading and one trailing space, so that short words and
+// word boundaries also count.
+func trigrams(text string) []string {
+	seen := make(map[string]bool)
+	var result []string
+
+	for _, word := range words(text) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			trigram := string(padded[i : i+3])
+			if !seen[trigram] {
+				seen[trigram] = true
+				result = append(result, trigram)
+			}
+		}
+	}
+
+	return result
+}
+
+// StreamSimilar only reads the users sharing enough trigrams with the text to
+// be within maxEdits of it: an edit changes at most three trigrams, or four
+// for a transposition, so the others must be shared.
+func (ur *userRepository) StreamSimilar(c context.Context, filter domain.UserFilter, maxEdits int, fn func(*domain.User) error) error {
+	collection := ur.database.Collection(ur.collection)
+
+	grams := trigrams(filter.Text)
+	if len(grams) == 0 {
+		return nil
+	}
+	minOverlap := max(1, len(grams)-4*maxEdits)
+
+	query := filterQuery(domain.UserFilter{Email: filter.Email, MinAge: filter.MinAge, MaxAge: filter.MaxAge, Expression: filter.Expression})
+	query["trigrams"] = bson.M{"$in": grams}
+	query["$expr"] = bson.M{"$gte": bson.A{
+		bson.M{"$size": bson.M{"$setIntersection": bson.A{"$trigrams", grams}}},
+		minOverlap,
+	}}
+
+	cursor, err := collection.Find(c, query)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(c)
+
+	for cursor.Next(c) {
+		var user domain.User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func (ur *userRepository) Reindex(c context.Context) (int64, error) {
+	collection := ur.database.Collection(ur.collection)
+
+	cursor, err := collection.Find(c, bson.M{"$or": bson.A{
+		bson.M{"words": bson.M{"$exists": false}},
+		bson.M{"trigrams": bson.M{"$exists": false}},
+	}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(c)
+
+	var updated int64
+	for cursor.Next(c) {
+		var user domain.User
+		if err := cursor.Decode(&user); err != nil {
+			return updated, err
+		}
+
+		// Matching the email skips users whose email changed meanwhile; that
+		// write stored their words and trigrams already.
+		_, err := collection.UpdateOne(c, bson.M{"_id": user.ID, "email": user.Email}, bson.M{"$set": bson.M{
+			"words":    words(user.Email),
+			"trigrams": trigrams(user.Email),
+		}})
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	return updated, cursor.Err()
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type webhookDeliveryRepository struct {
+	database   mongo.Database
+	collection string
+}
+
+func NewWebhookDeliveryRepository(db mongo.Database, collection string) domain.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+func (dr *webhookDeliveryRepository) EnsureIndexes(c context.Context, retention time.Duration) error {
+	collection := dr.database.Collection(dr.collection)
+
+	_, err := collection.CreateIndexes(c, []mongodriver.IndexModel{
+		{
+			// Instances dispatching the same events queue each delivery once.
+			Keys:    bson.D{{Key: "webhook_id", Value: 1}, {Key: "event_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		},
+	})
+	return err
+}
+
+func (dr *webhookDeliveryRepository) Insert(c context.Context, deliveries []domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	collection := dr.database.Collection(dr.collection)
+
+	models := make([]mongodriver.WriteModel, len(deliveries))
+	for i := range deliveries {
+		models[i] = mongodriver.NewInsertOneModel().SetDocument(deliveries[i])
+	}
+
+	_, err := collection.BulkWrite(c, models, options.BulkWrite().SetOrdered(false))
+
+	failed, err := writeErrors(err)
+	if err != nil {
+		return err
+	}
+	for _, writeErr := range failed {
+		if !mongodriver.IsDuplicateKeyError(writeErr) {
+			return writeErr
+		}
+	}
+
+	return nil
+}
+
+func (dr *webhookDeliveryRepository) Claim(c context.Context, lease time.Duration) (*domain.WebhookDelivery, error) {
+	collection := dr.database.Collection(dr.collection)
+
+	var delivery domain.WebhookDelivery
+
+	now := time.Now()
+	filter := bson.M{
+		"status":          domain.WebhookDeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	err := collection.FindOneAndUpdate(c, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+func (dr *webhookDeliveryRepository) Finish(c context.Context, delivery *domain.WebhookDelivery) error {
+	collection := dr.database.Collection(dr.collection)
+
+	set := bson.M{
+		"status":     delivery.Status,
+		"tries":      delivery.Tries,
+		"updated_at": delivery.UpdatedAt,
+	}
+	update := bson.M{"$set": set}
+	if delivery.NextAttemptAt != nil {
+		set["next_attempt_at"] = delivery.NextAttemptAt
+	} else {
+		update["$unset"] = bson.M{"next_attempt_at": ""}
+	}
+	if n := len(delivery.Attempts); n > 0 {
+		update["$push"] = bson.M{"attempts": delivery.Attempts[n-1]}
+	}
+
+	_, err := collection.UpdateOne(c, bson.M{"_id": delivery.ID}, update)
+	return err
+}
+
+func (dr *webhookDeliveryRepository) GetByID(c context.Context, webhookID, id string) (*domain.WebhookDelivery, error) {
+	collection := dr.database.Collection(dr.collection)
+
+	var delivery domain.WebhookDelivery
+
+	filter, err := deliveryFilter(webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = collection.FindOne(c, filter).Decode(&delivery)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return nil, domain.ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+func (dr *webhookDeliveryRepository) FetchByWebhook(c context.Context, webhookID string, offset, limit int) ([]domain.WebhookDelivery, int64, error) {
+	collection := dr.database.Collection(dr.collection)
+
+	deliveries := []domain.WebhookDelivery{}
+
+	objID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter := bson.M{"webhook_id": objID}
+
+	// The newest deliveries come first.
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "_id", Value: -1}})
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(limit))
+
+	cursor, err := collection.Find(c, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = cursor.All(c, &deliveries)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := collection.CountDocuments(c, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+func (dr *webhookDeliveryRepository) Replay(c context.Context, webhookID, id string) (*domain.WebhookDelivery, error) {
+	collection := dr.database.Collection(dr.collection)
+
+	var delivery domain.WebhookDelivery
+
+	filter, err := deliveryFilter(webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":          domain.WebhookDeliveryPending,
+		"tries":           0,
+		"next_attempt_at": now,
+		"updated_at":      now,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err = collection.FindOneAndUpdate(c, filter, update, opts).Decode(&delivery)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return nil, domain.ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+func deliveryFilter(webhookID, id string) (bson.M, error) {
+	webhookObjID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"_id": objID, "webhook_id": webhookObjID}, nil
+}
+
+// writeErrors returns the write errors of an unordered bulk write by the
+// index of the failed operation, or err when it is not a write error.
+func writeErrors(err error) (map[int]error, error) {
+	var bulkErr mongodriver.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return nil, err
+	}
+
+	failed := make(map[int]error, len(bulkErr.WriteErrors))
+	for _, writeErr := range bulkErr.WriteErrors {
+		failed[writeErr.Index] = writeErr
+	}
+	return failed, nil
+}
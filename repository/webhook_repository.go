@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type webhookRepository struct {
+	database   mongo.Database
+	collection string
+}
+
+func NewWebhookRepository(db mongo.Database, collection string) domain.WebhookRepository {
+	return &webhookRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+func (wr *webhookRepository) Create(c context.Context, webhook *domain.Webhook) error {
+	collection := wr.database.Collection(wr.collection)
+	_, err := collection.InsertOne(c, webhook)
+	return err
+}
+
+func (wr *webhookRepository) GetByID(c context.Context, id string) (*domain.Webhook, error) {
+	collection := wr.database.Collection(wr.collection)
+
+	var webhook domain.Webhook
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = collection.FindOne(c, bson.M{"_id": objID}).Decode(&webhook)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return nil, domain.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (wr *webhookRepository) Fetch(c context.Context, offset, limit int) ([]domain.Webhook, int64, error) {
+	collection := wr.database.Collection(wr.collection)
+
+	webhooks := []domain.Webhook{}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "_id", Value: 1}})
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(limit))
+
+	cursor, err := collection.Find(c, bson.M{}, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = cursor.All(c, &webhooks)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := collection.CountDocuments(c, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return webhooks, total, nil
+}
+
+func (wr *webhookRepository) FetchActive(c context.Context) ([]domain.Webhook, error) {
+	collection := wr.database.Collection(wr.collection)
+
+	var webhooks []domain.Webhook
+
+	cursor, err := collection.Find(c, bson.M{"active": true})
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(c, &webhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (wr *webhookRepository) Update(c context.Context, webhook *domain.Webhook) error {
+	collection := wr.database.Collection(wr.collection)
+
+	update := bson.M{"$set": bson.M{
+		"url":             webhook.URL,
+		"events":          webhook.Events,
+		"secret":          webhook.Secret,
+		"active":          webhook.Active,
+		"failures":        webhook.Failures,
+		"disabled_reason": webhook.DisabledReason,
+		"updated_at":      webhook.UpdatedAt,
+	}}
+
+	result, err := collection.UpdateOne(c, bson.M{"_id": webhook.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (wr *webhookRepository) Delete(c context.Context, id string) error {
+	collection := wr.database.Collection(wr.collection)
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := collection.DeleteOne(c, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (wr *webhookRepository) Advance(c context.Context, id primitive.ObjectID, lastEventSeq int64) error {
+	collection := wr.database.Collection(wr.collection)
+
+	// $max keeps an instance that dispatched an older batch from moving the
+	// position back.
+	_, err := collection.UpdateOne(c, bson.M{"_id": id}, bson.M{"$max": bson.M{"last_event_seq": lastEventSeq}})
+	return err
+}
+
+func (wr *webhookRepository) Succeeded(c context.Context, id primitive.ObjectID) error {
+	collection := wr.database.Collection(wr.collection)
+
+	_, err := collection.UpdateOne(c, bson.M{"_id": id, "failures": bson.M{"$gt": 0}}, bson.M{"$set": bson.M{"failures": 0}})
+	return err
+}
+
+func (wr *webhookRepository) Failed(c context.Context, id primitive.ObjectID) (int, error) {
+	collection := wr.database.Collection(wr.collection)
+
+	var webhook domain.Webhook
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(c, bson.M{"_id": id}, bson.M{"$inc": bson.M{"failures": 1}}, opts).Decode(&webhook)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return 0, domain.ErrWebhookNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return webhook.Failures, nil
+}
+
+func (wr *webhookRepository) Disable(c context.Context, id primitive.ObjectID, reason string) error {
+	collection := wr.database.Collection(wr.collection)
+
+	update := bson.M{"$set": bson.M{
+		"active":          false,
+		"disabled_reason": reason,
+		"updated_at":      time.Now(),
+	}}
+
+	_, err := collection.UpdateOne(c, bson.M{"_id": id, "active": true}, update)
+	return err
+}
@@ -0,0 +1,38 @@
+package route
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nebojsaj1726/user-manager/api/gql"
+	"github.com/nebojsaj1726/user-manager/bootstrap"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"github.com/nebojsaj1726/user-manager/repository"
+	"github.com/nebojsaj1726/user-manager/usecase"
+)
+
+func NewGraphQLRouter(env *bootstrap.Env, timeout time.Duration, db mongo.Database, group gin.IRoutes) {
+	ur := repository.NewUserRepository(db, domain.CollectionUser)
+	er := repository.NewUserEventRepository(db, domain.CollectionUserEvent)
+	obr := repository.NewOutboxRepository(db, domain.CollectionOutbox)
+	tx := repository.NewTransactor(db)
+
+	schema, err := gql.NewSchema(usecase.NewUserUseCase(ur, er, obr, tx, timeout), env.RequireIfMatch)
+	if err != nil {
+		log.Fatalf("Invalid GraphQL schema: %v", err)
+	}
+
+	handler := &gql.Handler{
+		Schema: schema,
+		Limits: gql.Limits{
+			MaxDepth:      env.GraphQLMaxDepth,
+			MaxComplexity: env.GraphQLMaxComplexity,
+		},
+	}
+
+	group.GET("/graphql", handler.Serve)
+	group.POST("/graphql", handler.Serve)
+}
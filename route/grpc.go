@@ -0,0 +1,34 @@
+package route
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	userv1 "github.com/nebojsaj1726/user-manager/api/proto/user/v1"
+	"github.com/nebojsaj1726/user-manager/api/rpc"
+	"github.com/nebojsaj1726/user-manager/bootstrap"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"github.com/nebojsaj1726/user-manager/repository"
+	"github.com/nebojsaj1726/user-manager/usecase"
+)
+
+// SetupGRPC registers the gRPC services. Reflection is enabled so tools such
+// as grpcurl can discover them.
+func SetupGRPC(env *bootstrap.Env, timeout time.Duration, db mongo.Database) *grpc.Server {
+	ur := repository.NewUserRepository(db, domain.CollectionUser)
+	er := repository.NewUserEventRepository(db, domain.CollectionUserEvent)
+	obr := repository.NewOutboxRepository(db, domain.CollectionOutbox)
+	tx := repository.NewTransactor(db)
+
+	server := grpc.NewServer()
+	userv1.RegisterUserServiceServer(server, &rpc.UserServer{
+		UserUsecase:    usecase.NewUserUseCase(ur, er, obr, tx, timeout),
+		RequireIfMatch: env.RequireIfMatch,
+	})
+	reflection.Register(server)
+
+	return server
+}
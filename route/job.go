@@ -0,0 +1,18 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+func NewJobRouter(ju domain.JobUsecase, group *gin.RouterGroup) {
+	controller := &controller.JobController{
+		JobUsecase: ju,
+	}
+
+	group.GET("/jobs/:id", controller.GetByID)
+	group.POST("/jobs/:id/cancel", controller.Cancel)
+	group.GET("/jobs/:id/result", controller.Result)
+}
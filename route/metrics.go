@@ -0,0 +1,16 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewMetricsHandler serves the Prometheus metrics. It is meant for a
+// listener of its own that is only reachable from inside the deployment,
+// since the metrics describe the traffic of every client.
+func NewMetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+	return mux
+}
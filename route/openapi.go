@@ -0,0 +1,375 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/nebojsaj1726/user-manager/api/openapi"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+var (
+	idParam = openapi.Param{Name: "id", In: "path", Pattern: "^[0-9a-fA-F]{24}$"}
+
+	asyncParam = openapi.Param{Name: "async", In: "query", Type: "boolean", Description: "Run as a background job and return 202 with the job"}
+
+	userFilterParams = []openapi.Param{
+		{Name: "email", In: "query", Description: "Exact email address"},
+		{Name: "min_age", In: "query", Type: "integer"},
+		{Name: "max_age", In: "query", Type: "integer"},
+	}
+
+	jobResponse = domain.Job{}
+)
+
+// userShapes are the documented responses written by a UserPresenter.
+type userShapes struct {
+	createdStatus int
+	created       interface{}
+	updated       interface{}
+	deletedStatus int
+	deleted       interface{}
+	list          interface{}
+	search        interface{}
+}
+
+var (
+	v1Shapes = userShapes{
+		createdStatus: http.StatusOK,
+		created:       domain.UserResponse{},
+		updated:       domain.UserResponse{},
+		deletedStatus: http.StatusOK,
+		deleted:       domain.SuccessResponse{},
+		list:          domain.UserListResponse{},
+		search:        domain.UserSearchResponse{},
+	}
+	v2Shapes = userShapes{
+		createdStatus: http.StatusCreated,
+		created:       domain.User{},
+		updated:       domain.User{},
+		deletedStatus: http.StatusNoContent,
+		list:          domain.UserPage{},
+		search:        domain.UserSearchPage{},
+	}
+)
+
+// describeVersion documents the routes registered by newVersionRouter.
+func describeVersion(registry *openapi.Registry, version apiVersion) {
+	describeUserRoutes(registry, version)
+	describeJobRoutes(registry, version)
+	describeWebhookRoutes(registry, version)
+}
+
+// describer prefixes paths with the version and marks the operations of
+// deprecated versions.
+func describer(registry *openapi.Registry, version apiVersion) func(method, path string, op openapi.Operation) {
+	return func(method, path string, op openapi.Operation) {
+		op.Deprecated = version.deprecation != nil
+		registry.Describe(method, version.prefix+path, op)
+	}
+}
+
+// problemResponse documents the RFC 7807 body of every error response.
+func problemResponse(registry *openapi.Registry) openapi.Media {
+	return openapi.Media{ContentType: domain.ContentTypeProblem, Schema: registry.Schema(domain.Problem{})}
+}
+
+func describeUserRoutes(registry *openapi.Registry, version apiVersion) {
+	tags := []string{"users"}
+	shapes := version.shapes
+	describe := describer(registry, version)
+	problem := problemResponse(registry)
+
+	describe(http.MethodGet, "/users", openapi.Operation{
+		Summary: "List users",
+		Tags:    tags,
+		Params: append([]openapi.Param{
+			{Name: "page", In: "query", Type: "integer"},
+			{Name: "limit", In: "query", Type: "integer"},
+			{Name: "ids", In: "query", Description: "Comma-separated IDs to look up instead of listing; see POST /users:lookup"},
+			{Name: "emails", In: "query", Description: "Comma-separated emails to look up instead of listing"},
+		}, userFilterParams...),
+		Responses: map[int]interface{}{
+			http.StatusOK:         shapes.list,
+			http.StatusBadRequest: problem,
+		},
+	})
+
+	describe(http.MethodGet, "/users/export", openapi.Operation{
+		Summary: "Export users",
+		Tags:    tags,
+		Params: append([]openapi.Param{
+			{Name: "format", In: "query", Enum: []string{"csv", "ndjson", "json"}},
+			asyncParam,
+		}, userFilterParams...),
+		Responses: map[int]interface{}{
+			http.StatusOK:         openapi.Media{ContentType: "application/octet-stream"},
+			http.StatusAccepted:   jobResponse,
+			http.StatusBadRequest: problem,
+		},
+	})
+
+	describe(http.MethodGet, "/users/search", openapi.Operation{
+		Summary: "Search users by relevance",
+		Tags:    tags,
+		Params: append([]openapi.Param{
+			{Name: "q", In: "query", Required: true, Description: "Search terms, each matching the start of a word of the email; prefix a term with - to exclude it"},
+			{Name: "mode", In: "query", Enum: []string{"text", "fuzzy"}, Description: "fuzzy tolerates typos and ranks by edit distance"},
+			{Name: "page", In: "query", Type: "integer"},
+			{Name: "limit", In: "query", Type: "integer"},
+		}, userFilterParams...),
+		Responses: map[int]interface{}{
+			http.StatusOK:         shapes.search,
+			http.StatusBadRequest: problem,
+		},
+	})
+
+	describe(http.MethodGet, "/users/events", openapi.Operation{
+		Summary: "Stream user changes as server-sent events",
+		Tags:    tags,
+		Params: []openapi.Param{
+			{Name: "last_event_id", In: "query", Pattern: "^[0-9]+$", Description: "Resume after this event; the Last-Event-ID header takes precedence"},
+			{Name: "ticket", In: "query", Description: "One-time ticket from POST /tickets, for clients that cannot send an Authorization header"},
+		},
+		Responses: map[int]interface{}{
+			http.StatusOK:         openapi.Media{ContentType: "text/event-stream", Schema: registry.Schema(domain.UserEvent{})},
+			http.StatusBadRequest: problem,
+		},
+	})
+
+	describe(http.MethodPost, "/users", openapi.Operation{
+		Summary:     "Create a user",
+		Tags:        tags,
+		RequestBody: domain.User{},
+		Responses: map[int]interface{}{
+			shapes.createdStatus:  shapes.created,
+			http.StatusBadRequest: problem,
+			http.StatusConflict:   problem,
+		},
+	})
+
+	describe(http.MethodPost, "/users/import", openapi.Operation{
+		Summary: "Import users from CSV",
+		Tags:    tags,
+		Params: []openapi.Param{
+			{Name: "dry_run", In: "query", Type: "boolean"},
+			{Name: "report", In: "query", Enum: []string{"json", "csv"}},
+			asyncParam,
+		},
+		RequestBody: openapi.Media{ContentType: "text/csv", Schema: openapi.Schema{"type": "string"}},
+		Responses: map[int]interface{}{
+			http.StatusOK:                   openapi.Media{ContentType: "application/octet-stream"},
+			http.StatusAccepted:             jobResponse,
+			http.StatusBadRequest:           problem,
+			http.StatusUnsupportedMediaType: problem,
+		},
+	})
+
+	describe(http.MethodGet, "/users/:id", openapi.Operation{
+		Summary: "Get a user",
+		Tags:    tags,
+		Params:  []openapi.Param{idParam},
+		Responses: map[int]interface{}{
+			http.StatusOK:       domain.User{},
+			http.StatusNotFound: problem,
+		},
+	})
+
+	describe(http.MethodPut, "/users/:id", openapi.Operation{
+		Summary:     "Replace a user",
+		Tags:        tags,
+		Params:      []openapi.Param{idParam},
+		RequestBody: domain.User{},
+		Responses: map[int]interface{}{
+			http.StatusOK:                   shapes.updated,
+			http.StatusBadRequest:           problem,
+			http.StatusNotFound:             problem,
+			http.StatusConflict:             problem,
+			http.StatusPreconditionFailed:   problem,
+			http.StatusPreconditionRequired: problem,
+		},
+	})
+
+	describe(http.MethodPatch, "/users/:id", openapi.Operation{
+		Summary: "Apply a JSON Patch to a user",
+		Tags:    tags,
+		Params:  []openapi.Param{idParam},
+		RequestBody: openapi.Media{ContentType: domain.ContentTypeJSONPatch, Schema: openapi.Schema{
+			"type":  "array",
+			"items": registry.Schema(domain.PatchOperation{}),
+		}},
+		Responses: map[int]interface{}{
+			http.StatusOK:                   shapes.updated,
+			http.StatusBadRequest:           problem,
+			http.StatusNotFound:             problem,
+			http.StatusConflict:             problem,
+			http.StatusPreconditionFailed:   problem,
+			http.StatusUnsupportedMediaType: problem,
+		},
+	})
+
+	describe(http.MethodDelete, "/users/:id", openapi.Operation{
+		Summary: "Delete a user",
+		Tags:    tags,
+		Params:  []openapi.Param{idParam},
+		Responses: map[int]interface{}{
+			shapes.deletedStatus:          shapes.deleted,
+			http.StatusNotFound:           problem,
+			http.StatusPreconditionFailed: problem,
+		},
+	})
+
+	describe(http.MethodPost, "/users:batch", openapi.Operation{
+		Summary:     "Create, update and delete users in one request",
+		Tags:        tags,
+		Params:      []openapi.Param{asyncParam},
+		RequestBody: domain.BatchRequest{},
+		Responses: map[int]interface{}{
+			http.StatusOK:                    domain.BatchResponse{},
+			http.StatusAccepted:              jobResponse,
+			http.StatusBadRequest:            domain.BatchResponse{},
+			http.StatusRequestEntityTooLarge: problem,
+		},
+	})
+
+	describe(http.MethodPost, "/users:lookup", openapi.Operation{
+		Summary:     "Look up users by ID and email",
+		Tags:        tags,
+		RequestBody: domain.UserLookupRequest{},
+		Responses: map[int]interface{}{
+			http.StatusOK:         domain.UserLookup{},
+			http.StatusBadRequest: problem,
+		},
+	})
+}
+
+func describeJobRoutes(registry *openapi.Registry, version apiVersion) {
+	tags := []string{"jobs"}
+	describe := describer(registry, version)
+	problem := problemResponse(registry)
+
+	describe(http.MethodGet, "/jobs/:id", openapi.Operation{
+		Summary: "Get a background job",
+		Tags:    tags,
+		Params:  []openapi.Param{idParam},
+		Responses: map[int]interface{}{
+			http.StatusOK:       jobResponse,
+			http.StatusNotFound: problem,
+		},
+	})
+
+	describe(http.MethodPost, "/jobs/:id/cancel", openapi.Operation{
+		Summary: "Cancel a background job",
+		Tags:    tags,
+		Params:  []openapi.Param{idParam},
+		Responses: map[int]interface{}{
+			http.StatusAccepted: jobResponse,
+			http.StatusNotFound: problem,
+		},
+	})
+
+	describe(http.MethodGet, "/jobs/:id/result", openapi.Operation{
+		Summary: "Download the result of a finished job",
+		Tags:    tags,
+		Params:  []openapi.Param{idParam},
+		Responses: map[int]interface{}{
+			http.StatusOK:       openapi.Media{ContentType: "application/octet-stream"},
+			http.StatusNotFound: problem,
+			http.StatusConflict: problem,
+		},
+	})
+}
+
+func describeWebhookRoutes(registry *openapi.Registry, version apiVersion) {
+	tags := []string{"webhooks"}
+	describe := describer(registry, version)
+	problem := problemResponse(registry)
+	deliveryParams := []openapi.Param{idParam, {Name: "delivery_id", In: "path", Pattern: "^[0-9a-fA-F]{24}$"}}
+	pageParams := []openapi.Param{
+		{Name: "page", In: "query", Type: "integer"},
+		{Name: "limit", In: "query", Type: "integer"},
+	}
+
+	describe(http.MethodGet, "/webhooks", openapi.Operation{
+		Summary: "List webhooks",
+		Tags:    tags,
+		Params:  pageParams,
+		Responses: map[int]interface{}{
+			http.StatusOK:         domain.WebhookPage{},
+			http.StatusBadRequest: problem,
+		},
+	})
+
+	describe(http.MethodPost, "/webhooks", openapi.Operation{
+		Summary:     "Subscribe a URL to user events",
+		Tags:        tags,
+		RequestBody: domain.WebhookRequest{},
+		Responses: map[int]interface{}{
+			http.StatusCreated:    domain.Webhook{},
+			http.StatusBadRequest: problem,
+		},
+	})
+
+	describe(http.MethodGet, "/webhooks/:id", openapi.Operation{
+		Summary: "Get a webhook",
+		Tags:    tags,
+		Params:  []openapi.Param{idParam},
+		Responses: map[int]interface{}{
+			http.StatusOK:       domain.Webhook{},
+			http.StatusNotFound: problem,
+		},
+	})
+
+	describe(http.MethodPut, "/webhooks/:id", openapi.Operation{
+		Summary:     "Replace a webhook; setting active enables a disabled one again",
+		Tags:        tags,
+		Params:      []openapi.Param{idParam},
+		RequestBody: domain.WebhookRequest{},
+		Responses: map[int]interface{}{
+			http.StatusOK:         domain.Webhook{},
+			http.StatusBadRequest: problem,
+			http.StatusNotFound:   problem,
+		},
+	})
+
+	describe(http.MethodDelete, "/webhooks/:id", openapi.Operation{
+		Summary: "Delete a webhook",
+		Tags:    tags,
+		Params:  []openapi.Param{idParam},
+		Responses: map[int]interface{}{
+			http.StatusNoContent: nil,
+			http.StatusNotFound:  problem,
+		},
+	})
+
+	describe(http.MethodGet, "/webhooks/:id/deliveries", openapi.Operation{
+		Summary: "List the deliveries of a webhook, newest first",
+		Tags:    tags,
+		Params:  append([]openapi.Param{idParam}, pageParams...),
+		Responses: map[int]interface{}{
+			http.StatusOK:         domain.WebhookDeliveryPage{},
+			http.StatusBadRequest: problem,
+			http.StatusNotFound:   problem,
+		},
+	})
+
+	describe(http.MethodGet, "/webhooks/:id/deliveries/:delivery_id", openapi.Operation{
+		Summary: "Get a webhook delivery with its attempts",
+		Tags:    tags,
+		Params:  deliveryParams,
+		Responses: map[int]interface{}{
+			http.StatusOK:       domain.WebhookDelivery{},
+			http.StatusNotFound: problem,
+		},
+	})
+
+	describe(http.MethodPost, "/webhooks/:id/deliveries/:delivery_id/replay", openapi.Operation{
+		Summary: "Send a webhook delivery again",
+		Tags:    tags,
+		Params:  deliveryParams,
+		Responses: map[int]interface{}{
+			http.StatusAccepted: domain.WebhookDelivery{},
+			http.StatusNotFound: problem,
+			http.StatusConflict: problem,
+		},
+	})
+}
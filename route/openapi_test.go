@@ -0,0 +1,31 @@
+package route
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nebojsaj1726/user-manager/api/openapi"
+	"github.com/nebojsaj1726/user-manager/bootstrap"
+)
+
+// The descriptions are written by hand, so every versioned route must have
+// one.
+func TestDescribeVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	env := &bootstrap.Env{}
+	router := gin.New()
+	registry := openapi.NewRegistry("Test", "1.0.0")
+
+	for _, version := range apiVersions(env) {
+		describeVersion(registry, version)
+		newVersionRouter(context.Background(), env, time.Second, nil, nil, nil, version, router.Group(version.prefix))
+	}
+
+	assert.NotEmpty(t, router.Routes())
+	assert.Empty(t, registry.Undescribed(router.Routes()))
+}
@@ -1,15 +1,204 @@
 package route
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
 
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/api/openapi"
 	"github.com/nebojsaj1726/user-manager/bootstrap"
+	"github.com/nebojsaj1726/user-manager/domain"
 	"github.com/nebojsaj1726/user-manager/mongo"
+	"github.com/nebojsaj1726/user-manager/repository"
+	"github.com/nebojsaj1726/user-manager/usecase"
+	"github.com/nebojsaj1726/user-manager/worker"
 )
 
-func Setup(env *bootstrap.Env, timeout time.Duration, db mongo.Database, router *gin.Engine) {
-	userGroup := router.Group("/users")
-	NewUserRouter(env, timeout, db, userGroup)
+// Setup registers the routes and starts the background workers, which run
+// until ctx is done. It returns a function that waits for them to stop.
+func Setup(ctx context.Context, env *bootstrap.Env, timeout time.Duration, db mongo.Database, publisher domain.EventPublisher, router *gin.Engine) (wait func()) {
+	controller.RegisterFieldNames()
+
+	var workers sync.WaitGroup
+
+	ir := repository.NewIdempotencyRepository(db, domain.CollectionIdempotency)
+	tr := repository.NewTicketRepository(db, domain.CollectionTicket)
+
+	setupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := ir.EnsureIndexes(setupCtx); err != nil {
+		log.Errorf("Failed to create idempotency indexes: %v", err)
+	}
+	if err := tr.EnsureIndexes(setupCtx); err != nil {
+		log.Errorf("Failed to create ticket indexes: %v", err)
+	}
+	ur := repository.NewUserRepository(db, domain.CollectionUser)
+	if err := ur.EnsureIndexes(setupCtx); err != nil {
+		log.Errorf("Failed to create user indexes: %v", err)
+	}
+	// Users without a version would get the ETag "0", which If-Match treats
+	// as no precondition at all.
+	if updated, err := ur.BackfillVersions(setupCtx); err != nil {
+		log.Errorf("Failed to backfill user versions: %v", err)
+	} else if updated > 0 {
+		log.Infof("Backfilled versions of %d users", updated)
+	}
+	er := repository.NewUserEventRepository(db, domain.CollectionUserEvent)
+	if err := er.EnsureIndexes(setupCtx, time.Duration(env.UserEventRetention)*time.Hour); err != nil {
+		log.Errorf("Failed to create user event indexes: %v", err)
+	}
+	// Users stored before search words and trigrams existed are indexed in
+	// the background so startup does not wait on large collections. It stops
+	// with ctx and resumes on the next start.
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+
+		updated, err := ur.Reindex(ctx)
+		switch {
+		case errors.Is(err, context.Canceled):
+			log.Warnf("Indexing users for search stopped after %d users", updated)
+		case err != nil:
+			log.Errorf("Failed to index users for search after %d users: %v", updated, err)
+		case updated > 0:
+			log.Infof("Indexed %d users for search", updated)
+		}
+	}()
+
+	obr := repository.NewOutboxRepository(db, domain.CollectionOutbox)
+	if err := obr.EnsureIndexes(setupCtx, time.Duration(env.OutboxRetention)*time.Hour); err != nil {
+		log.Errorf("Failed to create outbox indexes: %v", err)
+	}
+
+	wdr := repository.NewWebhookDeliveryRepository(db, domain.CollectionWebhookDelivery)
+	if err := wdr.EnsureIndexes(setupCtx, time.Duration(env.WebhookRetention)*24*time.Hour); err != nil {
+		log.Errorf("Failed to create webhook delivery indexes: %v", err)
+	}
+
+	idempotencyTTL := time.Duration(env.IdempotencyTTL) * time.Hour
+	idempotencyLease := time.Duration(env.IdempotencyLease) * time.Second
+
+	jr := repository.NewJobRepository(db, domain.CollectionJob)
+	if err := jr.EnsureIndexes(setupCtx); err != nil {
+		log.Errorf("Failed to create job indexes: %v", err)
+	}
+	jfr := repository.NewJobFileRepository(db, domain.BucketJobFile)
+	ju := usecase.NewJobUsecase(jr, jfr, time.Duration(env.JobRetention)*time.Hour, timeout)
+
+	wr := repository.NewWebhookRepository(db, domain.CollectionWebhook)
+	wu := usecase.NewWebhookUsecase(wr, wdr, er, usecase.WebhookOptions{
+		RequestTimeout:       time.Duration(env.WebhookTimeout) * time.Second,
+		MaxAttempts:          env.WebhookMaxAttempts,
+		DisableAfter:         env.WebhookDisableAfter,
+		AllowPrivateNetworks: env.WebhookAllowPrivate,
+	}, timeout)
+
+	ou := usecase.NewOutboxUsecase(obr, publisher, timeout)
+
+	// The WebSocket and event streams push every change to connected
+	// clients and require an access token. Browsers, which open them
+	// without headers, redeem a ticket instead.
+	tokens := accessTokens(env)
+	tu := ticketUsecase(env, timeout, db)
+
+	registry := openapi.NewRegistry("User Manager API", "1.0.0")
+	metrics := middleware.NewMetrics(prometheus.DefaultRegisterer)
+
+	router.GET("/openapi.json", registry.Handler(router))
+	router.GET("/docs", openapi.DocsHandler("/openapi.json", "/docs/assets"))
+	router.StaticFS("/docs/assets", openapi.DocsAssets())
+
+	// GraphQL evolves its schema in place instead of through URL versions.
+	NewGraphQLRouter(env, timeout, db, router.Group("", metrics.Middleware("graphql")))
+	// SCIM is versioned by its own specification.
+	NewScimRouter(env, timeout, db, router.Group("/scim/v2", metrics.Middleware("scim")))
+	// The WebSocket protocol is versioned by its messages.
+	hub := NewSocketRouter(env, timeout, db, router.Group("/ws", metrics.Middleware("ws"), middleware.BearerToken(tokens, tu)))
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		hub.Run(ctx)
+	}()
+	// Tickets are issued for a token, never for another ticket.
+	NewTicketRouter(tu, router.Group("/tickets", metrics.Middleware("tickets"), middleware.BearerToken(tokens, nil), middleware.Errors()))
+
+	versions := apiVersions(env)
+	for _, version := range versions {
+		describeVersion(registry, version)
+	}
+
+	// The validator checks the routes described so far.
+	var validator gin.HandlerFunc
+	if env.OpenAPIValidation {
+		var err error
+		validator, err = registry.Validator()
+		if err != nil {
+			log.Fatalf("Invalid OpenAPI description: %v", err)
+		}
+	}
+
+	var userController *controller.UserController
+	for _, version := range versions {
+		middlewares := []gin.HandlerFunc{
+			middleware.APIVersion(version.name, version.prefix, version.deprecation),
+			metrics.Middleware(version.name),
+		}
+		// Invalid requests are rejected before an idempotency key is claimed.
+		if validator != nil {
+			middlewares = append(middlewares, validator)
+		}
+		middlewares = append(middlewares, middleware.Idempotency(ir, idempotencyTTL, idempotencyLease), middleware.Errors())
+
+		userController = newVersionRouter(ctx, env, timeout, db, ju, wu, version, router.Group(version.prefix, middlewares...))
+	}
+
+	jobHandlers := usecase.NewUserJobHandlers(userController.UserUsecase, controller.BatchJobResult)
+	pool := worker.NewPool(ju, jobHandlers, env.JobWorkers)
+	pool.Start(ctx)
+	webhooks := worker.NewWebhooks(wu, env.WebhookWorkers)
+	webhooks.Start(ctx)
+	relay := worker.NewRelay(ou)
+	relay.Start(ctx)
+
+	return func() {
+		workers.Wait()
+		pool.Wait()
+		webhooks.Wait()
+		relay.Wait()
+	}
+}
+
+// newVersionRouter registers the routes of one API version, which
+// describeVersion documents.
+func newVersionRouter(ctx context.Context, env *bootstrap.Env, timeout time.Duration, db mongo.Database, ju domain.JobUsecase, wu domain.WebhookUsecase, version apiVersion, group *gin.RouterGroup) *controller.UserController {
+	userController := NewUserRouter(ctx, env, timeout, db, ju, version.presenter, group)
+	NewJobRouter(ju, group)
+	NewWebhookRouter(wu, group)
+	return userController
+}
+
+// ticketUsecase returns the usecase of the tickets that open event streams
+// and WebSockets.
+func ticketUsecase(env *bootstrap.Env, timeout time.Duration, db mongo.Database) domain.TicketUsecase {
+	tr := repository.NewTicketRepository(db, domain.CollectionTicket)
+	return usecase.NewTicketUsecase(tr, time.Duration(env.TicketTTL)*time.Second, timeout)
+}
+
+// accessTokens returns the tokens of ACCESS_TOKENS.
+func accessTokens(env *bootstrap.Env) []string {
+	var tokens []string
+	for _, token := range strings.Split(env.AccessTokens, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
 }
@@ -0,0 +1,39 @@
+package route
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/bootstrap"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"github.com/nebojsaj1726/user-manager/repository"
+	"github.com/nebojsaj1726/user-manager/usecase"
+)
+
+func NewScimRouter(env *bootstrap.Env, timeout time.Duration, db mongo.Database, group gin.IRoutes) {
+	ur := repository.NewUserRepository(db, domain.CollectionUser)
+	er := repository.NewUserEventRepository(db, domain.CollectionUserEvent)
+	obr := repository.NewOutboxRepository(db, domain.CollectionOutbox)
+	tx := repository.NewTransactor(db)
+	sc := &controller.ScimController{
+		UserUsecase:    usecase.NewUserUseCase(ur, er, obr, tx, timeout),
+		RequireIfMatch: env.RequireIfMatch,
+		MaxResults:     env.ScimMaxResults,
+	}
+
+	group.GET("/Users", sc.List)
+	group.POST("/Users", sc.Create)
+	group.GET("/Users/:id", sc.Get)
+	group.PUT("/Users/:id", sc.Replace)
+	group.PATCH("/Users/:id", sc.Patch)
+	group.DELETE("/Users/:id", sc.Delete)
+
+	group.GET("/ServiceProviderConfig", sc.ServiceProviderConfig)
+	group.GET("/ResourceTypes", sc.ResourceTypes)
+	group.GET("/ResourceTypes/:id", sc.ResourceType)
+	group.GET("/Schemas", sc.Schemas)
+	group.GET("/Schemas/:id", sc.Schema)
+}
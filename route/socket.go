@@ -0,0 +1,42 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/api/socket"
+	"github.com/nebojsaj1726/user-manager/bootstrap"
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/mongo"
+	"github.com/nebojsaj1726/user-manager/repository"
+	"github.com/nebojsaj1726/user-manager/usecase"
+)
+
+// NewSocketRouter returns the hub of the WebSocket connections, which the
+// caller runs.
+func NewSocketRouter(env *bootstrap.Env, timeout time.Duration, db mongo.Database, group *gin.RouterGroup) *socket.Hub {
+	er := repository.NewUserEventRepository(db, domain.CollectionUserEvent)
+	hub := socket.NewHub(usecase.NewUserEventUsecase(er, timeout), socket.Options{
+		PollInterval: time.Duration(env.UserEventPoll) * time.Second,
+		PingInterval: time.Duration(env.WSPingInterval) * time.Second,
+		SendBuffer:   env.WSSendBuffer,
+	})
+
+	controller := &controller.UserSocketController{
+		Hub: hub,
+		Upgrader: websocket.Upgrader{
+			// Clients authenticate with a token or ticket rather than
+			// cookies, so a page on another origin gains nothing by
+			// connecting.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+
+	group.GET("", controller.Connect)
+
+	return hub
+}
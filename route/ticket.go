@@ -0,0 +1,16 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+func NewTicketRouter(tu domain.TicketUsecase, group *gin.RouterGroup) {
+	controller := &controller.TicketController{
+		TicketUsecase: tu,
+	}
+
+	group.POST("", controller.Create)
+}
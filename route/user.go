@@ -1,11 +1,16 @@
 package route
 
 import (
+	"context"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/api/media"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
 	"github.com/nebojsaj1726/user-manager/bootstrap"
 	"github.com/nebojsaj1726/user-manager/domain"
 	"github.com/nebojsaj1726/user-manager/mongo"
@@ -13,15 +18,61 @@ import (
 	"github.com/nebojsaj1726/user-manager/usecase"
 )
 
-func NewUserRouter(env *bootstrap.Env, timeout time.Duration, db mongo.Database, group *gin.RouterGroup) {
+func NewUserRouter(ctx context.Context, env *bootstrap.Env, timeout time.Duration, db mongo.Database, ju domain.JobUsecase, presenter controller.UserPresenter, group *gin.RouterGroup) *controller.UserController {
 	ur := repository.NewUserRepository(db, domain.CollectionUser)
+	er := repository.NewUserEventRepository(db, domain.CollectionUserEvent)
+	obr := repository.NewOutboxRepository(db, domain.CollectionOutbox)
+	tx := repository.NewTransactor(db)
+	eventController := &controller.UserEventController{
+		UserEventUsecase:  usecase.NewUserEventUsecase(er, timeout),
+		PollInterval:      time.Duration(env.UserEventPoll) * time.Second,
+		HeartbeatInterval: time.Duration(env.UserEventHeartbeat) * time.Second,
+		Done:              ctx.Done(),
+	}
+
 	controller := &controller.UserController{
-		UserUsecase: usecase.NewUserUseCase(ur, timeout),
+		UserUsecase:    usecase.NewUserUseCase(ur, er, obr, tx, timeout),
+		JobUsecase:     ju,
+		RequireIfMatch: env.RequireIfMatch,
+		MaxBatchSize:   env.BatchMaxOperations,
+		MaxLookupSize:  env.LookupMaxIDs,
+		Presenter:      presenter,
+	}
+
+	// Export and import choose their formats themselves; every other user
+	// route answers in the representation negotiated from Accept.
+	negotiate := media.Negotiate(media.Default())
+
+	group.GET("/users", negotiate, controller.Fetch)
+	group.GET("/users/export", controller.Export)
+	group.GET("/users/search", negotiate, controller.Search)
+	// The event streams carry the same data as the WebSocket and require the
+	// same tokens or tickets.
+	group.GET("/users/events", middleware.BearerToken(accessTokens(env), ticketUsecase(env, timeout, db)), eventController.Stream)
+	group.POST("/users", negotiate, controller.Create)
+	group.POST("/users/import", controller.Import)
+	group.GET("/users/:id", negotiate, controller.GetByID)
+	group.PUT("/users/:id", negotiate, controller.Update)
+	group.PATCH("/users/:id", negotiate, controller.Patch)
+	group.DELETE("/users/:id", negotiate, controller.Delete)
+
+	// Collection-level custom methods such as POST /users:batch share one
+	// route because the router treats ":" as the start of a parameter.
+	actions := map[string]gin.HandlerFunc{
+		"batch":  controller.Batch,
+		"lookup": controller.Lookup,
 	}
+	group.POST("/users:action", negotiate, func(c *gin.Context) {
+		// The parameter also matches paths such as /usersbatch, which must
+		// not reach an action.
+		name, found := strings.CutPrefix(c.Param("action"), ":")
+		handler, ok := actions[name]
+		if !found || !ok {
+			c.Error(domain.NewProblem(http.StatusNotFound, "Unknown action").WithCode("unknown_action", nil))
+			return
+		}
+		handler(c)
+	})
 
-	group.GET("", controller.Fetch)
-	group.POST("", controller.Create)
-	group.GET("/:id", controller.GetByID)
-	group.PUT("/:id", controller.Update)
-	group.DELETE("/:id", controller.Delete)
+	return controller
 }
@@ -0,0 +1,61 @@
+package route
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/api/middleware"
+	"github.com/nebojsaj1726/user-manager/bootstrap"
+)
+
+const deprecationDateLayout = "2006-01-02"
+
+// apiVersion is one mounted version of the API. Versions share use cases and
+// differ in the presenter that shapes their responses.
+type apiVersion struct {
+	name        string
+	prefix      string
+	presenter   controller.UserPresenter
+	shapes      userShapes
+	deprecation *middleware.Deprecation
+}
+
+// apiVersions lists the mounted versions. The unversioned routes predate
+// /v1 and are kept as a deprecated alias of it for existing clients.
+func apiVersions(env *bootstrap.Env) []apiVersion {
+	v1Deprecation := &middleware.Deprecation{
+		Since:     parseDeprecationDate("API_V1_DEPRECATED_AT", env.APIV1DeprecatedAt),
+		Sunset:    parseDeprecationDate("API_V1_SUNSET", env.APIV1Sunset),
+		Successor: "/v2",
+	}
+	if v1Deprecation.Since.IsZero() && v1Deprecation.Sunset.IsZero() {
+		v1Deprecation = nil
+	}
+
+	unversionedDeprecation := &middleware.Deprecation{Successor: "/v1"}
+	if v1Deprecation != nil {
+		unversionedDeprecation.Since = v1Deprecation.Since
+		unversionedDeprecation.Sunset = v1Deprecation.Sunset
+	}
+
+	return []apiVersion{
+		{name: "unversioned", prefix: "", presenter: controller.V1Presenter{}, shapes: v1Shapes, deprecation: unversionedDeprecation},
+		{name: "v1", prefix: "/v1", presenter: controller.V1Presenter{}, shapes: v1Shapes, deprecation: v1Deprecation},
+		{name: "v2", prefix: "/v2", presenter: controller.V2Presenter{}, shapes: v2Shapes},
+	}
+}
+
+func parseDeprecationDate(key, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	date, err := time.Parse(deprecationDateLayout, value)
+	if err != nil {
+		log.Errorf("Ignoring %s, expected a date like %s: %v", key, deprecationDateLayout, err)
+		return time.Time{}
+	}
+	return date
+}
@@ -0,0 +1,23 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/nebojsaj1726/user-manager/api/controller"
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+func NewWebhookRouter(wu domain.WebhookUsecase, group *gin.RouterGroup) {
+	controller := &controller.WebhookController{
+		WebhookUsecase: wu,
+	}
+
+	group.GET("/webhooks", controller.Fetch)
+	group.POST("/webhooks", controller.Create)
+	group.GET("/webhooks/:id", controller.GetByID)
+	group.PUT("/webhooks/:id", controller.Update)
+	group.DELETE("/webhooks/:id", controller.Delete)
+	group.GET("/webhooks/:id/deliveries", controller.Deliveries)
+	group.GET("/webhooks/:id/deliveries/:delivery_id", controller.Delivery)
+	group.POST("/webhooks/:id/deliveries/:delivery_id/replay", controller.Replay)
+}
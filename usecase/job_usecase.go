@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// expireBatchSize is the number of expired jobs Expire deletes per query.
+const expireBatchSize = 100
+
+type jobUsecase struct {
+	jobRepository     domain.JobRepository
+	jobFileRepository domain.JobFileRepository
+	retention         time.Duration
+	contextTimeout    time.Duration
+}
+
+// NewJobUsecase returns a JobUsecase that keeps finished jobs and their
+// results for retention.
+func NewJobUsecase(jobRepository domain.JobRepository, jobFileRepository domain.JobFileRepository, retention, timeout time.Duration) domain.JobUsecase {
+	return &jobUsecase{
+		jobRepository:     jobRepository,
+		jobFileRepository: jobFileRepository,
+		retention:         retention,
+		contextTimeout:    timeout,
+	}
+}
+
+func (u *jobUsecase) Submit(c context.Context, job *domain.Job, input io.Reader) error {
+	now := time.Now()
+	job.ID = primitive.NewObjectID()
+	job.Status = domain.JobStatusQueued
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if input != nil {
+		// The upload is copied before the timeout starts, it may be large.
+		file, err := u.jobFileRepository.Create(c, inputName(job.ID))
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(file, input)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			u.removeFiles(c, inputName(job.ID))
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+	return u.jobRepository.Create(ctx, job)
+}
+
+func (u *jobUsecase) GetByID(c context.Context, id string) (*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+	return u.jobRepository.GetByID(ctx, id)
+}
+
+func (u *jobUsecase) Cancel(c context.Context, id string) (*domain.Job, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if err := u.jobRepository.Cancel(ctx, id, time.Now().Add(u.retention)); err != nil {
+		return nil, err
+	}
+
+	return u.jobRepository.GetByID(ctx, id)
+}
+
+func (u *jobUsecase) OpenResult(c context.Context, id string) (*domain.Job, io.ReadCloser, error) {
+	job, err := u.GetByID(c, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if job.Status != domain.JobStatusSucceeded {
+		return job, nil, domain.NewConflictError("job is %s, no result is available", job.Status)
+	}
+
+	file, err := u.jobFileRepository.Open(c, resultName(job.ID))
+	if err != nil {
+		return job, nil, err
+	}
+
+	return job, file, nil
+}
+
+func (u *jobUsecase) Claim(c context.Context) (*domain.Job, io.ReadCloser, io.WriteCloser, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	job, err := u.jobRepository.Claim(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The files are read and written for as long as the job runs, so they
+	// are not bound by the timeout.
+	var input io.ReadCloser
+	file, err := u.jobFileRepository.Open(c, inputName(job.ID))
+	if err == nil {
+		input = file
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return job, nil, nil, err
+	}
+
+	output, err := u.jobFileRepository.Create(c, resultName(job.ID))
+	if err != nil {
+		if input != nil {
+			input.Close()
+		}
+		return job, nil, nil, err
+	}
+
+	return job, input, output, nil
+}
+
+func (u *jobUsecase) Heartbeat(c context.Context, job *domain.Job, processed, total int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+	return u.jobRepository.Heartbeat(ctx, job.ID, processed, total)
+}
+
+func (u *jobUsecase) Finish(c context.Context, job *domain.Job, err error) error {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	expiresAt := now.Add(u.retention)
+	job.UpdatedAt = now
+	job.FinishedAt = &now
+	job.ExpiresAt = &expiresAt
+
+	switch {
+	case err == nil:
+		job.Status = domain.JobStatusSucceeded
+		job.ResultLocation = job.BasePath + "/jobs/" + job.ID.Hex() + "/result"
+	case errors.Is(err, context.Canceled):
+		job.Status = domain.JobStatusCanceled
+	default:
+		job.Status = domain.JobStatusFailed
+		job.ErrorCode, job.Error = jobFailure(job, err)
+	}
+
+	u.removeFiles(ctx, inputName(job.ID))
+	if job.Status != domain.JobStatusSucceeded {
+		u.removeFiles(ctx, resultName(job.ID))
+	}
+
+	return u.jobRepository.Finish(ctx, job)
+}
+
+func (u *jobUsecase) FailStale(c context.Context, staleAfter time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+	now := time.Now()
+	return u.jobRepository.FailStale(ctx, now.Add(-staleAfter), now.Add(u.retention))
+}
+
+func (u *jobUsecase) Expire(c context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	var count int64
+	for {
+		jobs, err := u.jobRepository.Expired(ctx, expireBatchSize)
+		if err != nil {
+			return count, err
+		}
+
+		for _, job := range jobs {
+			// The files go first: a job whose files could not be deleted is
+			// retried by the next run rather than leaving them orphaned.
+			if err := u.jobFileRepository.Delete(ctx, inputName(job.ID)); err != nil {
+				return count, err
+			}
+			if err := u.jobFileRepository.Delete(ctx, resultName(job.ID)); err != nil {
+				return count, err
+			}
+			if err := u.jobRepository.Delete(ctx, job.ID); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(jobs) < expireBatchSize {
+			return count, nil
+		}
+	}
+}
+
+// jobFailure returns the code and message job reports for err. Domain errors
+// are meant for clients and reported as they are; other errors may expose
+// internals, so they are logged and reported as an internal error.
+func jobFailure(job *domain.Job, err error) (string, string) {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		log.Errorf("Job %s failed: %v", job.ID.Hex(), err)
+		return domain.JobErrorInternal, "the job failed unexpectedly"
+	}
+
+	switch {
+	case domainErr.Code != "":
+		return domainErr.Code, domainErr.Message
+	case errors.Is(err, domain.ErrNotFound):
+		return "not_found", domainErr.Message
+	case errors.Is(err, domain.ErrConflict):
+		return "conflict", domainErr.Message
+	default:
+		return "validation_failed", domainErr.Message
+	}
+}
+
+// removeFiles deletes job files that are no longer needed. Failures only
+// leave storage behind, so they are logged rather than returned.
+func (u *jobUsecase) removeFiles(c context.Context, names ...string) {
+	for _, name := range names {
+		if err := u.jobFileRepository.Delete(c, name); err != nil {
+			log.Errorf("Failed to delete job file %s: %v", name, err)
+		}
+	}
+}
+
+func inputName(id primitive.ObjectID) string {
+	return id.Hex() + ".input"
+}
+
+func resultName(id primitive.ObjectID) string {
+	return id.Hex() + ".result"
+}
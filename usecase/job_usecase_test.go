@@ -0,0 +1,228 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/usecase"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MockJobRepository struct {
+	jobs map[string]*domain.Job
+}
+
+func (m *MockJobRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockJobRepository) Create(ctx context.Context, job *domain.Job) error {
+	m.jobs[job.ID.Hex()] = job
+	return nil
+}
+
+func (m *MockJobRepository) GetByID(ctx context.Context, id string) (*domain.Job, error) {
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, errors.New("job not found")
+	}
+	return job, nil
+}
+
+func (m *MockJobRepository) Claim(ctx context.Context) (*domain.Job, error) {
+	for _, job := range m.jobs {
+		if job.Status == domain.JobStatusQueued {
+			job.Status = domain.JobStatusRunning
+			return job, nil
+		}
+	}
+	return nil, errors.New("no queued jobs")
+}
+
+func (m *MockJobRepository) Heartbeat(ctx context.Context, id primitive.ObjectID, processed, total int64) (bool, error) {
+	job := m.jobs[id.Hex()]
+	job.Processed, job.Total = processed, total
+	return job.CancelRequested, nil
+}
+
+func (m *MockJobRepository) Finish(ctx context.Context, job *domain.Job) error {
+	m.jobs[job.ID.Hex()] = job
+	return nil
+}
+
+func (m *MockJobRepository) Cancel(ctx context.Context, id string, expiresAt time.Time) error {
+	m.jobs[id].CancelRequested = true
+	return nil
+}
+
+func (m *MockJobRepository) FailStale(ctx context.Context, before, expiresAt time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockJobRepository) Expired(ctx context.Context, limit int64) ([]domain.Job, error) {
+	var jobs []domain.Job
+	for _, job := range m.jobs {
+		if job.ExpiresAt != nil && !job.ExpiresAt.After(time.Now()) && int64(len(jobs)) < limit {
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *MockJobRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	delete(m.jobs, id.Hex())
+	return nil
+}
+
+type MockJobFileRepository struct {
+	files map[string][]byte
+}
+
+type mockJobFile struct {
+	bytes.Buffer
+	close func([]byte)
+}
+
+func (f *mockJobFile) Close() error {
+	f.close(f.Bytes())
+	return nil
+}
+
+func (m *MockJobFileRepository) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	delete(m.files, name)
+	return &mockJobFile{close: func(data []byte) { m.files[name] = data }}, nil
+}
+
+func (m *MockJobFileRepository) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	data, ok := m.files[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *MockJobFileRepository) Delete(ctx context.Context, name string) error {
+	delete(m.files, name)
+	return nil
+}
+
+func TestJobUseCase_Lifecycle(t *testing.T) {
+	repoMock := &MockJobRepository{jobs: map[string]*domain.Job{}}
+	fileMock := &MockJobFileRepository{files: map[string][]byte{}}
+	jobUseCase := usecase.NewJobUsecase(repoMock, fileMock, time.Hour, 10*time.Second)
+
+	job := &domain.Job{Type: domain.JobTypeImport, BasePath: "/v2"}
+	err := jobUseCase.Submit(context.TODO(), job, strings.NewReader("email,age\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, domain.JobStatusQueued, job.Status)
+
+	_, _, err = jobUseCase.OpenResult(context.TODO(), job.ID.Hex())
+	assert.Error(t, err)
+
+	claimed, input, output, err := jobUseCase.Claim(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, claimed.ID)
+
+	data, err := io.ReadAll(input)
+	assert.NoError(t, err)
+	assert.Equal(t, "email,age\n", string(data))
+	input.Close()
+
+	_, err = io.WriteString(output, "report")
+	assert.NoError(t, err)
+	output.Close()
+
+	err = jobUseCase.Finish(context.TODO(), claimed, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.JobStatusSucceeded, claimed.Status)
+	assert.Equal(t, "/v2/jobs/"+job.ID.Hex()+"/result", claimed.ResultLocation)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *claimed.ExpiresAt, time.Minute)
+
+	_, result, err := jobUseCase.OpenResult(context.TODO(), job.ID.Hex())
+	assert.NoError(t, err)
+	data, _ = io.ReadAll(result)
+	result.Close()
+	assert.Equal(t, "report", string(data))
+	assert.NotContains(t, fileMock.files, job.ID.Hex()+".input")
+}
+
+func TestJobUseCase_FinishWithError(t *testing.T) {
+	repoMock := &MockJobRepository{jobs: map[string]*domain.Job{}}
+	fileMock := &MockJobFileRepository{files: map[string][]byte{}}
+	jobUseCase := usecase.NewJobUsecase(repoMock, fileMock, time.Hour, 10*time.Second)
+
+	job := &domain.Job{Type: domain.JobTypeExport}
+	assert.NoError(t, jobUseCase.Submit(context.TODO(), job, nil))
+
+	claimed, input, output, err := jobUseCase.Claim(context.TODO())
+	assert.NoError(t, err)
+	assert.Nil(t, input)
+	output.Close()
+
+	err = jobUseCase.Finish(context.TODO(), claimed, context.Canceled)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.JobStatusCanceled, claimed.Status)
+
+	_, _, err = jobUseCase.OpenResult(context.TODO(), job.ID.Hex())
+	assert.Error(t, err)
+
+	job = &domain.Job{Type: domain.JobTypeExport}
+	assert.NoError(t, jobUseCase.Submit(context.TODO(), job, nil))
+	claimed, _, output, err = jobUseCase.Claim(context.TODO())
+	assert.NoError(t, err)
+	output.Close()
+
+	err = jobUseCase.Finish(context.TODO(), claimed, errors.New("connection reset by peer"))
+	assert.NoError(t, err)
+	assert.Equal(t, domain.JobStatusFailed, claimed.Status)
+	assert.Equal(t, domain.JobErrorInternal, claimed.ErrorCode)
+	assert.Equal(t, "the job failed unexpectedly", claimed.Error)
+	assert.Empty(t, fileMock.files)
+
+	job = &domain.Job{Type: domain.JobTypeImport}
+	assert.NoError(t, jobUseCase.Submit(context.TODO(), job, nil))
+	claimed, _, output, err = jobUseCase.Claim(context.TODO())
+	assert.NoError(t, err)
+	output.Close()
+
+	err = jobUseCase.Finish(context.TODO(), claimed, domain.NewValidationError("import job has no input file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "validation_failed", claimed.ErrorCode)
+	assert.Equal(t, "import job has no input file", claimed.Error)
+}
+
+func TestJobUseCase_Expire(t *testing.T) {
+	repoMock := &MockJobRepository{jobs: map[string]*domain.Job{}}
+	fileMock := &MockJobFileRepository{files: map[string][]byte{}}
+	jobUseCase := usecase.NewJobUsecase(repoMock, fileMock, 0, 10*time.Second)
+
+	job := &domain.Job{Type: domain.JobTypeExport}
+	assert.NoError(t, jobUseCase.Submit(context.TODO(), job, nil))
+	claimed, _, output, err := jobUseCase.Claim(context.TODO())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = io.WriteString(output, "report")
+	assert.NoError(t, err)
+	output.Close()
+	assert.NoError(t, jobUseCase.Finish(context.TODO(), claimed, nil))
+	assert.Contains(t, fileMock.files, job.ID.Hex()+".result")
+
+	queued := &domain.Job{Type: domain.JobTypeExport}
+	assert.NoError(t, jobUseCase.Submit(context.TODO(), queued, nil))
+
+	count, err := jobUseCase.Expire(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Empty(t, fileMock.files)
+	assert.NotContains(t, repoMock.jobs, job.ID.Hex())
+	assert.Contains(t, repoMock.jobs, queued.ID.Hex())
+}
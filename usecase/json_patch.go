@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// applyPatch applies RFC 6902 operations to a JSON document decoded into
+// interface{} values. The document is modified in place, so callers should
+// pass a copy and discard it if an error is returned.
+func applyPatch(doc interface{}, ops []domain.PatchOperation) (interface{}, error) {
+	if len(ops) == 0 {
+		return nil, domain.NewValidationError("invalid patch: no operations")
+	}
+
+	for i, op := range ops {
+		tokens, err := parsePointer(op.Path)
+		if err != nil {
+			return nil, domain.NewValidationError("invalid patch operation %d: %v", i, err)
+		}
+
+		var value interface{}
+		if op.Op != "remove" {
+			if len(op.Value) == 0 {
+				return nil, domain.NewValidationError("invalid patch operation %d: value is required", i)
+			}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, domain.NewValidationError("invalid patch operation %d: %v", i, err)
+			}
+		}
+
+		switch op.Op {
+		case "add":
+			doc, err = mutate(doc, tokens, func(parent interface{}, key string) (interface{}, error) {
+				return addValue(parent, key, value)
+			})
+		case "remove":
+			if len(tokens) == 0 {
+				return nil, domain.NewValidationError("invalid patch operation %d: cannot remove the document root", i)
+			}
+			doc, err = mutate(doc, tokens, removeValue)
+		case "replace":
+			doc, err = mutate(doc, tokens, func(parent interface{}, key string) (interface{}, error) {
+				return replaceValue(parent, key, value)
+			})
+		case "test":
+			var current interface{}
+			current, err = getValue(doc, tokens)
+			if err == nil && !reflect.DeepEqual(current, value) {
+				return nil, domain.NewConflictError("patch test failed: operation %d at %q", i, op.Path)
+			}
+		default:
+			return nil, domain.NewValidationError("invalid patch operation %d: unsupported op %q", i, op.Op)
+		}
+
+		if err != nil {
+			return nil, domain.NewValidationError("invalid patch operation %d: %v", i, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path %q must start with '/'", path)
+	}
+
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+	return tokens, nil
+}
+
+// mutate walks the document down to the parent of the last token and hands
+// it to fn, writing the (possibly reallocated) parent back on the way up.
+func mutate(node interface{}, tokens []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 0 {
+		return fn(nil, "")
+	}
+	if len(tokens) == 1 {
+		return fn(node, tokens[0])
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("path segment %q not found", tokens[0])
+		}
+		updated, err := mutate(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[tokens[0]] = updated
+		return n, nil
+	case []interface{}:
+		idx, err := arrayIndex(tokens[0], len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := mutate(n[idx], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = updated
+		return n, nil
+	default:
+		return nil, fmt.Errorf("path segment %q not found", tokens[0])
+	}
+}
+
+func getValue(node interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("path segment %q not found", token)
+			}
+			node = child
+		case []interface{}:
+			idx, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[idx]
+		default:
+			return nil, fmt.Errorf("path segment %q not found", token)
+		}
+	}
+	return node, nil
+}
+
+func addValue(parent interface{}, key string, value interface{}) (interface{}, error) {
+	switch p := parent.(type) {
+	case nil:
+		return value, nil
+	case map[string]interface{}:
+		p[key] = value
+		return p, nil
+	case []interface{}:
+		if key == "-" {
+			return append(p, value), nil
+		}
+		idx, err := arrayIndex(key, len(p))
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, nil)
+		copy(p[idx+1:], p[idx:])
+		p[idx] = value
+		return p, nil
+	default:
+		return nil, fmt.Errorf("cannot add %q to a scalar value", key)
+	}
+}
+
+func removeValue(parent interface{}, key string) (interface{}, error) {
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := p[key]; !ok {
+			return nil, fmt.Errorf("path segment %q not found", key)
+		}
+		delete(p, key)
+		return p, nil
+	case []interface{}:
+		idx, err := arrayIndex(key, len(p)-1)
+		if err != nil {
+			return nil, err
+		}
+		return append(p[:idx], p[idx+1:]...), nil
+	default:
+		return nil, fmt.Errorf("path segment %q not found", key)
+	}
+}
+
+func replaceValue(parent interface{}, key string, value interface{}) (interface{}, error) {
+	switch p := parent.(type) {
+	case nil:
+		return value, nil
+	case map[string]interface{}:
+		if _, ok := p[key]; !ok {
+			return nil, fmt.Errorf("path segment %q not found", key)
+		}
+		p[key] = value
+		return p, nil
+	case []interface{}:
+		idx, err := arrayIndex(key, len(p)-1)
+		if err != nil {
+			return nil, err
+		}
+		p[idx] = value
+		return p, nil
+	default:
+		return nil, fmt.Errorf("path segment %q not found", key)
+	}
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return idx, nil
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const (
+	// outboxBatch is the number of entries published at once.
+	outboxBatch = 100
+	// outboxLease keeps other relays off claimed entries; it must exceed the
+	// time publishing a batch takes, or entries are published twice.
+	outboxLease = time.Minute
+	// outboxRetryDelay is the wait before entries that failed to publish are
+	// claimed again.
+	outboxRetryDelay = 10 * time.Second
+	// outboxReleaseTimeout bounds releasing entries after a failed publish,
+	// which runs on a fresh context since the failure may be the timeout.
+	outboxReleaseTimeout = 5 * time.Second
+)
+
+type outboxUsecase struct {
+	outboxRepository domain.OutboxRepository
+	publisher        domain.EventPublisher
+	contextTimeout   time.Duration
+}
+
+func NewOutboxUsecase(outboxRepository domain.OutboxRepository, publisher domain.EventPublisher, timeout time.Duration) domain.OutboxUsecase {
+	return &outboxUsecase{
+		outboxRepository: outboxRepository,
+		publisher:        publisher,
+		contextTimeout:   timeout,
+	}
+}
+
+// Relay marks entries published only after the publisher accepted them, so
+// entries of a relay that stops in between are published again once their
+// lease ran out. Consumers tell such repeats by the event ID.
+func (u *outboxUsecase) Relay(c context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	entries, err := u.outboxRepository.Claim(ctx, outboxBatch, outboxLease)
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+
+	ids := make([]primitive.ObjectID, len(entries))
+	cloudEvents := make([]domain.CloudEvent, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+		cloudEvents[i] = entry.Event
+	}
+
+	if err := u.publisher.Publish(ctx, cloudEvents); err != nil {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), outboxReleaseTimeout)
+		defer cancel()
+
+		if releaseErr := u.outboxRepository.Release(releaseCtx, ids, err.Error(), outboxRetryDelay); releaseErr != nil {
+			return 0, releaseErr
+		}
+		return 0, err
+	}
+
+	if err := u.outboxRepository.MarkPublished(ctx, ids); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,78 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/usecase"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MockEventPublisher struct {
+	Events []domain.CloudEvent
+	Err    error
+	// Hang makes Publish wait until ctx is done.
+	Hang bool
+}
+
+func (m *MockEventPublisher) Publish(ctx context.Context, events []domain.CloudEvent) error {
+	if m.Hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Events = append(m.Events, events...)
+	return nil
+}
+
+func TestOutboxUsecase_Relay(t *testing.T) {
+	entries := []domain.OutboxEntry{
+		{ID: primitive.NewObjectID(), Event: domain.CloudEvent{ID: "1", Type: "user-manager.user.created"}},
+		{ID: primitive.NewObjectID(), Event: domain.CloudEvent{ID: "2", Type: "user-manager.user.deleted"}},
+	}
+	pending := entries
+	outboxMock := &MockOutboxRepository{
+		ClaimFunc: func(ctx context.Context, limit int, lease time.Duration) ([]domain.OutboxEntry, error) {
+			claimed := pending
+			pending = nil
+			return claimed, nil
+		},
+	}
+	publisherMock := &MockEventPublisher{}
+	outboxUsecase := usecase.NewOutboxUsecase(outboxMock, publisherMock, 10*time.Second)
+
+	published, err := outboxUsecase.Relay(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []domain.CloudEvent{entries[0].Event, entries[1].Event}, publisherMock.Events)
+	assert.Equal(t, []primitive.ObjectID{entries[0].ID, entries[1].ID}, outboxMock.MarkPublishedIDs)
+
+	published, err = outboxUsecase.Relay(context.TODO())
+	assert.NoError(t, err)
+	assert.Zero(t, published)
+
+	// Entries the publisher rejected stay pending.
+	pending = entries[:1]
+	publisherMock.Err = errors.New("broker unavailable")
+	published, err = outboxUsecase.Relay(context.TODO())
+	assert.Error(t, err)
+	assert.Zero(t, published)
+	assert.Equal(t, []primitive.ObjectID{entries[0].ID}, outboxMock.ReleasedIDs)
+	assert.Equal(t, "broker unavailable", outboxMock.ReleaseReason)
+	assert.Len(t, outboxMock.MarkPublishedIDs, 2)
+
+	// Entries are released even when publishing used up the timeout.
+	pending = entries[1:]
+	outboxMock.ReleasedIDs = nil
+	outboxUsecase = usecase.NewOutboxUsecase(outboxMock, &MockEventPublisher{Hang: true}, 10*time.Millisecond)
+	_, err = outboxUsecase.Relay(context.TODO())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []primitive.ObjectID{entries[1].ID}, outboxMock.ReleasedIDs)
+	assert.NoError(t, outboxMock.ReleaseErr)
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const ticketBytes = 32
+
+type ticketUsecase struct {
+	ticketRepository domain.TicketRepository
+	ttl              time.Duration
+	contextTimeout   time.Duration
+}
+
+// NewTicketUsecase returns a usecase whose tickets expire after ttl. Tickets
+// end up in URLs and access logs, so ttl should be just long enough for a
+// client to open its connection.
+func NewTicketUsecase(ticketRepository domain.TicketRepository, ttl time.Duration, timeout time.Duration) domain.TicketUsecase {
+	return &ticketUsecase{
+		ticketRepository: ticketRepository,
+		ttl:              ttl,
+		contextTimeout:   timeout,
+	}
+}
+
+func (u *ticketUsecase) Issue(c context.Context) (*domain.TicketResponse, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	key := make([]byte, ticketBytes)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(key)
+	expiresAt := time.Now().Add(u.ttl)
+
+	err := u.ticketRepository.Create(ctx, &domain.Ticket{Hash: ticketHash(ticket), ExpiresAt: expiresAt})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TicketResponse{Ticket: ticket, ExpiresAt: expiresAt}, nil
+}
+
+func (u *ticketUsecase) Redeem(c context.Context, ticket string) (bool, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.ticketRepository.Consume(ctx, ticketHash(ticket))
+}
+
+// ticketHash is the key a ticket is stored under, so that reading the
+// collection does not yield usable tickets.
+func ticketHash(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
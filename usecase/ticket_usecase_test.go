@@ -0,0 +1,56 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockTicketRepository struct {
+	domain.TicketRepository
+	tickets map[string]time.Time
+}
+
+func (m *MockTicketRepository) Create(ctx context.Context, ticket *domain.Ticket) error {
+	m.tickets[ticket.Hash] = ticket.ExpiresAt
+	return nil
+}
+
+func (m *MockTicketRepository) Consume(ctx context.Context, hash string) (bool, error) {
+	expiresAt, ok := m.tickets[hash]
+	delete(m.tickets, hash)
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func TestTicketUsecase(t *testing.T) {
+	repo := &MockTicketRepository{tickets: make(map[string]time.Time)}
+	ticketUsecase := usecase.NewTicketUsecase(repo, time.Minute, time.Second)
+
+	issued, err := ticketUsecase.Issue(context.TODO())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NotEmpty(t, issued.Ticket)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), issued.ExpiresAt, time.Second)
+	assert.NotContains(t, repo.tickets, issued.Ticket)
+
+	valid, err := ticketUsecase.Redeem(context.TODO(), issued.Ticket)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	t.Run("once", func(t *testing.T) {
+		valid, err := ticketUsecase.Redeem(context.TODO(), issued.Ticket)
+		assert.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		valid, err := ticketUsecase.Redeem(context.TODO(), "unknown")
+		assert.NoError(t, err)
+		assert.False(t, valid)
+	})
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+type userEventUsecase struct {
+	eventRepository domain.UserEventRepository
+	contextTimeout  time.Duration
+}
+
+func NewUserEventUsecase(eventRepository domain.UserEventRepository, timeout time.Duration) domain.UserEventUsecase {
+	return &userEventUsecase{
+		eventRepository: eventRepository,
+		contextTimeout:  timeout,
+	}
+}
+
+func (u *userEventUsecase) Since(c context.Context, lastEventID string, limit int) ([]domain.UserEvent, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	seq, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil || seq < 0 {
+		return nil, domain.NewCodedError(domain.ErrValidation, "invalid_event_id", nil, "invalid event id")
+	}
+
+	return u.eventRepository.After(ctx, seq, limit)
+}
+
+func (u *userEventUsecase) LastEventID(c context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	seq, err := u.eventRepository.Last(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(seq, 10), nil
+}
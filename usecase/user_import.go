@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"io"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// importChunkSize is the number of rows validated before the accepted ones
+// are written with a single bulk write.
+const importChunkSize = 100
+
+// Import only remembers the emails of the current chunk. Rows of a later
+// chunk are validated after the earlier chunks were written, so the email
+// check of validate, backed by the unique email index, rejects those
+// duplicates instead.
+func (u *userUsecase) Import(c context.Context, source domain.UserSource, dryRun bool, report func(domain.ImportRowResult) error) (*domain.ImportSummary, error) {
+	summary := &domain.ImportSummary{DryRun: dryRun}
+	claimed := make(map[string]bool, importChunkSize)
+	chunk := make([]*domain.ImportRow, 0, importChunkSize)
+
+	for {
+		row, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return summary, err
+		}
+
+		if row.Err == nil {
+			row.Err = u.validateImportRow(c, row, claimed)
+		}
+		chunk = append(chunk, row)
+
+		if len(chunk) == importChunkSize {
+			if err := u.flushImport(c, chunk, summary, report); err != nil {
+				return summary, err
+			}
+			chunk = chunk[:0]
+			clear(claimed)
+		}
+	}
+
+	if err := u.flushImport(c, chunk, summary, report); err != nil {
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+func (u *userUsecase) validateImportRow(c context.Context, row *domain.ImportRow, claimed map[string]bool) error {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if err := u.validate(ctx, "", row.User); err != nil {
+		return err
+	}
+
+	if claimed[row.User.Email] {
+		return domain.ErrEmailNotUnique
+	}
+	claimed[row.User.Email] = true
+
+	return nil
+}
+
+// flushImport writes the valid rows of a chunk and reports every row of it in
+// file order.
+func (u *userUsecase) flushImport(c context.Context, chunk []*domain.ImportRow, summary *domain.ImportSummary, report func(domain.ImportRowResult) error) error {
+	var ops []domain.BatchOperation
+	var opRows []*domain.ImportRow
+
+	for _, row := range chunk {
+		if row.Err != nil || summary.DryRun {
+			continue
+		}
+		row.User.ID = primitive.NewObjectID()
+		ops = append(ops, domain.BatchOperation{Method: domain.BatchMethodCreate, User: row.User})
+		opRows = append(opRows, row)
+	}
+
+	if len(ops) > 0 {
+		ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+		failed, err := u.applyBatch(ctx, ops, func(i int) domain.UserEvent {
+			return userEvent(domain.UserEventCreated, opRows[i].User)
+		}, false)
+		cancel()
+		if err != nil {
+			return err
+		}
+		for i, writeErr := range failed {
+			opRows[i].Err = writeErr
+		}
+	}
+
+	for _, row := range chunk {
+		result := domain.ImportRowResult{Line: row.Line, Accepted: row.Err == nil, User: row.User}
+		if row.Err != nil {
+			result.Error = row.Err.Error()
+			summary.Rejected++
+		} else {
+			summary.Accepted++
+		}
+
+		if err := report(result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/userio"
+)
+
+// batchJobChunkSize is the number of operations a best-effort batch job
+// applies at once, so that its progress advances while it runs.
+const batchJobChunkSize = 100
+
+type userJobs struct {
+	userUsecase domain.UserUsecase
+	batchResult func(domain.BatchItemResult) domain.BatchResult
+}
+
+// NewUserJobHandlers returns the background handlers for the user job types.
+// batchResult turns the outcome of an operation into its entry in the report
+// of a batch job.
+func NewUserJobHandlers(userUsecase domain.UserUsecase, batchResult func(domain.BatchItemResult) domain.BatchResult) map[string]domain.JobHandler {
+	jobs := &userJobs{
+		userUsecase: userUsecase,
+		batchResult: batchResult,
+	}
+
+	return map[string]domain.JobHandler{
+		domain.JobTypeImport: jobs.importUsers,
+		domain.JobTypeExport: jobs.exportUsers,
+		domain.JobTypeBatch:  jobs.batch,
+	}
+}
+
+func (j *userJobs) importUsers(ctx context.Context, job *domain.Job, input io.Reader, output io.Writer, progress domain.JobProgress) error {
+	if input == nil {
+		return domain.NewValidationError("import job has no input file")
+	}
+
+	mapping := make(map[string]string)
+	for param, value := range job.Params {
+		if field, ok := strings.CutPrefix(param, domain.JobParamMapping); ok {
+			mapping[field] = value
+		}
+	}
+
+	source, err := userio.NewCSVSource(input, mapping)
+	if err != nil {
+		return err
+	}
+
+	report, err := userio.NewReportWriter(output, job.Params[domain.JobParamReport])
+	if err != nil {
+		return err
+	}
+
+	dryRun, _ := strconv.ParseBool(job.Params[domain.JobParamDryRun])
+
+	summary, err := j.userUsecase.Import(ctx, source, dryRun, func(result domain.ImportRowResult) error {
+		progress.Add(1)
+		return report.WriteRow(result)
+	})
+	if closeErr := report.Close(summary, err); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (j *userJobs) exportUsers(ctx context.Context, job *domain.Job, input io.Reader, output io.Writer, progress domain.JobProgress) error {
+	filter, err := jobUserFilter(job.Params)
+	if err != nil {
+		return err
+	}
+
+	writer, err := userio.NewExportWriter(output, job.Params[domain.JobParamFormat])
+	if err != nil {
+		return err
+	}
+
+	total, err := j.userUsecase.Count(ctx, filter)
+	if err != nil {
+		return err
+	}
+	progress.SetTotal(total)
+
+	err = j.userUsecase.Export(ctx, filter, func(user *domain.User) error {
+		progress.Add(1)
+		return writer.Write(user)
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+func (j *userJobs) batch(ctx context.Context, job *domain.Job, input io.Reader, output io.Writer, progress domain.JobProgress) error {
+	if input == nil {
+		return domain.NewValidationError("batch job has no input file")
+	}
+
+	var request domain.BatchRequest
+	if err := json.NewDecoder(input).Decode(&request); err != nil {
+		return domain.NewValidationError("invalid batch file: %v", err)
+	}
+	progress.SetTotal(int64(len(request.Operations)))
+
+	chunkSize := batchJobChunkSize
+	options := domain.BatchOptions{Atomic: request.Mode == domain.BatchModeAtomic}
+	options.RequireVersion, _ = strconv.ParseBool(job.Params[domain.JobParamRequireVersion])
+	if options.Atomic {
+		chunkSize = len(request.Operations)
+	}
+
+	results := make([]domain.BatchResult, 0, len(request.Operations))
+	for offset := 0; offset < len(request.Operations); offset += chunkSize {
+		end := min(offset+chunkSize, len(request.Operations))
+
+		items, err := j.userUsecase.RunBatch(ctx, request.Operations[offset:end], options)
+		if err != nil && items == nil {
+			return err
+		}
+		for _, item := range items {
+			item.Index += offset
+			results = append(results, j.batchResult(item))
+		}
+		progress.Add(int64(end - offset))
+
+		if err != nil {
+			return writeBatchReport(output, results, err)
+		}
+	}
+
+	return writeBatchReport(output, results, nil)
+}
+
+func writeBatchReport(w io.Writer, results []domain.BatchResult, err error) error {
+	report := domain.BatchResponse{Results: results}
+	if err != nil {
+		report.Message = err.Error()
+	}
+	return json.NewEncoder(w).Encode(report)
+}
+
+// jobUserFilter reads the user filter stored in the params of a job. The
+// params were validated when the job was submitted.
+func jobUserFilter(params map[string]string) (domain.UserFilter, error) {
+	filter := domain.UserFilter{Email: params["email"]}
+
+	for param, target := range map[string]*int{"min_age": &filter.MinAge, "max_age": &filter.MaxAge} {
+		if value := params[param]; value != "" {
+			age, err := strconv.Atoi(value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s parameter: %w", param, err)
+			}
+			*target = age
+		}
+	}
+
+	return filter, nil
+}
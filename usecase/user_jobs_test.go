@@ -0,0 +1,87 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+type jobProgress struct {
+	processed, total int64
+}
+
+func (p *jobProgress) Add(n int64) {
+	p.processed += n
+}
+
+func (p *jobProgress) SetTotal(total int64) {
+	p.total = total
+}
+
+func TestUserJobs_Batch(t *testing.T) {
+	repoMock := &MockUserRepository{
+		FetchByEmailFunc: func(ctx context.Context, email string) ([]domain.User, error) {
+			return []domain.User{}, nil
+		},
+		// Writes outlast the usecase timeout, which must not apply to jobs.
+		ApplyBatchFunc: func(ctx context.Context, ops []domain.BatchOperation) error {
+			time.Sleep(5 * time.Millisecond)
+			return ctx.Err()
+		},
+	}
+
+	userUseCase := usecase.NewUserUseCase(repoMock, &MockUserEventRepository{}, &MockOutboxRepository{}, &MockTransactor{}, time.Millisecond)
+	handlers := usecase.NewUserJobHandlers(userUseCase, func(item domain.BatchItemResult) domain.BatchResult {
+		result := domain.BatchResult{Index: item.Index, Status: http.StatusOK}
+		if item.Err != nil {
+			result.Status, result.Error = http.StatusBadRequest, item.Err.Error()
+		}
+		return result
+	})
+
+	for _, mode := range []string{domain.BatchModeAtomic, domain.BatchModeBestEffort} {
+		t.Run(mode, func(t *testing.T) {
+			request := domain.BatchRequest{Mode: mode}
+			for i := 0; i < 150; i++ {
+				request.Operations = append(request.Operations, domain.BatchOperation{
+					Method: domain.BatchMethodCreate,
+					User:   &domain.User{Email: fmt.Sprintf("user%d@example.com", i), Age: 30},
+				})
+			}
+			request.Operations[120].User.Age = 15
+			input, _ := json.Marshal(request)
+
+			var output bytes.Buffer
+			progress := &jobProgress{}
+			err := handlers[domain.JobTypeBatch](context.TODO(), &domain.Job{Type: domain.JobTypeBatch}, bytes.NewReader(input), &output, progress)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.Equal(t, &jobProgress{processed: 150, total: 150}, progress)
+
+			var report domain.BatchResponse
+			assert.NoError(t, json.NewDecoder(strings.NewReader(output.String())).Decode(&report))
+			if !assert.Len(t, report.Results, 150) {
+				t.FailNow()
+			}
+			for i, result := range report.Results {
+				assert.Equal(t, i, result.Index)
+			}
+			assert.Equal(t, http.StatusBadRequest, report.Results[120].Status)
+			if mode == domain.BatchModeAtomic {
+				assert.Equal(t, http.StatusBadRequest, report.Results[0].Status)
+			} else {
+				assert.Equal(t, http.StatusOK, report.Results[0].Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+func (u *userUsecase) Search(c context.Context, filter domain.UserFilter, page, limit int) ([]domain.UserSearchHit, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	offset := (page - 1) * limit
+
+	hits, err := u.userRepository.Search(ctx, filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := searchTerms(filter.Text)
+	for i := range hits {
+		if fragment, ok := highlight(hits[i].Email, terms); ok {
+			hits[i].Highlights = []domain.SearchHighlight{{Field: "email", Fragment: fragment}}
+		}
+	}
+
+	return hits, nil
+}
+
+// searchTerms splits a text search the way the repository splits emails
+// into words. Negated terms are left out since they never match.
+func searchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		tokens := strings.FieldsFunc(strings.ToLower(word), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		terms = append(terms, tokens...)
+	}
+	return terms
+}
+
+// highlight wraps every occurrence of terms in value with <em> tags,
+// merging overlapping matches. It reports false when nothing matched.
+func highlight(value string, terms []string) (string, bool) {
+	lower := strings.ToLower(value)
+	if len(lower) != len(value) {
+		// Offsets into lower must also be offsets into value.
+		lower = value
+	}
+
+	type span struct{ start, end int }
+	var spans []span
+	for _, term := range terms {
+		for from := 0; from < len(lower); {
+			i := strings.Index(lower[from:], term)
+			if i < 0 {
+				break
+			}
+			start := from + i
+			spans = append(spans, span{start, start + len(term)})
+			from = start + len(term)
+		}
+	}
+	if len(spans) == 0 {
+		return value, false
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(spans); i++ {
+		start, end := spans[i].start, spans[i].end
+		for i+1 < len(spans) && spans[i+1].start <= end {
+			i++
+			end = max(end, spans[i].end)
+		}
+		b.WriteString(value[last:start])
+		b.WriteString("<em>")
+		b.WriteString(value[start:end])
+		b.WriteString("</em>")
+		last = end
+	}
+	b.WriteString(value[last:])
+
+	return b.String(), true
+}
+
+func (u *userUsecase) FuzzySearch(c context.Context, filter domain.UserFilter, page, limit int) ([]domain.UserSearchHit, int64, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	hits, err := u.similar(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := int64(len(hits))
+	offset := min((page-1)*limit, len(hits))
+	end := min(offset+limit, len(hits))
+
+	return hits[offset:end], total, nil
+}
+
+func (u *userUsecase) Suggest(c context.Context, filter domain.UserFilter, limit int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	hits, err := u.similar(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]string, 0, min(limit, len(hits)))
+	for _, hit := range hits[:min(limit, len(hits))] {
+		suggestions = append(suggestions, hit.Email)
+	}
+	return suggestions, nil
+}
+
+// similar returns the users within the typo budget of filter.Text, closest
+// first. The budget is one edit per four characters, at least one. Only the
+// matches are kept, so every one of them is counted however many candidates
+// the repository reads.
+func (u *userUsecase) similar(ctx context.Context, filter domain.UserFilter) ([]domain.UserSearchHit, error) {
+	text := strings.ToLower(strings.TrimSpace(filter.Text))
+	budget := max(1, utf8.RuneCountInString(text)/4)
+
+	var hits []domain.UserSearchHit
+	err := u.userRepository.StreamSimilar(ctx, filter, budget, func(user *domain.User) error {
+		distance, length := closestDistance(text, strings.ToLower(user.Email))
+		if distance <= budget {
+			hits = append(hits, domain.UserSearchHit{
+				User:     *user,
+				Score:    1 - float64(distance)/float64(length),
+				Distance: &distance,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if *hits[i].Distance != *hits[j].Distance {
+			return *hits[i].Distance < *hits[j].Distance
+		}
+		return hits[i].Email < hits[j].Email
+	})
+	return hits, nil
+}
+
+// closestDistance compares text with the whole email, its local part and
+// each of its words, so that "jhon" is as close to "john.smith@example.com"
+// as to "john". It returns the smallest distance and the length of the
+// longer of the two strings compared.
+func closestDistance(text, email string) (int, int) {
+	local, _, _ := strings.Cut(email, "@")
+	candidates := append([]string{email, local}, strings.FieldsFunc(email, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})...)
+
+	best, bestLength := -1, 1
+	for _, candidate := range candidates {
+		distance := editDistance(text, candidate)
+		if best < 0 || distance < best {
+			best = distance
+			bestLength = max(1, utf8.RuneCountInString(text), utf8.RuneCountInString(candidate))
+		}
+	}
+	return best, bestLength
+}
+
+// editDistance is the optimal string alignment distance: the number of
+// insertions, deletions, substitutions and transpositions of adjacent
+// characters needed to turn a into b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(s)][len(t)]
+}
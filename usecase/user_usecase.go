@@ -1,22 +1,36 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"net/mail"
 	"time"
 
+	log "github.com/sirupsen/logrus"
+
 	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/events"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
 )
 
 type userUsecase struct {
-	userRepository domain.UserRepository
-	contextTimeout time.Duration
+	userRepository   domain.UserRepository
+	eventRepository  domain.UserEventRepository
+	outboxRepository domain.OutboxRepository
+	transactor       domain.Transactor
+	contextTimeout   time.Duration
 }
 
-func NewUserUseCase(userRepository domain.UserRepository, timeout time.Duration) domain.UserUsecase {
+func NewUserUseCase(userRepository domain.UserRepository, eventRepository domain.UserEventRepository, outboxRepository domain.OutboxRepository, transactor domain.Transactor, timeout time.Duration) domain.UserUsecase {
 	return &userUsecase{
-		userRepository: userRepository,
-		contextTimeout: timeout,
+		userRepository:   userRepository,
+		eventRepository:  eventRepository,
+		outboxRepository: outboxRepository,
+		transactor:       transactor,
+		contextTimeout:   timeout,
 	}
 }
 
@@ -24,29 +38,35 @@ func (u *userUsecase) Create(c context.Context, user *domain.User) error {
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
 
-	if user.Age <= 18 {
-		return fmt.Errorf("age must be greater than 18")
-	}
-
-	existingUsers, err := u.userRepository.FetchByEmail(ctx, user.Email)
-	if err != nil {
+	if err := u.validate(ctx, "", user); err != nil {
 		return err
 	}
 
-	if len(existingUsers) > 0 {
-		return fmt.Errorf("email must be unique")
-	}
-
-	return u.userRepository.Create(ctx, user)
+	return u.transactor.WithTransaction(ctx, func(tc context.Context) error {
+		if err := u.userRepository.Create(tc, user); err != nil {
+			return err
+		}
+		return u.record(tc, userEvent(domain.UserEventCreated, user))
+	})
 }
 
-func (u *userUsecase) Fetch(c context.Context, page, limit int) ([]domain.User, error) {
+func (u *userUsecase) Fetch(c context.Context, filter domain.UserFilter, page, limit int) ([]domain.User, error) {
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
 
 	offset := (page - 1) * limit
 
-	return u.userRepository.Fetch(ctx, offset, limit)
+	return u.userRepository.Fetch(ctx, filter, offset, limit)
+}
+
+func (u *userUsecase) FetchRange(c context.Context, filter domain.UserFilter, offset, limit int) ([]domain.User, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+	return u.userRepository.Fetch(ctx, filter, offset, limit)
+}
+
+func (u *userUsecase) Export(c context.Context, filter domain.UserFilter, fn func(*domain.User) error) error {
+	return u.userRepository.Stream(c, filter, fn)
 }
 
 func (u *userUsecase) GetByID(c context.Context, id string) (*domain.User, error) {
@@ -55,36 +75,491 @@ func (u *userUsecase) GetByID(c context.Context, id string) (*domain.User, error
 	return u.userRepository.GetByID(ctx, id)
 }
 
+func (u *userUsecase) Lookup(c context.Context, ids, emails []string) (*domain.UserLookup, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	ids = unique(ids)
+	emails = unique(emails)
+
+	users, err := u.userRepository.FetchByIDsOrEmails(ctx, ids, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]domain.User, len(users))
+	byEmail := make(map[string]domain.User, len(users))
+	for _, user := range users {
+		byID[user.ID.Hex()] = user
+		byEmail[user.Email] = user
+	}
+
+	lookup := &domain.UserLookup{Users: []domain.User{}, MissingIDs: []string{}, MissingEmails: []string{}}
+	added := make(map[string]bool, len(users))
+	add := func(user domain.User) {
+		if !added[user.ID.Hex()] {
+			added[user.ID.Hex()] = true
+			lookup.Users = append(lookup.Users, user)
+		}
+	}
+
+	for _, id := range ids {
+		if user, ok := byID[id]; ok {
+			add(user)
+		} else {
+			lookup.MissingIDs = append(lookup.MissingIDs, id)
+		}
+	}
+	for _, email := range emails {
+		if user, ok := byEmail[email]; ok {
+			add(user)
+		} else {
+			lookup.MissingEmails = append(lookup.MissingEmails, email)
+		}
+	}
+
+	return lookup, nil
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
 func (u *userUsecase) Update(c context.Context, id string, user *domain.User) error {
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
 
-	if user.Age <= 18 {
-		return fmt.Errorf("age must be greater than 18")
+	if err := u.validate(ctx, id, user); err != nil {
+		return err
 	}
 
-	existingUsers, err := u.userRepository.FetchByEmail(ctx, user.Email)
+	return u.transactor.WithTransaction(ctx, func(tc context.Context) error {
+		previous, err := u.userRepository.GetByID(tc, id)
+		if err != nil {
+			return err
+		}
+		if err := u.userRepository.Update(tc, id, user); err != nil {
+			return err
+		}
+
+		// The update may not have named a version, so the event reads back
+		// the stored user.
+		updated, err := u.userRepository.GetByID(tc, id)
+		if err != nil {
+			return err
+		}
+		return u.record(tc, changeEvent(domain.UserEventUpdated, updated, previous))
+	})
+}
+
+func (u *userUsecase) Validate(c context.Context, id string, user *domain.User) error {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.validate(ctx, id, user)
+}
+
+func (u *userUsecase) Patch(c context.Context, id string, version int64, ops []domain.PatchOperation) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	current, err := u.userRepository.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, existingUser := range existingUsers {
-		if existingUser.ID.Hex() != id {
-			return fmt.Errorf("email must be unique")
+	if version != domain.AnyVersion && version != current.Version {
+		return nil, domain.ErrVersionMismatch
+	}
+
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	doc, err = applyPatch(doc, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err = json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched domain.User
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return nil, domain.NewValidationError("invalid patch: %v", err)
+	}
+
+	if patched.ID != current.ID {
+		return nil, domain.NewValidationError("invalid patch: id cannot be modified")
+	}
+
+	if patched.Version != current.Version {
+		return nil, domain.NewValidationError("invalid patch: version cannot be modified")
+	}
+
+	if err := u.validate(ctx, id, &patched); err != nil {
+		return nil, err
+	}
+
+	updated := patched
+	updated.Version++
+	err = u.transactor.WithTransaction(ctx, func(tc context.Context) error {
+		if err := u.userRepository.Update(tc, id, &patched); err != nil {
+			return err
 		}
+		return u.record(tc, changeEvent(domain.UserEventUpdated, &updated, current))
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &updated, nil
+}
+
+func (u *userUsecase) Delete(c context.Context, id string, version int64) error {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
 
-	return u.userRepository.Update(ctx, id, user)
+	return u.transactor.WithTransaction(ctx, func(tc context.Context) error {
+		previous, err := u.userRepository.GetByID(tc, id)
+		if err != nil {
+			return err
+		}
+		if err := u.userRepository.Delete(tc, id, version); err != nil {
+			return err
+		}
+		return u.record(tc, changeEvent(domain.UserEventDeleted, nil, previous))
+	})
 }
 
-func (u *userUsecase) Delete(c context.Context, id string) error {
+func (u *userUsecase) Batch(c context.Context, ops []domain.BatchOperation, options domain.BatchOptions) ([]domain.BatchItemResult, error) {
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
-	return u.userRepository.Delete(ctx, id)
+	return u.RunBatch(ctx, ops, options)
 }
 
-func (u *userUsecase) Count(c context.Context) (int64, error) {
+func (u *userUsecase) RunBatch(ctx context.Context, ops []domain.BatchOperation, options domain.BatchOptions) ([]domain.BatchItemResult, error) {
+	var ids []string
+	for _, op := range ops {
+		if op.Method != domain.BatchMethodCreate && primitive.IsValidObjectID(op.ID) {
+			ids = append(ids, op.ID)
+		}
+	}
+
+	current := make(map[string]domain.User, len(ids))
+	if len(ids) > 0 {
+		users, err := u.userRepository.FetchByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			current[user.ID.Hex()] = user
+		}
+	}
+
+	results := make([]domain.BatchItemResult, len(ops))
+	claimed := make(map[string]bool)
+	touched := make(map[string]bool)
+	var valid []int
+
+	for i := range ops {
+		results[i].Index = i
+		user, err := u.validateBatchOperation(ctx, &ops[i], options.RequireVersion, current, claimed, touched)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].User = user
+		valid = append(valid, i)
+	}
+
+	if options.Atomic && len(valid) < len(ops) {
+		for _, i := range valid {
+			results[i].User = nil
+			results[i].Err = domain.ErrBatchAborted
+		}
+		failed := len(ops) - len(valid)
+		return results, domain.NewCodedError(domain.ErrValidation, "batch_rejected", map[string]interface{}{"failed": failed, "total": len(ops)},
+			"batch rejected: %d of %d operations failed", failed, len(ops))
+	}
+
+	writes := make([]domain.BatchOperation, len(valid))
+	for n, i := range valid {
+		writes[n] = ops[i]
+	}
+
+	failed, err := u.applyBatch(ctx, writes, func(n int) domain.UserEvent {
+		return batchEvent(ops[valid[n]], results[valid[n]].User, current)
+	}, options.Atomic)
+	if err != nil {
+		return nil, err
+	}
+
+	for n, i := range valid {
+		if writeErr, ok := failed[n]; ok {
+			results[i].User = nil
+			results[i].Err = writeErr
+		}
+	}
+
+	return results, nil
+}
+
+// applyBatch writes ops together with their events, which event returns by
+// index once the operations were applied, in one transaction. Unless
+// atomic, an operation failing makes it retry every operation in a
+// transaction of its own, so that the others are still stored; failed maps
+// the indexes of the failing ones to their errors.
+func (u *userUsecase) applyBatch(ctx context.Context, ops []domain.BatchOperation, event func(i int) domain.UserEvent, atomic bool) (map[int]error, error) {
+	if len(ops) == 0 {
+		return nil, nil
+	}
+
+	write := func(from, to int) error {
+		return u.transactor.WithTransaction(ctx, func(tc context.Context) error {
+			if err := u.userRepository.ApplyBatch(tc, ops[from:to]); err != nil {
+				return err
+			}
+			changes := make([]domain.UserEvent, 0, to-from)
+			for i := from; i < to; i++ {
+				changes = append(changes, event(i))
+			}
+
+			// Batches of jobs are not bounded by the usecase timeout, but
+			// recording their events is, so the events settle in time
+			// for the streams however long the writes took.
+			rc, cancel := context.WithTimeout(tc, u.contextTimeout)
+			defer cancel()
+			return u.record(rc, changes...)
+		})
+	}
+
+	err := write(0, len(ops))
+	if opErr := operationError(err); opErr == nil {
+		return nil, err
+	} else if atomic {
+		return nil, opErr
+	}
+
+	failed := make(map[int]error)
+	for i := range ops {
+		err := write(i, i+1)
+		if opErr := operationError(err); opErr != nil {
+			failed[i] = opErr
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return failed, nil
+}
+
+// operationError returns the error of the single operation that made a
+// batch fail, or nil when err is not caused by one, e.g. a lost connection.
+// Write errors of the database are reported as domain errors; those no
+// client can act on are logged and reported as ErrOperationFailed.
+func operationError(err error) error {
+	if errors.Is(err, domain.ErrVersionMismatch) {
+		return domain.ErrVersionMismatch
+	}
+
+	var bulkErr mongodriver.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return nil
+	}
+
+	// Emails are checked before writing, so a duplicate was stored by a
+	// concurrent request in the meantime.
+	if mongodriver.IsDuplicateKeyError(bulkErr.WriteErrors[0]) {
+		return domain.ErrEmailNotUnique
+	}
+	log.Errorf("Batch operation failed: %v", bulkErr.WriteErrors[0])
+	return domain.ErrOperationFailed
+}
+
+// batchEvent returns the event of a batch operation. current holds the
+// users the batch updates or deletes as they were before.
+func batchEvent(op domain.BatchOperation, user *domain.User, current map[string]domain.User) domain.UserEvent {
+	previous := current[op.ID]
+	switch op.Method {
+	case domain.BatchMethodCreate:
+		return userEvent(domain.UserEventCreated, user)
+	case domain.BatchMethodUpdate:
+		return changeEvent(domain.UserEventUpdated, user, &previous)
+	default:
+		return changeEvent(domain.UserEventDeleted, nil, &previous)
+	}
+}
+
+func userEvent(eventType string, user *domain.User) domain.UserEvent {
+	snapshot := *user
+	return domain.UserEvent{Type: eventType, UserID: user.ID, User: &snapshot}
+}
+
+// changeEvent is the event of a change to the user previous, which user is
+// the result of, or nil for a deletion.
+func changeEvent(eventType string, user, previous *domain.User) domain.UserEvent {
+	before := *previous
+	event := domain.UserEvent{Type: eventType, UserID: previous.ID, Previous: &before}
+	if user != nil {
+		after := *user
+		event.User = &after
+	}
+	return event
+}
+
+// record appends events to the event log and to the outbox, from which the
+// outbox relay publishes them. It runs in the transaction of the writes, so
+// a failure undoes them. It is the last step of the transaction, since
+// appending holds back every other append until the transaction ends.
+func (u *userUsecase) record(ctx context.Context, changes ...domain.UserEvent) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i := range changes {
+		changes[i].ID = primitive.NewObjectID()
+		changes[i].Time = now
+	}
+
+	if err := u.eventRepository.Append(ctx, changes); err != nil {
+		return err
+	}
+
+	entries := make([]domain.OutboxEntry, len(changes))
+	for i, event := range changes {
+		cloudEvent, err := events.FromUserEvent(event)
+		if err != nil {
+			return err
+		}
+		entries[i] = domain.OutboxEntry{ID: primitive.NewObjectID(), Event: cloudEvent, CreatedAt: now}
+	}
+	return u.outboxRepository.Add(ctx, entries)
+}
+
+// validateBatchOperation applies the single-user rules to a batch operation
+// and also rejects emails claimed twice and users touched twice in the same
+// batch, which the per-request checks cannot see. With requireVersion,
+// updates and deletes must carry a version.
+func (u *userUsecase) validateBatchOperation(ctx context.Context, op *domain.BatchOperation, requireVersion bool, current map[string]domain.User, claimed, touched map[string]bool) (*domain.User, error) {
+	switch op.Method {
+	case domain.BatchMethodCreate, domain.BatchMethodUpdate, domain.BatchMethodDelete:
+	default:
+		return nil, domain.NewValidationError("invalid batch operation: unknown method %q", op.Method)
+	}
+
+	id := ""
+	if op.Method != domain.BatchMethodCreate {
+		if !primitive.IsValidObjectID(op.ID) {
+			return nil, domain.NewValidationError("invalid batch operation: invalid id")
+		}
+		if touched[op.ID] {
+			return nil, domain.NewValidationError("invalid batch operation: user %s appears more than once", op.ID)
+		}
+		touched[op.ID] = true
+
+		if requireVersion && op.Version == domain.AnyVersion {
+			return nil, domain.ErrVersionRequired
+		}
+
+		existing, ok := current[op.ID]
+		if !ok {
+			return nil, domain.ErrUserNotFound
+		}
+		if op.Version != domain.AnyVersion && op.Version != existing.Version {
+			return nil, domain.ErrVersionMismatch
+		}
+		if op.Method == domain.BatchMethodDelete {
+			return &existing, nil
+		}
+		id = op.ID
+	}
+
+	if op.User == nil {
+		return nil, domain.NewValidationError("invalid batch operation: user is required")
+	}
+
+	if err := u.validate(ctx, id, op.User); err != nil {
+		return nil, err
+	}
+
+	if claimed[op.User.Email] {
+		return nil, domain.ErrEmailNotUnique
+	}
+	claimed[op.User.Email] = true
+
+	if op.Method == domain.BatchMethodCreate {
+		op.User.ID = primitive.NewObjectID()
+		return op.User, nil
+	}
+
+	updated := *op.User
+	updated.ID = current[op.ID].ID
+	updated.Version = current[op.ID].Version + 1
+	return &updated, nil
+}
+
+func (u *userUsecase) Count(c context.Context, filter domain.UserFilter) (int64, error) {
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
-	return u.userRepository.Count(ctx)
+	return u.userRepository.Count(ctx, filter)
+}
+
+// validate enforces the business rules shared by every write. id is the user
+// being modified, or empty when a new user is created.
+func (u *userUsecase) validate(ctx context.Context, id string, user *domain.User) error {
+	var fields []domain.FieldError
+
+	if user.Age <= 18 {
+		fields = append(fields, domain.FieldError{
+			Field:   "age",
+			Code:    "gt",
+			Message: "age must be greater than 18",
+			Params:  map[string]interface{}{"gt": 18},
+		})
+	}
+
+	if _, err := mail.ParseAddress(user.Email); err != nil {
+		fields = append(fields, domain.FieldError{
+			Field:   "email",
+			Code:    "email",
+			Message: "email must be a valid address",
+		})
+	}
+
+	// Uniqueness is only checked for otherwise valid users, which saves a
+	// query for requests that are rejected anyway.
+	if len(fields) > 0 {
+		return domain.NewFieldError(domain.ErrValidation, fields...)
+	}
+
+	existingUsers, err := u.userRepository.FetchByEmail(ctx, user.Email)
+	if err != nil {
+		return err
+	}
+
+	for _, existingUser := range existingUsers {
+		if id == "" || existingUser.ID.Hex() != id {
+			return domain.ErrEmailNotUnique
+		}
+	}
+
+	return nil
 }
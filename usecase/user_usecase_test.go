@@ -3,6 +3,8 @@ package usecase_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -10,16 +12,43 @@ import (
 	"github.com/nebojsaj1726/user-manager/usecase"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
 )
 
 type MockUserRepository struct {
-	CreateFunc       func(ctx context.Context, user *domain.User) error
-	FindByIDFunc     func(ctx context.Context, id primitive.ObjectID) (*domain.User, error)
-	DeleteFunc       func(ctx context.Context, id string) error
-	UpdateFunc       func(ctx context.Context, id string, user *domain.User) error
-	CountFunc        func(ctx context.Context) (int64, error)
-	FetchFunc        func(ctx context.Context, offset, limit int) ([]domain.User, error)
-	FetchByEmailFunc func(ctx context.Context, email string) ([]domain.User, error)
+	CreateFunc             func(ctx context.Context, user *domain.User) error
+	FindByIDFunc           func(ctx context.Context, id primitive.ObjectID) (*domain.User, error)
+	DeleteFunc             func(ctx context.Context, id string, version int64) error
+	UpdateFunc             func(ctx context.Context, id string, user *domain.User) error
+	CountFunc              func(ctx context.Context) (int64, error)
+	FetchFunc              func(ctx context.Context, offset, limit int) ([]domain.User, error)
+	FetchByEmailFunc       func(ctx context.Context, email string) ([]domain.User, error)
+	FetchByIDsFunc         func(ctx context.Context, ids []string) ([]domain.User, error)
+	FetchByIDsOrEmailsFunc func(ctx context.Context, ids, emails []string) ([]domain.User, error)
+	SearchFunc             func(ctx context.Context, filter domain.UserFilter, offset, limit int) ([]domain.UserSearchHit, error)
+	StreamSimilarFunc      func(ctx context.Context, filter domain.UserFilter, maxEdits int, fn func(*domain.User) error) error
+	ApplyBatchFunc         func(ctx context.Context, ops []domain.BatchOperation) error
+	StreamFunc             func(ctx context.Context, filter domain.UserFilter, fn func(*domain.User) error) error
+}
+
+func (m *MockUserRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockUserRepository) BackfillVersions(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockUserRepository) Search(ctx context.Context, filter domain.UserFilter, offset, limit int) ([]domain.UserSearchHit, error) {
+	return m.SearchFunc(ctx, filter, offset, limit)
+}
+
+func (m *MockUserRepository) StreamSimilar(ctx context.Context, filter domain.UserFilter, maxEdits int, fn func(*domain.User) error) error {
+	return m.StreamSimilarFunc(ctx, filter, maxEdits, fn)
+}
+
+func (m *MockUserRepository) Reindex(ctx context.Context) (int64, error) {
+	return 0, nil
 }
 
 func (m *MockUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
@@ -30,7 +59,7 @@ func (m *MockUserRepository) GetByID(ctx context.Context, id string) (*domain.Us
 	return m.FindByIDFunc(ctx, objectID)
 }
 
-func (m *MockUserRepository) Fetch(ctx context.Context, offset int, limit int) ([]domain.User, error) {
+func (m *MockUserRepository) Fetch(ctx context.Context, filter domain.UserFilter, offset int, limit int) ([]domain.User, error) {
 	if m.FetchFunc != nil {
 		return m.FetchFunc(ctx, offset, limit)
 	}
@@ -45,18 +74,34 @@ func (m *MockUserRepository) FindByID(ctx context.Context, id primitive.ObjectID
 	return m.FindByIDFunc(ctx, id)
 }
 
-func (m *MockUserRepository) Delete(ctx context.Context, id string) error {
-	return m.DeleteFunc(ctx, id)
+func (m *MockUserRepository) Delete(ctx context.Context, id string, version int64) error {
+	return m.DeleteFunc(ctx, id, version)
 }
 
 func (m *MockUserRepository) Update(ctx context.Context, id string, user *domain.User) error {
 	return m.UpdateFunc(ctx, id, user)
 }
 
-func (m *MockUserRepository) Count(ctx context.Context) (int64, error) {
+func (m *MockUserRepository) Stream(ctx context.Context, filter domain.UserFilter, fn func(*domain.User) error) error {
+	return m.StreamFunc(ctx, filter, fn)
+}
+
+func (m *MockUserRepository) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
 	return m.CountFunc(ctx)
 }
 
+func (m *MockUserRepository) FetchByIDs(ctx context.Context, ids []string) ([]domain.User, error) {
+	return m.FetchByIDsFunc(ctx, ids)
+}
+
+func (m *MockUserRepository) FetchByIDsOrEmails(ctx context.Context, ids, emails []string) ([]domain.User, error) {
+	return m.FetchByIDsOrEmailsFunc(ctx, ids, emails)
+}
+
+func (m *MockUserRepository) ApplyBatch(ctx context.Context, ops []domain.BatchOperation) error {
+	return m.ApplyBatchFunc(ctx, ops)
+}
+
 func (m *MockUserRepository) FetchByEmail(ctx context.Context, email string) ([]domain.User, error) {
 	if m.FetchByEmailFunc != nil {
 		return m.FetchByEmailFunc(ctx, email)
@@ -64,6 +109,83 @@ func (m *MockUserRepository) FetchByEmail(ctx context.Context, email string) ([]
 	return nil, errors.New("FetchByEmailFunc not implemented")
 }
 
+type MockUserEventRepository struct {
+	Events []domain.UserEvent
+}
+
+func (m *MockUserEventRepository) EnsureIndexes(ctx context.Context, retention time.Duration) error {
+	return nil
+}
+
+func (m *MockUserEventRepository) Append(ctx context.Context, events []domain.UserEvent) error {
+	for i := range events {
+		events[i].Seq = int64(len(m.Events) + 1)
+		m.Events = append(m.Events, events[i])
+	}
+	return nil
+}
+
+func (m *MockUserEventRepository) After(ctx context.Context, seq int64, limit int) ([]domain.UserEvent, error) {
+	var events []domain.UserEvent
+	for _, event := range m.Events {
+		if event.Seq > seq && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockUserEventRepository) Last(ctx context.Context) (int64, error) {
+	if len(m.Events) == 0 {
+		return 0, nil
+	}
+	return m.Events[len(m.Events)-1].Seq, nil
+}
+
+type MockOutboxRepository struct {
+	Entries          []domain.OutboxEntry
+	ClaimFunc        func(ctx context.Context, limit int, lease time.Duration) ([]domain.OutboxEntry, error)
+	MarkPublishedIDs []primitive.ObjectID
+	ReleasedIDs      []primitive.ObjectID
+	ReleaseReason    string
+	ReleaseErr       error
+}
+
+func (m *MockOutboxRepository) EnsureIndexes(ctx context.Context, retention time.Duration) error {
+	return nil
+}
+
+func (m *MockOutboxRepository) Add(ctx context.Context, entries []domain.OutboxEntry) error {
+	m.Entries = append(m.Entries, entries...)
+	return nil
+}
+
+func (m *MockOutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.OutboxEntry, error) {
+	return m.ClaimFunc(ctx, limit, lease)
+}
+
+func (m *MockOutboxRepository) MarkPublished(ctx context.Context, ids []primitive.ObjectID) error {
+	m.MarkPublishedIDs = append(m.MarkPublishedIDs, ids...)
+	return nil
+}
+
+func (m *MockOutboxRepository) Release(ctx context.Context, ids []primitive.ObjectID, reason string, retryAfter time.Duration) error {
+	m.ReleasedIDs = append(m.ReleasedIDs, ids...)
+	m.ReleaseReason = reason
+	m.ReleaseErr = ctx.Err()
+	return ctx.Err()
+}
+
+// MockTransactor runs fn without a transaction and counts how often it did.
+type MockTransactor struct {
+	Calls int
+}
+
+func (m *MockTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.Calls++
+	return fn(ctx)
+}
+
 func TestUserUseCase_Create(t *testing.T) {
 	repoMock := &MockUserRepository{
 		FetchByEmailFunc: func(ctx context.Context, email string) ([]domain.User, error) {
@@ -79,7 +201,9 @@ func TestUserUseCase_Create(t *testing.T) {
 		},
 	}
 
-	userUseCase := usecase.NewUserUseCase(repoMock, 10*time.Second)
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
 
 	testUser := &domain.User{
 		ID:    primitive.NewObjectID(),
@@ -89,6 +213,18 @@ func TestUserUseCase_Create(t *testing.T) {
 
 	err := userUseCase.Create(context.TODO(), testUser)
 	assert.NoError(t, err)
+	assert.Len(t, eventMock.Events, 1)
+	assert.Equal(t, domain.UserEventCreated, eventMock.Events[0].Type)
+	assert.Equal(t, testUser.ID, eventMock.Events[0].UserID)
+	assert.False(t, eventMock.Events[0].ID.IsZero())
+	if assert.Len(t, outboxMock.Entries, 1) {
+		published := outboxMock.Entries[0].Event
+		assert.Equal(t, "1.0", published.SpecVersion)
+		assert.Equal(t, "user-manager.user.created", published.Type)
+		assert.Equal(t, eventMock.Events[0].ID.Hex(), published.ID)
+		assert.Equal(t, testUser.ID.Hex(), published.Subject)
+		assert.Contains(t, string(published.Data), `"email":"test@example.com"`)
+	}
 
 	testUser = &domain.User{
 		ID:    primitive.NewObjectID(),
@@ -97,9 +233,21 @@ func TestUserUseCase_Create(t *testing.T) {
 	}
 
 	err = userUseCase.Create(context.TODO(), testUser)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, domain.ErrValidation)
 	assert.Equal(t, "age must be greater than 18", err.Error())
 
+	testUser = &domain.User{
+		ID:    primitive.NewObjectID(),
+		Email: "not an address",
+		Age:   17,
+	}
+
+	err = userUseCase.Create(context.TODO(), testUser)
+	var validationErr *domain.Error
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{"age", "email"}, []string{validationErr.Fields[0].Field, validationErr.Fields[1].Field})
+	assert.Equal(t, "gt", validationErr.Fields[0].Code)
+
 	testUser = &domain.User{
 		ID:    primitive.NewObjectID(),
 		Email: "existing@example.com",
@@ -107,8 +255,10 @@ func TestUserUseCase_Create(t *testing.T) {
 	}
 
 	err = userUseCase.Create(context.TODO(), testUser)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, domain.ErrConflict)
 	assert.Equal(t, "email must be unique", err.Error())
+	assert.Len(t, eventMock.Events, 1)
+	assert.Len(t, outboxMock.Entries, 1)
 }
 
 func TestUserUseCase_Fetch(t *testing.T) {
@@ -121,9 +271,11 @@ func TestUserUseCase_Fetch(t *testing.T) {
 		},
 	}
 
-	userUseCase := usecase.NewUserUseCase(repoMock, 10*time.Second)
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
 
-	users, err := userUseCase.Fetch(context.TODO(), 1, 2)
+	users, err := userUseCase.Fetch(context.TODO(), domain.UserFilter{}, 1, 2)
 	assert.NoError(t, err)
 	assert.Len(t, users, 2)
 
@@ -131,12 +283,63 @@ func TestUserUseCase_Fetch(t *testing.T) {
 		return nil, errors.New("fetch failed")
 	}
 
-	users, err = userUseCase.Fetch(context.TODO(), 1, 2)
+	users, err = userUseCase.Fetch(context.TODO(), domain.UserFilter{}, 1, 2)
 	assert.Error(t, err)
 	assert.Nil(t, users)
 	assert.Equal(t, "fetch failed", err.Error())
 }
 
+func TestUserUseCase_Export(t *testing.T) {
+	stored := []domain.User{
+		{ID: primitive.NewObjectID(), Email: "user1@example.com", Age: 25},
+		{ID: primitive.NewObjectID(), Email: "user2@example.com", Age: 30},
+	}
+	errWrite := errors.New("write failed")
+
+	tests := []struct {
+		name      string
+		streamErr error
+		writeErr  error
+		want      []string
+		wantErr   error
+	}{
+		{name: "all users", want: []string{"user1@example.com", "user2@example.com"}},
+		{name: "write fails", writeErr: errWrite, want: []string{"user1@example.com"}, wantErr: errWrite},
+		{name: "stream fails", streamErr: errors.New("stream failed"), wantErr: errors.New("stream failed")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := domain.UserFilter{Email: "example.com", MinAge: 18, MaxAge: 40}
+
+			repoMock := &MockUserRepository{
+				StreamFunc: func(ctx context.Context, got domain.UserFilter, fn func(*domain.User) error) error {
+					assert.Equal(t, filter, got)
+					if tt.streamErr != nil {
+						return tt.streamErr
+					}
+					for i := range stored {
+						if err := fn(&stored[i]); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			}
+
+			userUseCase := usecase.NewUserUseCase(repoMock, &MockUserEventRepository{}, &MockOutboxRepository{}, &MockTransactor{}, 10*time.Second)
+
+			var written []string
+			err := userUseCase.Export(context.TODO(), filter, func(user *domain.User) error {
+				written = append(written, user.Email)
+				return tt.writeErr
+			})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, written)
+		})
+	}
+}
+
 func TestUserUseCase_GetByID(t *testing.T) {
 	testID := primitive.NewObjectID()
 	repoMock := &MockUserRepository{
@@ -144,11 +347,13 @@ func TestUserUseCase_GetByID(t *testing.T) {
 			if id == testID {
 				return &domain.User{ID: id, Email: "test@example.com", Age: 21}, nil
 			}
-			return nil, errors.New("user not found")
+			return nil, domain.ErrUserNotFound
 		},
 	}
 
-	userUseCase := usecase.NewUserUseCase(repoMock, 10*time.Second)
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
 
 	user, err := userUseCase.GetByID(context.TODO(), testID.Hex())
 	assert.NoError(t, err)
@@ -159,7 +364,7 @@ func TestUserUseCase_GetByID(t *testing.T) {
 	user, err = userUseCase.GetByID(context.TODO(), invalidID.Hex())
 	assert.Error(t, err)
 	assert.Nil(t, user)
-	assert.Equal(t, "user not found", err.Error())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
 }
 
 func TestUserUseCase_Update(t *testing.T) {
@@ -176,9 +381,14 @@ func TestUserUseCase_Update(t *testing.T) {
 		UpdateFunc: func(ctx context.Context, id string, user *domain.User) error {
 			return nil
 		},
+		FindByIDFunc: func(ctx context.Context, id primitive.ObjectID) (*domain.User, error) {
+			return &domain.User{ID: id, Email: "updated@example.com", Age: 22, Version: 4}, nil
+		},
 	}
 
-	userUseCase := usecase.NewUserUseCase(repoMock, 10*time.Second)
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
 
 	testUser := &domain.User{
 		ID:    testID,
@@ -188,6 +398,9 @@ func TestUserUseCase_Update(t *testing.T) {
 
 	err := userUseCase.Update(context.TODO(), testID.Hex(), testUser)
 	assert.NoError(t, err)
+	assert.Len(t, eventMock.Events, 1)
+	assert.Equal(t, domain.UserEventUpdated, eventMock.Events[0].Type)
+	assert.Equal(t, int64(4), eventMock.Events[0].User.Version)
 
 	testUser = &domain.User{
 		ID:    testID,
@@ -210,29 +423,493 @@ func TestUserUseCase_Update(t *testing.T) {
 	assert.Equal(t, "email must be unique", err.Error())
 }
 
+func TestUserUseCase_Patch(t *testing.T) {
+	testID := primitive.NewObjectID()
+	var stored *domain.User
+	repoMock := &MockUserRepository{
+		FindByIDFunc: func(ctx context.Context, id primitive.ObjectID) (*domain.User, error) {
+			return &domain.User{ID: testID, Email: "test@example.com", Age: 21, Version: 3}, nil
+		},
+		FetchByEmailFunc: func(ctx context.Context, email string) ([]domain.User, error) {
+			if email == "existing@example.com" {
+				return []domain.User{
+					{ID: primitive.NewObjectID(), Email: "existing@example.com", Age: 25},
+				}, nil
+			}
+			return []domain.User{}, nil
+		},
+		UpdateFunc: func(ctx context.Context, id string, user *domain.User) error {
+			updated := *user
+			stored = &updated
+			return nil
+		},
+	}
+
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
+
+	user, err := userUseCase.Patch(context.TODO(), testID.Hex(), 3, []domain.PatchOperation{
+		{Op: "test", Path: "/email", Value: []byte(`"test@example.com"`)},
+		{Op: "replace", Path: "/email", Value: []byte(`"patched@example.com"`)},
+		{Op: "replace", Path: "/age", Value: []byte(`30`)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "patched@example.com", user.Email)
+	assert.Equal(t, 30, user.Age)
+	assert.Equal(t, int64(4), user.Version)
+	assert.Equal(t, int64(3), stored.Version)
+
+	stored = nil
+	_, err = userUseCase.Patch(context.TODO(), testID.Hex(), 2, []domain.PatchOperation{
+		{Op: "replace", Path: "/age", Value: []byte(`40`)},
+	})
+	assert.ErrorIs(t, err, domain.ErrVersionMismatch)
+	assert.Nil(t, stored)
+
+	_, err = userUseCase.Patch(context.TODO(), testID.Hex(), domain.AnyVersion, []domain.PatchOperation{
+		{Op: "replace", Path: "/age", Value: []byte(`40`)},
+		{Op: "test", Path: "/email", Value: []byte(`"other@example.com"`)},
+	})
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	assert.Contains(t, err.Error(), "patch test failed")
+	assert.Nil(t, stored)
+
+	_, err = userUseCase.Patch(context.TODO(), testID.Hex(), domain.AnyVersion, []domain.PatchOperation{
+		{Op: "replace", Path: "/age", Value: []byte(`17`)},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "age must be greater than 18", err.Error())
+
+	_, err = userUseCase.Patch(context.TODO(), testID.Hex(), domain.AnyVersion, []domain.PatchOperation{
+		{Op: "replace", Path: "/email", Value: []byte(`"existing@example.com"`)},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "email must be unique", err.Error())
+
+	_, err = userUseCase.Patch(context.TODO(), testID.Hex(), domain.AnyVersion, []domain.PatchOperation{
+		{Op: "add", Path: "/name", Value: []byte(`"John"`)},
+	})
+	assert.ErrorIs(t, err, domain.ErrValidation)
+	assert.Contains(t, err.Error(), "invalid patch")
+
+	_, err = userUseCase.Patch(context.TODO(), testID.Hex(), domain.AnyVersion, []domain.PatchOperation{
+		{Op: "remove", Path: "/id"},
+	})
+	assert.ErrorIs(t, err, domain.ErrValidation)
+	assert.Contains(t, err.Error(), "invalid patch")
+
+	_, err = userUseCase.Patch(context.TODO(), testID.Hex(), domain.AnyVersion, []domain.PatchOperation{
+		{Op: "replace", Path: "/version", Value: []byte(`10`)},
+	})
+	assert.ErrorIs(t, err, domain.ErrValidation)
+	assert.Contains(t, err.Error(), "invalid patch")
+}
+
 func TestUserUseCase_Delete(t *testing.T) {
 	testID := primitive.NewObjectID()
+	existing := domain.User{ID: testID, Email: "jane@example.com", Age: 25, Version: 3}
 
 	repoMock := &MockUserRepository{
-		DeleteFunc: func(ctx context.Context, id string) error {
+		FindByIDFunc: func(ctx context.Context, id primitive.ObjectID) (*domain.User, error) {
+			user := existing
+			return &user, nil
+		},
+		DeleteFunc: func(ctx context.Context, id string, version int64) error {
 			return nil
 		},
 	}
 
-	userUseCase := usecase.NewUserUseCase(repoMock, 10*time.Second)
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
 
-	err := userUseCase.Delete(context.TODO(), testID.Hex())
+	err := userUseCase.Delete(context.TODO(), testID.Hex(), domain.AnyVersion)
 	assert.NoError(t, err)
+	assert.Equal(t, []domain.UserEvent{{ID: eventMock.Events[0].ID, Seq: 1, Type: domain.UserEventDeleted, UserID: testID, Previous: &existing, Time: eventMock.Events[0].Time}}, eventMock.Events)
+	if assert.Len(t, outboxMock.Entries, 1) {
+		assert.Equal(t, "user-manager.user.deleted", outboxMock.Entries[0].Event.Type)
+		assert.JSONEq(t, `{"id":"`+testID.Hex()+`"}`, string(outboxMock.Entries[0].Event.Data))
+	}
 
-	repoMock.DeleteFunc = func(ctx context.Context, id string) error {
+	repoMock.DeleteFunc = func(ctx context.Context, id string, version int64) error {
 		return errors.New("delete failed")
 	}
 
-	err = userUseCase.Delete(context.TODO(), testID.Hex())
+	err = userUseCase.Delete(context.TODO(), testID.Hex(), domain.AnyVersion)
 	assert.Error(t, err)
 	assert.Equal(t, "delete failed", err.Error())
 }
 
+func TestUserUseCase_Batch(t *testing.T) {
+	existingID := primitive.NewObjectID()
+	var written []domain.BatchOperation
+	repoMock := &MockUserRepository{
+		FetchByEmailFunc: func(ctx context.Context, email string) ([]domain.User, error) {
+			if email == "existing@example.com" {
+				return []domain.User{{ID: existingID, Email: email, Age: 25, Version: 2}}, nil
+			}
+			return []domain.User{}, nil
+		},
+		FetchByIDsFunc: func(ctx context.Context, ids []string) ([]domain.User, error) {
+			return []domain.User{{ID: existingID, Email: "existing@example.com", Age: 25, Version: 2}}, nil
+		},
+		ApplyBatchFunc: func(ctx context.Context, ops []domain.BatchOperation) error {
+			written = ops
+			return nil
+		},
+	}
+
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
+
+	ops := func() []domain.BatchOperation {
+		return []domain.BatchOperation{
+			{Method: domain.BatchMethodCreate, User: &domain.User{Email: "new@example.com", Age: 30}},
+			{Method: domain.BatchMethodCreate, User: &domain.User{Email: "new@example.com", Age: 31}},
+			{Method: domain.BatchMethodUpdate, ID: existingID.Hex(), Version: 2, User: &domain.User{Email: "existing@example.com", Age: 40}},
+			{Method: domain.BatchMethodDelete, ID: primitive.NewObjectID().Hex()},
+		}
+	}
+
+	results, err := userUseCase.Batch(context.TODO(), ops(), domain.BatchOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "email must be unique", results[1].Err.Error())
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, int64(3), results[2].User.Version)
+	assert.Equal(t, "user not found", results[3].Err.Error())
+	assert.Len(t, written, 2)
+	assert.Equal(t, []string{domain.UserEventCreated, domain.UserEventUpdated}, []string{eventMock.Events[0].Type, eventMock.Events[1].Type})
+	assert.Len(t, outboxMock.Entries, 2)
+
+	written = nil
+	results, err = userUseCase.Batch(context.TODO(), ops(), domain.BatchOptions{Atomic: true})
+	assert.Error(t, err)
+	assert.ErrorIs(t, results[0].Err, domain.ErrBatchAborted)
+	assert.Nil(t, written)
+
+	results, err = userUseCase.Batch(context.TODO(), []domain.BatchOperation{
+		{Method: domain.BatchMethodDelete, ID: existingID.Hex(), Version: 1},
+		{Method: "upsert"},
+	}, domain.BatchOptions{})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, domain.ErrVersionMismatch)
+	assert.ErrorIs(t, results[1].Err, domain.ErrValidation)
+
+	written = nil
+	results, err = userUseCase.Batch(context.TODO(), []domain.BatchOperation{
+		{Method: domain.BatchMethodCreate, User: &domain.User{Email: "new@example.com", Age: 30}},
+		{Method: domain.BatchMethodUpdate, ID: existingID.Hex(), User: &domain.User{Email: "existing@example.com", Age: 40}},
+	}, domain.BatchOptions{RequireVersion: true})
+	assert.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, domain.ErrVersionRequired)
+	assert.Len(t, written, 1)
+}
+
+func TestUserUseCase_BatchWriteConflict(t *testing.T) {
+	var calls [][]domain.BatchOperation
+	repoMock := &MockUserRepository{
+		FetchByEmailFunc: func(ctx context.Context, email string) ([]domain.User, error) {
+			return []domain.User{}, nil
+		},
+		// The second user was created concurrently, so the unique index on
+		// emails rejects it; the fourth fails the collection's validation.
+		ApplyBatchFunc: func(ctx context.Context, ops []domain.BatchOperation) error {
+			calls = append(calls, ops)
+			for i, op := range ops {
+				code := 0
+				switch op.User.Email {
+				case "taken@example.com":
+					code = 11000
+				case "invalid@example.com":
+					code = 121
+				}
+				if code != 0 {
+					return mongodriver.BulkWriteException{
+						WriteErrors: []mongodriver.BulkWriteError{{WriteError: mongodriver.WriteError{Index: i, Code: code, Message: "E11000 duplicate key error collection: users"}}},
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	transactorMock := &MockTransactor{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, transactorMock, 10*time.Second)
+
+	ops := func() []domain.BatchOperation {
+		return []domain.BatchOperation{
+			{Method: domain.BatchMethodCreate, User: &domain.User{Email: "first@example.com", Age: 30}},
+			{Method: domain.BatchMethodCreate, User: &domain.User{Email: "taken@example.com", Age: 30}},
+			{Method: domain.BatchMethodCreate, User: &domain.User{Email: "third@example.com", Age: 30}},
+			{Method: domain.BatchMethodCreate, User: &domain.User{Email: "invalid@example.com", Age: 30}},
+		}
+	}
+
+	results, err := userUseCase.Batch(context.TODO(), ops(), domain.BatchOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, domain.ErrEmailNotUnique)
+	assert.Nil(t, results[1].User)
+	assert.NoError(t, results[2].Err)
+	// Driver messages are not reported.
+	assert.ErrorIs(t, results[3].Err, domain.ErrOperationFailed)
+	// One transaction for the batch, then one per operation.
+	assert.Len(t, calls, 5)
+	assert.Equal(t, 5, transactorMock.Calls)
+	if assert.Len(t, outboxMock.Entries, 2) {
+		assert.Equal(t, results[0].User.ID.Hex(), outboxMock.Entries[0].Event.Subject)
+		assert.Equal(t, results[2].User.ID.Hex(), outboxMock.Entries[1].Event.Subject)
+	}
+
+	calls = nil
+	outboxMock.Entries = nil
+	_, err = userUseCase.Batch(context.TODO(), ops(), domain.BatchOptions{Atomic: true})
+	assert.ErrorIs(t, err, domain.ErrEmailNotUnique)
+	assert.Len(t, calls, 1)
+	assert.Empty(t, outboxMock.Entries)
+}
+
+func TestUserUseCase_Lookup(t *testing.T) {
+	first := domain.User{ID: primitive.NewObjectID(), Email: "first@example.com", Age: 25}
+	second := domain.User{ID: primitive.NewObjectID(), Email: "second@example.com", Age: 30}
+	missingID := primitive.NewObjectID().Hex()
+
+	var queried []string
+	repoMock := &MockUserRepository{
+		FetchByIDsOrEmailsFunc: func(ctx context.Context, ids, emails []string) ([]domain.User, error) {
+			queried = append(ids, emails...)
+			return []domain.User{first, second}, nil
+		},
+	}
+
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
+
+	lookup, err := userUseCase.Lookup(context.TODO(),
+		[]string{second.ID.Hex(), missingID, second.ID.Hex()},
+		[]string{"first@example.com", "second@example.com", "nobody@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{second.ID.Hex(), missingID, "first@example.com", "second@example.com", "nobody@example.com"}, queried)
+	assert.Equal(t, []domain.User{second, first}, lookup.Users)
+	assert.Equal(t, []string{missingID}, lookup.MissingIDs)
+	assert.Equal(t, []string{"nobody@example.com"}, lookup.MissingEmails)
+
+	repoMock.FetchByIDsOrEmailsFunc = func(ctx context.Context, ids, emails []string) ([]domain.User, error) {
+		return nil, errors.New("lookup failed")
+	}
+
+	lookup, err = userUseCase.Lookup(context.TODO(), []string{missingID}, nil)
+	assert.Error(t, err)
+	assert.Nil(t, lookup)
+}
+
+func TestUserUseCase_Search(t *testing.T) {
+	var offset int
+	repoMock := &MockUserRepository{
+		SearchFunc: func(ctx context.Context, filter domain.UserFilter, o, limit int) ([]domain.UserSearchHit, error) {
+			offset = o
+			return []domain.UserSearchHit{
+				{User: domain.User{Email: "Jane.Doe@example.com"}, Score: 1.5},
+				{User: domain.User{Email: "doe@example.com"}, Score: 0.75},
+			}, nil
+		},
+	}
+
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
+
+	hits, err := userUseCase.Search(context.TODO(), domain.UserFilter{Text: "jane DOE -example"}, 3, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, offset)
+	assert.Len(t, hits, 2)
+	assert.Equal(t, []domain.SearchHighlight{{Field: "email", Fragment: "<em>Jane</em>.<em>Doe</em>@example.com"}}, hits[0].Highlights)
+	assert.Equal(t, []domain.SearchHighlight{{Field: "email", Fragment: "<em>doe</em>@example.com"}}, hits[1].Highlights)
+
+	// Fragments match the start of a word.
+	hits, err = userUseCase.Search(context.TODO(), domain.UserFilter{Text: "jan"}, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.SearchHighlight{{Field: "email", Fragment: "<em>Jan</em>e.Doe@example.com"}}, hits[0].Highlights)
+
+	repoMock.SearchFunc = func(ctx context.Context, filter domain.UserFilter, offset, limit int) ([]domain.UserSearchHit, error) {
+		return nil, errors.New("search failed")
+	}
+
+	hits, err = userUseCase.Search(context.TODO(), domain.UserFilter{Text: "jane"}, 1, 10)
+	assert.Error(t, err)
+	assert.Nil(t, hits)
+}
+
+func TestUserUseCase_FuzzySearch(t *testing.T) {
+	var streamed domain.UserFilter
+	users := []domain.User{
+		{Email: "jonathan@example.com"},
+		{Email: "john.smith@example.com"},
+		{Email: "joan@example.com"},
+		{Email: "mary@example.com"},
+	}
+	repoMock := &MockUserRepository{
+		StreamSimilarFunc: func(ctx context.Context, filter domain.UserFilter, maxEdits int, fn func(*domain.User) error) error {
+			streamed = filter
+			for i := range users {
+				if err := fn(&users[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
+
+	hits, total, err := userUseCase.FuzzySearch(context.TODO(), domain.UserFilter{Text: "Jhon"}, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "john.smith@example.com", hits[0].Email)
+	assert.Equal(t, 1, *hits[0].Distance)
+	assert.Equal(t, 0.75, hits[0].Score)
+
+	hits, total, err = userUseCase.FuzzySearch(context.TODO(), domain.UserFilter{Text: "jonathn"}, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "jonathan@example.com", hits[0].Email)
+
+	hits, total, err = userUseCase.FuzzySearch(context.TODO(), domain.UserFilter{Text: "jonathn"}, 2, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Empty(t, hits)
+
+	suggestions, err := userUseCase.Suggest(context.TODO(), domain.UserFilter{Text: "jon.smtih@example.com", MinAge: 30}, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"john.smith@example.com"}, suggestions)
+	assert.Equal(t, domain.UserFilter{Text: "jon.smtih@example.com", MinAge: 30}, streamed)
+
+	// Every match is counted, however many there are.
+	users = make([]domain.User, 250)
+	for i := range users {
+		users[i].Email = "jonathan@example.com"
+	}
+	hits, total, err = userUseCase.FuzzySearch(context.TODO(), domain.UserFilter{Text: "jonathn"}, 3, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), total)
+	assert.Len(t, hits, 50)
+
+	repoMock.StreamSimilarFunc = func(ctx context.Context, filter domain.UserFilter, maxEdits int, fn func(*domain.User) error) error {
+		return errors.New("search failed")
+	}
+
+	_, err = userUseCase.Suggest(context.TODO(), domain.UserFilter{Text: "jhon"}, 5)
+	assert.Error(t, err)
+}
+
+type sliceUserSource struct {
+	rows []*domain.ImportRow
+}
+
+func (s *sliceUserSource) Next() (*domain.ImportRow, error) {
+	if len(s.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+func TestUserUseCase_Import(t *testing.T) {
+	written := 0
+	repoMock := &MockUserRepository{
+		FetchByEmailFunc: func(ctx context.Context, email string) ([]domain.User, error) {
+			if email == "existing@example.com" {
+				return []domain.User{{ID: primitive.NewObjectID(), Email: email, Age: 25}}, nil
+			}
+			return []domain.User{}, nil
+		},
+		ApplyBatchFunc: func(ctx context.Context, ops []domain.BatchOperation) error {
+			written += len(ops)
+			return nil
+		},
+	}
+
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
+
+	source := func() *sliceUserSource {
+		return &sliceUserSource{rows: []*domain.ImportRow{
+			{Line: 2, User: &domain.User{Email: "new@example.com", Age: 30}},
+			{Line: 3, User: &domain.User{Email: "new@example.com", Age: 30}},
+			{Line: 4, User: &domain.User{Email: "existing@example.com", Age: 30}},
+			{Line: 5, User: &domain.User{Email: "young@example.com", Age: 15}},
+			{Line: 6, User: &domain.User{}, Err: errors.New("age must be a number")},
+		}}
+	}
+
+	var reported []domain.ImportRowResult
+	report := func(result domain.ImportRowResult) error {
+		reported = append(reported, result)
+		return nil
+	}
+
+	summary, err := userUseCase.Import(context.TODO(), source(), true, report)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.Accepted)
+	assert.Equal(t, 4, summary.Rejected)
+	assert.Equal(t, 0, written)
+	assert.Len(t, reported, 5)
+	assert.Equal(t, 2, reported[0].Line)
+	assert.True(t, reported[0].Accepted)
+	assert.Equal(t, "email must be unique", reported[1].Error)
+	assert.Equal(t, "email must be unique", reported[2].Error)
+	assert.Equal(t, "age must be greater than 18", reported[3].Error)
+	assert.Equal(t, "age must be a number", reported[4].Error)
+
+	reported = nil
+	summary, err = userUseCase.Import(context.TODO(), source(), false, report)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.Accepted)
+	assert.Equal(t, 1, written)
+	assert.False(t, reported[0].User.ID.IsZero())
+
+	// Emails are only remembered per chunk; a duplicate in a later chunk is
+	// found among the users the earlier chunks wrote.
+	stored := make(map[string]bool)
+	repoMock.FetchByEmailFunc = func(ctx context.Context, email string) ([]domain.User, error) {
+		if stored[email] {
+			return []domain.User{{ID: primitive.NewObjectID(), Email: email, Age: 25}}, nil
+		}
+		return []domain.User{}, nil
+	}
+	repoMock.ApplyBatchFunc = func(ctx context.Context, ops []domain.BatchOperation) error {
+		for _, op := range ops {
+			stored[op.User.Email] = true
+		}
+		return nil
+	}
+
+	rows := make([]*domain.ImportRow, 101)
+	for i := range rows {
+		rows[i] = &domain.ImportRow{Line: i + 2, User: &domain.User{Email: fmt.Sprintf("user%d@example.com", i), Age: 30}}
+	}
+	rows[100].User.Email = rows[0].User.Email
+
+	reported = nil
+	summary, err = userUseCase.Import(context.TODO(), &sliceUserSource{rows: rows}, false, report)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, summary.Accepted)
+	assert.Equal(t, 1, summary.Rejected)
+	assert.Equal(t, "email must be unique", reported[100].Error)
+}
+
 func TestUserUseCase_Count(t *testing.T) {
 	repoMock := &MockUserRepository{
 		CountFunc: func(ctx context.Context) (int64, error) {
@@ -240,9 +917,11 @@ func TestUserUseCase_Count(t *testing.T) {
 		},
 	}
 
-	userUseCase := usecase.NewUserUseCase(repoMock, 10*time.Second)
+	eventMock := &MockUserEventRepository{}
+	outboxMock := &MockOutboxRepository{}
+	userUseCase := usecase.NewUserUseCase(repoMock, eventMock, outboxMock, &MockTransactor{}, 10*time.Second)
 
-	count, err := userUseCase.Count(context.TODO())
+	count, err := userUseCase.Count(context.TODO(), domain.UserFilter{})
 	assert.NoError(t, err)
 	assert.Equal(t, int64(42), count)
 
@@ -250,7 +929,7 @@ func TestUserUseCase_Count(t *testing.T) {
 		return 0, errors.New("count failed")
 	}
 
-	count, err = userUseCase.Count(context.TODO())
+	count, err = userUseCase.Count(context.TODO(), domain.UserFilter{})
 	assert.Error(t, err)
 	assert.Equal(t, int64(0), count)
 	assert.Equal(t, "count failed", err.Error())
@@ -0,0 +1,529 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const (
+	// webhookEventBatch is the number of events dispatched to a webhook at
+	// once.
+	webhookEventBatch = 100
+	// Retries wait webhookBackoffBase after the first failed attempt and
+	// twice as long after every further one, up to webhookBackoffMax.
+	webhookBackoffBase = 30 * time.Second
+	webhookBackoffMax  = 6 * time.Hour
+
+	webhookSecretPrefix = "whsec_"
+	webhookSecretBytes  = 24
+)
+
+type WebhookOptions struct {
+	// RequestTimeout bounds a single delivery attempt.
+	RequestTimeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery fails.
+	MaxAttempts int
+	// DisableAfter is the number of failed attempts in a row after which a
+	// webhook is disabled.
+	DisableAfter int
+	// AllowPrivateNetworks lets webhooks reach loopback, private and
+	// link-local addresses, which are otherwise refused so that webhooks
+	// cannot probe the network the service runs in.
+	AllowPrivateNetworks bool
+}
+
+// errWebhookAddress is returned when a webhook resolves to an address it
+// may not reach.
+var errWebhookAddress = errors.New("webhook address is not allowed")
+
+type webhookUsecase struct {
+	webhookRepository  domain.WebhookRepository
+	deliveryRepository domain.WebhookDeliveryRepository
+	eventRepository    domain.UserEventRepository
+	client             *http.Client
+	options            WebhookOptions
+	contextTimeout     time.Duration
+}
+
+func NewWebhookUsecase(webhookRepository domain.WebhookRepository, deliveryRepository domain.WebhookDeliveryRepository, eventRepository domain.UserEventRepository, options WebhookOptions, timeout time.Duration) domain.WebhookUsecase {
+	return &webhookUsecase{
+		webhookRepository:  webhookRepository,
+		deliveryRepository: deliveryRepository,
+		eventRepository:    eventRepository,
+		client: &http.Client{
+			Transport: webhookTransport(options.AllowPrivateNetworks),
+			Timeout:   options.RequestTimeout,
+			// A redirect counts as a failed attempt; receivers update their
+			// URL instead.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		options:        options,
+		contextTimeout: timeout,
+	}
+}
+
+func (u *webhookUsecase) Create(c context.Context, request *domain.WebhookRequest) (*domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if err := validateWebhookURL(request.URL, u.options.AllowPrivateNetworks); err != nil {
+		return nil, err
+	}
+	secret, err := webhookSecret(request.Secret)
+	if err != nil {
+		return nil, err
+	}
+	// The webhook receives the changes made from now on.
+	lastEventSeq, err := u.eventRepository.Last(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	webhook := &domain.Webhook{
+		ID:           primitive.NewObjectID(),
+		URL:          request.URL,
+		Events:       unique(request.Events),
+		Secret:       secret,
+		Active:       request.Active == nil || *request.Active,
+		LastEventSeq: lastEventSeq,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := u.webhookRepository.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (u *webhookUsecase) GetByID(c context.Context, id string) (*domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+	return u.webhookRepository.GetByID(ctx, id)
+}
+
+func (u *webhookUsecase) Fetch(c context.Context, page, limit int) ([]domain.Webhook, int64, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+	return u.webhookRepository.Fetch(ctx, (page-1)*limit, limit)
+}
+
+func (u *webhookUsecase) Update(c context.Context, id string, request *domain.WebhookRequest) (*domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if err := validateWebhookURL(request.URL, u.options.AllowPrivateNetworks); err != nil {
+		return nil, err
+	}
+
+	webhook, err := u.webhookRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.URL = request.URL
+	webhook.Events = unique(request.Events)
+	// The secret is kept unless a new one is given.
+	if request.Secret != "" {
+		if webhook.Secret, err = webhookSecret(request.Secret); err != nil {
+			return nil, err
+		}
+	}
+	if request.Active != nil {
+		if *request.Active && !webhook.Active {
+			webhook.Failures = 0
+			webhook.DisabledReason = ""
+		}
+		webhook.Active = *request.Active
+	}
+	webhook.UpdatedAt = time.Now()
+
+	if err := u.webhookRepository.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (u *webhookUsecase) Delete(c context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+	return u.webhookRepository.Delete(ctx, id)
+}
+
+func (u *webhookUsecase) Deliveries(c context.Context, webhookID string, page, limit int) ([]domain.WebhookDelivery, int64, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, err := u.webhookRepository.GetByID(ctx, webhookID); err != nil {
+		return nil, 0, err
+	}
+	return u.deliveryRepository.FetchByWebhook(ctx, webhookID, (page-1)*limit, limit)
+}
+
+func (u *webhookUsecase) Delivery(c context.Context, webhookID, id string) (*domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+	return u.deliveryRepository.GetByID(ctx, webhookID, id)
+}
+
+func (u *webhookUsecase) Replay(c context.Context, webhookID, id string) (*domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	webhook, err := u.webhookRepository.GetByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !webhook.Active {
+		return nil, domain.NewCodedError(domain.ErrConflict, "webhook_disabled", nil, "webhook is disabled")
+	}
+
+	return u.deliveryRepository.Replay(ctx, webhookID, id)
+}
+
+func (u *webhookUsecase) Dispatch(c context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	webhooks, err := u.webhookRepository.FetchActive(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, webhook := range webhooks {
+		events, err := u.eventRepository.After(ctx, webhook.LastEventSeq, webhookEventBatch)
+		if err != nil {
+			return queued, err
+		}
+		if len(events) == 0 {
+			continue
+		}
+
+		var deliveries []domain.WebhookDelivery
+		for _, event := range events {
+			if !slices.Contains(webhook.Events, event.Type) {
+				continue
+			}
+			delivery, err := newWebhookDelivery(webhook, event)
+			if err != nil {
+				return queued, err
+			}
+			deliveries = append(deliveries, delivery)
+		}
+
+		if err := u.deliveryRepository.Insert(ctx, deliveries); err != nil {
+			return queued, err
+		}
+		if err := u.webhookRepository.Advance(ctx, webhook.ID, events[len(events)-1].Seq); err != nil {
+			return queued, err
+		}
+		queued += len(deliveries)
+	}
+
+	return queued, nil
+}
+
+func newWebhookDelivery(webhook domain.Webhook, event domain.UserEvent) (domain.WebhookDelivery, error) {
+	payload, err := json.Marshal(domain.WebhookPayload{Type: "user." + event.Type, Timestamp: event.Time, Data: event})
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	now := time.Now()
+	return domain.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     webhook.ID,
+		EventID:       event.ID,
+		EventType:     event.Type,
+		Payload:       payload,
+		Status:        domain.WebhookDeliveryPending,
+		Attempts:      []domain.WebhookAttempt{},
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
+
+func (u *webhookUsecase) Deliver(c context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	// The lease outlasts the attempt, so no other worker sends the delivery
+	// while it is in flight.
+	delivery, err := u.deliveryRepository.Claim(ctx, u.options.RequestTimeout+2*u.contextTimeout)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var attempt domain.WebhookAttempt
+	webhook, err := u.webhookRepository.GetByID(ctx, delivery.WebhookID.Hex())
+	switch {
+	case errors.Is(err, domain.ErrWebhookNotFound):
+		attempt = domain.WebhookAttempt{Time: time.Now(), Error: "webhook was deleted"}
+	case err != nil:
+		return true, err
+	case !webhook.Active:
+		attempt = domain.WebhookAttempt{Time: time.Now(), Error: "webhook is disabled"}
+	default:
+		attempt = u.send(c, webhook, delivery)
+	}
+
+	ctx, cancel = context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	delivery.Tries++
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.UpdatedAt = now
+	delivery.NextAttemptAt = nil
+
+	switch {
+	case webhook == nil || !webhook.Active:
+		delivery.Status = domain.WebhookDeliveryFailed
+	case attempt.Error == "":
+		delivery.Status = domain.WebhookDeliverySucceeded
+		if err := u.webhookRepository.Succeeded(ctx, webhook.ID); err != nil {
+			log.Errorf("Failed to reset failures of webhook %s: %v", webhook.ID.Hex(), err)
+		}
+	default:
+		delivery.Status = domain.WebhookDeliveryFailed
+		if delivery.Tries < u.options.MaxAttempts {
+			delivery.Status = domain.WebhookDeliveryPending
+			next := now.Add(webhookBackoff(delivery.Tries))
+			delivery.NextAttemptAt = &next
+		}
+		u.countFailure(ctx, webhook)
+	}
+
+	return true, u.deliveryRepository.Finish(ctx, delivery)
+}
+
+// send makes one attempt. Anything but a 2xx response is a failure.
+func (u *webhookUsecase) send(c context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) domain.WebhookAttempt {
+	start := time.Now()
+	attempt := domain.WebhookAttempt{Time: start}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	signature, err := signWebhook(webhook.Secret, delivery.ID.Hex(), timestamp, delivery.Payload)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(c, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "user-manager-webhooks")
+	req.Header.Set(domain.HeaderWebhookID, delivery.ID.Hex())
+	req.Header.Set(domain.HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(domain.HeaderWebhookSignature, signature)
+
+	resp, err := u.client.Do(req)
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = webhookRequestError(err)
+		return attempt
+	}
+	// Draining a bounded part of the body lets the connection be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = "unexpected status " + resp.Status
+	}
+	return attempt
+}
+
+func (u *webhookUsecase) countFailure(ctx context.Context, webhook *domain.Webhook) {
+	failures, err := u.webhookRepository.Failed(ctx, webhook.ID)
+	if err != nil {
+		log.Errorf("Failed to count failure of webhook %s: %v", webhook.ID.Hex(), err)
+		return
+	}
+	if failures < u.options.DisableAfter {
+		return
+	}
+
+	reason := fmt.Sprintf("disabled after %d failed attempts in a row", failures)
+	if err := u.webhookRepository.Disable(ctx, webhook.ID, reason); err != nil {
+		log.Errorf("Failed to disable webhook %s: %v", webhook.ID.Hex(), err)
+		return
+	}
+	log.Warnf("Webhook %s %s", webhook.ID.Hex(), reason)
+}
+
+// webhookBackoff returns the wait after the given number of failed tries,
+// with up to a tenth added at random so that retries of deliveries failed
+// together spread out.
+func webhookBackoff(tries int) time.Duration {
+	backoff := webhookBackoffMax
+	if shift := tries - 1; shift < 20 {
+		backoff = min(webhookBackoffBase<<shift, webhookBackoffMax)
+	}
+	return backoff + mathrand.N(backoff/10+1)
+}
+
+// signWebhook returns the Webhook-Signature of a request.
+func signWebhook(secret, id, timestamp string, body []byte) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, webhookSecretPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// webhookSecret checks a given secret or generates one.
+func webhookSecret(secret string) (string, error) {
+	if secret == "" {
+		key := make([]byte, webhookSecretBytes)
+		if _, err := rand.Read(key); err != nil {
+			return "", err
+		}
+		return webhookSecretPrefix + base64.StdEncoding.EncodeToString(key), nil
+	}
+
+	encoded, ok := strings.CutPrefix(secret, webhookSecretPrefix)
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if !ok || err != nil || len(key) < webhookSecretBytes || len(key) > 64 {
+		return "", domain.NewFieldError(domain.ErrValidation, domain.FieldError{
+			Field:   "secret",
+			Code:    "format",
+			Message: "secret must be whsec_ followed by 24 to 64 base64 encoded bytes",
+		})
+	}
+	return secret, nil
+}
+
+func validateWebhookURL(value string, allowPrivate bool) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return domain.NewFieldError(domain.ErrValidation, domain.FieldError{
+			Field:   "url",
+			Code:    "url",
+			Message: "url must be an http or https URL",
+		})
+	}
+
+	// Host names are resolved again on every delivery, so this only turns
+	// away obvious mistakes early; the dialer enforces the rule.
+	if !allowPrivate {
+		host := parsed.Hostname()
+		addr, err := netip.ParseAddr(host)
+		if strings.EqualFold(host, "localhost") || (err == nil && !publicAddr(addr)) {
+			return domain.NewFieldError(domain.ErrValidation, domain.FieldError{
+				Field:   "url",
+				Code:    "public_url",
+				Message: "url must not point to a loopback, private or link-local address",
+			})
+		}
+	}
+	return nil
+}
+
+// webhookTransport returns the transport of webhook requests. Unless
+// allowPrivate is set, it refuses to connect to addresses that are not
+// public, checked after name resolution so that a host name cannot be
+// pointed at them later. Requests bypass any proxy, which would make the
+// connection on their behalf.
+func webhookTransport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(addrPort.Addr()) {
+				return errWebhookAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// nonPublicPrefixes are ranges that netip counts as global unicast but that
+// do not reach the public internet: "this network" and the carrier-grade NAT
+// range of RFC 6598.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookRequestError describes a failed request for the delivery log,
+// which receivers read, without the resolved addresses and other details of
+// the network the service runs in that the error itself carries.
+func webhookRequestError(err error) string {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, errWebhookAddress):
+		return "address is not allowed"
+	case errors.Is(err, context.DeadlineExceeded), os.IsTimeout(err):
+		return "request timed out"
+	case errors.As(err, &dnsErr):
+		return "host could not be resolved"
+	case errors.As(err, &certErr):
+		return "certificate verification failed"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "connection failed"
+	default:
+		return "request failed"
+	}
+}
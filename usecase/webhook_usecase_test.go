@@ -0,0 +1,347 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+	"github.com/nebojsaj1726/user-manager/usecase"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+)
+
+type MockWebhookRepository struct {
+	webhooks map[string]*domain.Webhook
+}
+
+func (m *MockWebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	m.webhooks[webhook.ID.Hex()] = webhook
+	return nil
+}
+
+func (m *MockWebhookRepository) GetByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	webhook, ok := m.webhooks[id]
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+	copied := *webhook
+	return &copied, nil
+}
+
+func (m *MockWebhookRepository) Fetch(ctx context.Context, offset, limit int) ([]domain.Webhook, int64, error) {
+	return nil, 0, nil
+}
+
+func (m *MockWebhookRepository) FetchActive(ctx context.Context) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+	for _, webhook := range m.webhooks {
+		if webhook.Active {
+			webhooks = append(webhooks, *webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *MockWebhookRepository) Update(ctx context.Context, webhook *domain.Webhook) error {
+	m.webhooks[webhook.ID.Hex()] = webhook
+	return nil
+}
+
+func (m *MockWebhookRepository) Delete(ctx context.Context, id string) error {
+	delete(m.webhooks, id)
+	return nil
+}
+
+func (m *MockWebhookRepository) Advance(ctx context.Context, id primitive.ObjectID, lastEventSeq int64) error {
+	m.webhooks[id.Hex()].LastEventSeq = lastEventSeq
+	return nil
+}
+
+func (m *MockWebhookRepository) Succeeded(ctx context.Context, id primitive.ObjectID) error {
+	m.webhooks[id.Hex()].Failures = 0
+	return nil
+}
+
+func (m *MockWebhookRepository) Failed(ctx context.Context, id primitive.ObjectID) (int, error) {
+	m.webhooks[id.Hex()].Failures++
+	return m.webhooks[id.Hex()].Failures, nil
+}
+
+func (m *MockWebhookRepository) Disable(ctx context.Context, id primitive.ObjectID, reason string) error {
+	m.webhooks[id.Hex()].Active = false
+	m.webhooks[id.Hex()].DisabledReason = reason
+	return nil
+}
+
+type MockWebhookDeliveryRepository struct {
+	deliveries []*domain.WebhookDelivery
+}
+
+func (m *MockWebhookDeliveryRepository) EnsureIndexes(ctx context.Context, retention time.Duration) error {
+	return nil
+}
+
+func (m *MockWebhookDeliveryRepository) Insert(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	for i := range deliveries {
+		m.deliveries = append(m.deliveries, &deliveries[i])
+	}
+	return nil
+}
+
+func (m *MockWebhookDeliveryRepository) Claim(ctx context.Context, lease time.Duration) (*domain.WebhookDelivery, error) {
+	for _, delivery := range m.deliveries {
+		if delivery.Status == domain.WebhookDeliveryPending && !delivery.NextAttemptAt.After(time.Now()) {
+			copied := *delivery
+			return &copied, nil
+		}
+	}
+	return nil, mongodriver.ErrNoDocuments
+}
+
+func (m *MockWebhookDeliveryRepository) Finish(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	for i := range m.deliveries {
+		if m.deliveries[i].ID == delivery.ID {
+			m.deliveries[i] = delivery
+		}
+	}
+	return nil
+}
+
+func (m *MockWebhookDeliveryRepository) GetByID(ctx context.Context, webhookID, id string) (*domain.WebhookDelivery, error) {
+	return nil, domain.ErrWebhookDeliveryNotFound
+}
+
+func (m *MockWebhookDeliveryRepository) FetchByWebhook(ctx context.Context, webhookID string, offset, limit int) ([]domain.WebhookDelivery, int64, error) {
+	return nil, 0, nil
+}
+
+func (m *MockWebhookDeliveryRepository) Replay(ctx context.Context, webhookID, id string) (*domain.WebhookDelivery, error) {
+	for _, delivery := range m.deliveries {
+		if delivery.ID.Hex() == id {
+			now := time.Now()
+			delivery.Status, delivery.Tries, delivery.NextAttemptAt = domain.WebhookDeliveryPending, 0, &now
+			return delivery, nil
+		}
+	}
+	return nil, domain.ErrWebhookDeliveryNotFound
+}
+
+func newWebhookTest(t *testing.T, handler http.HandlerFunc) (domain.WebhookUsecase, *MockWebhookRepository, *MockWebhookDeliveryRepository, *MockUserEventRepository, *domain.Webhook) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	webhookMock := &MockWebhookRepository{webhooks: map[string]*domain.Webhook{}}
+	deliveryMock := &MockWebhookDeliveryRepository{}
+	eventMock := &MockUserEventRepository{}
+	webhookUsecase := usecase.NewWebhookUsecase(webhookMock, deliveryMock, eventMock, usecase.WebhookOptions{
+		RequestTimeout: time.Second,
+		MaxAttempts:    3,
+		DisableAfter:   5,
+		// The test server listens on a loopback address.
+		AllowPrivateNetworks: true,
+	}, 10*time.Second)
+
+	webhook, err := webhookUsecase.Create(context.Background(), &domain.WebhookRequest{
+		URL:    server.URL,
+		Events: []string{domain.UserEventCreated, domain.UserEventDeleted},
+	})
+	assert.NoError(t, err)
+
+	return webhookUsecase, webhookMock, deliveryMock, eventMock, webhook
+}
+
+func TestWebhookUsecase_Create(t *testing.T) {
+	_, _, _, _, webhook := newWebhookTest(t, nil)
+
+	assert.True(t, webhook.Active)
+	assert.True(t, strings.HasPrefix(webhook.Secret, "whsec_"))
+
+	webhookUsecase := usecase.NewWebhookUsecase(&MockWebhookRepository{webhooks: map[string]*domain.Webhook{}}, nil, &MockUserEventRepository{}, usecase.WebhookOptions{}, time.Second)
+
+	_, err := webhookUsecase.Create(context.Background(), &domain.WebhookRequest{URL: "ftp://example.com", Events: []string{"created"}})
+	assert.ErrorIs(t, err, domain.ErrValidation)
+
+	_, err = webhookUsecase.Create(context.Background(), &domain.WebhookRequest{URL: "https://example.com", Events: []string{"created"}, Secret: "hunter2"})
+	assert.ErrorIs(t, err, domain.ErrValidation)
+
+	secret := "whsec_" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef01234567"))
+	created, err := webhookUsecase.Create(context.Background(), &domain.WebhookRequest{URL: "https://example.com", Events: []string{"created"}, Secret: secret})
+	assert.NoError(t, err)
+	assert.Equal(t, secret, created.Secret)
+}
+
+func TestWebhookUsecase_Deliver(t *testing.T) {
+	var received []*http.Request
+	var bodies [][]byte
+	status := http.StatusOK
+
+	webhookUsecase, webhookMock, deliveryMock, eventMock, webhook := newWebhookTest(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	})
+	ctx := context.Background()
+
+	userID := primitive.NewObjectID()
+	eventMock.Events = []domain.UserEvent{
+		{ID: primitive.NewObjectID(), Seq: 1, Type: domain.UserEventCreated, UserID: userID, User: &domain.User{ID: userID, Email: "jane@example.com"}},
+		{ID: primitive.NewObjectID(), Seq: 2, Type: domain.UserEventUpdated, UserID: userID, User: &domain.User{ID: userID, Email: "jane@example.com"}},
+		{ID: primitive.NewObjectID(), Seq: 3, Type: domain.UserEventDeleted, UserID: userID},
+	}
+
+	queued, err := webhookUsecase.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, queued)
+	assert.Equal(t, eventMock.Events[2].Seq, webhookMock.webhooks[webhook.ID.Hex()].LastEventSeq)
+
+	queued, err = webhookUsecase.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, queued)
+
+	t.Run("signed request", func(t *testing.T) {
+		delivered, err := webhookUsecase.Deliver(ctx)
+		assert.NoError(t, err)
+		assert.True(t, delivered)
+
+		delivery := deliveryMock.deliveries[0]
+		assert.Equal(t, domain.WebhookDeliverySucceeded, delivery.Status)
+		assert.Len(t, delivery.Attempts, 1)
+		assert.Equal(t, http.StatusOK, delivery.Attempts[0].StatusCode)
+
+		req := received[0]
+		assert.Equal(t, delivery.ID.Hex(), req.Header.Get(domain.HeaderWebhookID))
+
+		key, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(webhook.Secret, "whsec_"))
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(req.Header.Get(domain.HeaderWebhookID) + "." + req.Header.Get(domain.HeaderWebhookTimestamp) + "."))
+		mac.Write(bodies[0])
+		assert.Equal(t, "v1,"+base64.StdEncoding.EncodeToString(mac.Sum(nil)), req.Header.Get(domain.HeaderWebhookSignature))
+
+		var payload domain.WebhookPayload
+		assert.NoError(t, json.Unmarshal(bodies[0], &payload))
+		assert.Equal(t, "user.created", payload.Type)
+		assert.Equal(t, "jane@example.com", payload.Data.User.Email)
+	})
+
+	t.Run("retries with backoff", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+
+		delivered, err := webhookUsecase.Deliver(ctx)
+		assert.NoError(t, err)
+		assert.True(t, delivered)
+
+		delivery := deliveryMock.deliveries[1]
+		assert.Equal(t, domain.WebhookDeliveryPending, delivery.Status)
+		assert.Equal(t, 1, delivery.Tries)
+		assert.Equal(t, "unexpected status 503 Service Unavailable", delivery.Attempts[0].Error)
+		assert.WithinDuration(t, time.Now().Add(33*time.Second), *delivery.NextAttemptAt, 4*time.Second)
+		assert.Equal(t, 1, webhookMock.webhooks[webhook.ID.Hex()].Failures)
+
+		// Nothing else is due.
+		delivered, err = webhookUsecase.Deliver(ctx)
+		assert.NoError(t, err)
+		assert.False(t, delivered)
+	})
+
+	t.Run("fails after max attempts", func(t *testing.T) {
+		delivery := deliveryMock.deliveries[1]
+		for delivery.Status == domain.WebhookDeliveryPending {
+			now := time.Now()
+			delivery.NextAttemptAt = &now
+			_, err := webhookUsecase.Deliver(ctx)
+			assert.NoError(t, err)
+			delivery = deliveryMock.deliveries[1]
+		}
+
+		assert.Equal(t, domain.WebhookDeliveryFailed, delivery.Status)
+		assert.Len(t, delivery.Attempts, 3)
+		assert.Nil(t, delivery.NextAttemptAt)
+		assert.True(t, webhookMock.webhooks[webhook.ID.Hex()].Active)
+	})
+
+	t.Run("disabled after repeated failures", func(t *testing.T) {
+		_, err := webhookUsecase.Replay(ctx, webhook.ID.Hex(), deliveryMock.deliveries[1].ID.Hex())
+		assert.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			now := time.Now()
+			deliveryMock.deliveries[1].NextAttemptAt = &now
+			_, err := webhookUsecase.Deliver(ctx)
+			assert.NoError(t, err)
+		}
+
+		disabled := webhookMock.webhooks[webhook.ID.Hex()]
+		assert.False(t, disabled.Active)
+		assert.Equal(t, "disabled after 5 failed attempts in a row", disabled.DisabledReason)
+		assert.Len(t, deliveryMock.deliveries[1].Attempts, 5)
+
+		_, err = webhookUsecase.Replay(ctx, webhook.ID.Hex(), deliveryMock.deliveries[1].ID.Hex())
+		assert.ErrorIs(t, err, domain.ErrConflict)
+
+		active := true
+		enabled, err := webhookUsecase.Update(ctx, webhook.ID.Hex(), &domain.WebhookRequest{URL: webhook.URL, Events: webhook.Events, Active: &active})
+		assert.NoError(t, err)
+		assert.True(t, enabled.Active)
+		assert.Zero(t, enabled.Failures)
+		assert.Empty(t, enabled.DisabledReason)
+		assert.Equal(t, webhook.Secret, enabled.Secret)
+	})
+}
+
+func TestWebhookUsecase_PrivateNetworks(t *testing.T) {
+	webhookUsecase := usecase.NewWebhookUsecase(&MockWebhookRepository{webhooks: map[string]*domain.Webhook{}}, nil, &MockUserEventRepository{}, usecase.WebhookOptions{}, time.Second)
+
+	for _, url := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::ffff:192.168.0.1]/hook",
+	} {
+		_, err := webhookUsecase.Create(context.Background(), &domain.WebhookRequest{URL: url, Events: []string{"created"}})
+		assert.ErrorIs(t, err, domain.ErrValidation, url)
+	}
+
+	t.Run("refused at dial time", func(t *testing.T) {
+		// A host name may resolve to a private address only after the
+		// webhook was created, which the loopback test server stands in for.
+		requests := 0
+		_, webhookMock, deliveryMock, eventMock, _ := newWebhookTest(t, func(w http.ResponseWriter, r *http.Request) {
+			requests++
+		})
+		webhookUsecase := usecase.NewWebhookUsecase(webhookMock, deliveryMock, eventMock, usecase.WebhookOptions{
+			RequestTimeout: time.Second,
+			MaxAttempts:    3,
+			DisableAfter:   5,
+		}, 10*time.Second)
+
+		userID := primitive.NewObjectID()
+		eventMock.Events = []domain.UserEvent{
+			{ID: primitive.NewObjectID(), Seq: 1, Type: domain.UserEventCreated, UserID: userID, User: &domain.User{ID: userID, Email: "jane@example.com"}},
+		}
+		_, err := webhookUsecase.Dispatch(context.Background())
+		assert.NoError(t, err)
+
+		delivered, err := webhookUsecase.Deliver(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, delivered)
+
+		assert.Zero(t, requests)
+		if !assert.Len(t, deliveryMock.deliveries[0].Attempts, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, "address is not allowed", deliveryMock.deliveries[0].Attempts[0].Error)
+	})
+}
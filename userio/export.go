@@ -0,0 +1,140 @@
+package userio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// exportFlushInterval is the number of users written between flushes, so an
+// HTTP client starts receiving data before the export finishes.
+const exportFlushInterval = 100
+
+var exportCSVHeader = []string{"id", "email", "age", "version"}
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+	"json":   "application/json; charset=utf-8",
+}
+
+type ExportWriter interface {
+	Write(user *domain.User) error
+	Close() error
+}
+
+// ExportContentType returns the media type of an export format, or an error
+// when the format is not supported.
+func ExportContentType(format string) (string, error) {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported export format %q", format)
+	}
+	return contentType, nil
+}
+
+func NewExportWriter(w io.Writer, format string) (ExportWriter, error) {
+	switch format {
+	case "csv":
+		return &csvExport{w: w, writer: csv.NewWriter(w)}, nil
+	case "ndjson":
+		return &jsonExport{w: w}, nil
+	case "json":
+		return &jsonExport{w: w, opening: "[", separator: ",", closing: "]\n"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+type csvExport struct {
+	w      io.Writer
+	writer *csv.Writer
+	count  int
+}
+
+func (e *csvExport) Write(user *domain.User) error {
+	if e.count == 0 {
+		if err := e.writer.Write(exportCSVHeader); err != nil {
+			return err
+		}
+	}
+
+	err := e.writer.Write([]string{
+		user.ID.Hex(),
+		user.Email,
+		strconv.Itoa(user.Age),
+		strconv.FormatInt(user.Version, 10),
+	})
+	if err != nil {
+		return err
+	}
+
+	e.count++
+	if e.count%exportFlushInterval == 0 {
+		e.writer.Flush()
+		flush(e.w)
+	}
+	return e.writer.Error()
+}
+
+func (e *csvExport) Close() error {
+	if e.count == 0 {
+		if err := e.writer.Write(exportCSVHeader); err != nil {
+			return err
+		}
+	}
+
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonExport writes users as a JSON array or, with empty delimiters, as
+// newline-delimited JSON.
+type jsonExport struct {
+	w         io.Writer
+	opening   string
+	separator string
+	closing   string
+	count     int
+}
+
+func (e *jsonExport) Write(user *domain.User) error {
+	prefix := e.separator
+	if e.count == 0 {
+		prefix = e.opening
+	}
+	if _, err := io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(e.w).Encode(user); err != nil {
+		return err
+	}
+
+	e.count++
+	if e.count%exportFlushInterval == 0 {
+		flush(e.w)
+	}
+	return nil
+}
+
+func (e *jsonExport) Close() error {
+	closing := e.closing
+	if e.count == 0 {
+		closing = e.opening + e.closing
+	}
+
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
+
+func flush(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,221 @@
+package userio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// ImportColumns are the user fields read from an import file. Their header
+// names default to the field name and can be remapped per request.
+var ImportColumns = []string{"email", "age"}
+
+type csvUserSource struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func NewCSVSource(r io.Reader, mapping map[string]string) (domain.UserSource, error) {
+	for field := range mapping {
+		if !slices.Contains(ImportColumns, field) {
+			return nil, domain.NewValidationError("unknown import field %q", field)
+		}
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, domain.NewValidationError("failed to read CSV header: %v", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	columns := make(map[string]int, len(ImportColumns))
+	for _, field := range ImportColumns {
+		name := field
+		if mapped, ok := mapping[field]; ok {
+			name = mapped
+		}
+
+		columns[field] = -1
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), name) {
+				columns[field] = i
+				break
+			}
+		}
+
+		if columns[field] < 0 {
+			return nil, domain.NewValidationError("CSV header is missing column %q", name)
+		}
+	}
+
+	return &csvUserSource{reader: reader, columns: columns}, nil
+}
+
+func (s *csvUserSource) Next() (*domain.ImportRow, error) {
+	record, err := s.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &domain.ImportRow{Line: parseErr.Line, User: &domain.User{}, Err: parseErr.Err}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := s.reader.FieldPos(0)
+	row := &domain.ImportRow{Line: line, User: &domain.User{}}
+
+	for _, index := range s.columns {
+		if index >= len(record) {
+			row.Err = fmt.Errorf("row has %d columns, expected at least %d", len(record), index+1)
+			return row, nil
+		}
+	}
+
+	row.User.Email = strings.TrimSpace(record[s.columns["email"]])
+
+	age, err := strconv.Atoi(strings.TrimSpace(record[s.columns["age"]]))
+	if err != nil {
+		row.Err = fmt.Errorf("age must be a number")
+		return row, nil
+	}
+	row.User.Age = age
+
+	return row, nil
+}
+
+// ReportWriter streams the per-row outcome of an import.
+type ReportWriter interface {
+	WriteRow(result domain.ImportRowResult) error
+	Close(summary *domain.ImportSummary, err error) error
+}
+
+var reportContentTypes = map[string]string{
+	"json": "application/json; charset=utf-8",
+	"csv":  "text/csv; charset=utf-8",
+}
+
+// ReportContentType returns the media type of a report format, or an error
+// when the format is not supported.
+func ReportContentType(format string) (string, error) {
+	contentType, ok := reportContentTypes[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported report format %q", format)
+	}
+	return contentType, nil
+}
+
+func NewReportWriter(w io.Writer, format string) (ReportWriter, error) {
+	switch format {
+	case "json":
+		return &jsonImportReport{w: w}, nil
+	case "csv":
+		return &csvImportReport{writer: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+type jsonImportReport struct {
+	w    io.Writer
+	rows int
+}
+
+func (r *jsonImportReport) WriteRow(result domain.ImportRowResult) error {
+	prefix := ","
+	if r.rows == 0 {
+		prefix = `{"rows":[`
+	}
+	if _, err := io.WriteString(r.w, prefix); err != nil {
+		return err
+	}
+	r.rows++
+
+	return json.NewEncoder(r.w).Encode(result)
+}
+
+func (r *jsonImportReport) Close(summary *domain.ImportSummary, err error) error {
+	if r.rows == 0 {
+		if _, err := io.WriteString(r.w, `{"rows":[`); err != nil {
+			return err
+		}
+	}
+
+	trailer := map[string]interface{}{"summary": summary}
+	if err != nil {
+		trailer["error"] = err.Error()
+	}
+
+	encoded, encodeErr := json.Marshal(trailer)
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	// Splice the trailer object's fields in after the rows array.
+	_, writeErr := fmt.Fprintf(r.w, "],%s", encoded[1:])
+	return writeErr
+}
+
+type csvImportReport struct {
+	writer  *csv.Writer
+	started bool
+}
+
+func (r *csvImportReport) start() error {
+	if r.started {
+		return nil
+	}
+
+	r.started = true
+	return r.writer.Write([]string{"line", "status", "email", "age", "error"})
+}
+
+func (r *csvImportReport) WriteRow(result domain.ImportRowResult) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+
+	status := "rejected"
+	if result.Accepted {
+		status = "accepted"
+	}
+
+	return r.writer.Write([]string{
+		strconv.Itoa(result.Line),
+		status,
+		result.User.Email,
+		strconv.Itoa(result.User.Age),
+		result.Error,
+	})
+}
+
+func (r *csvImportReport) Close(summary *domain.ImportSummary, err error) error {
+	if startErr := r.start(); startErr != nil {
+		return startErr
+	}
+
+	if err != nil {
+		if writeErr := r.writer.Write([]string{"", "aborted", "", "", err.Error()}); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	r.writer.Flush()
+	return r.writer.Error()
+}
@@ -2,23 +2,80 @@ import http from "../http-common";
 
 const UserDataService = {
   getAll(params) {
-    return http.get("/users", { params });
+    return http.get("/v1/users", { params });
   },
 
   get(id) {
-    return http.get(`/users/${id}`);
+    return http.get(`/v1/users/${id}`);
   },
 
   create(data) {
-    return http.post("/users", data);
+    return http.post("/v1/users", data);
   },
 
-  update(id, data) {
-    return http.put(`/users/${id}`, data);
+  update(id, data, etag) {
+    return http.put(`/v1/users/${id}`, data, {
+      headers: etag ? { "If-Match": etag } : {},
+    });
   },
 
-  delete(id) {
-    return http.delete(`/users/${id}`);
+  delete(id, etag) {
+    return http.delete(`/v1/users/${id}`, {
+      headers: etag ? { "If-Match": etag } : {},
+    });
+  },
+
+  // events calls onEvent for every user change of the given types until the
+  // returned stream is closed. EventSource cannot set headers, so every
+  // connection redeems a one-time ticket; a failed connection is reopened
+  // with a new ticket and resumes after the last event received. The app
+  // holds no API credentials of its own: tickets are only issued when
+  // something in front of the API, such as a proxy, authenticates the
+  // request, and live updates stay off otherwise.
+  events(types, onEvent) {
+    let source = null;
+    let retryTimer = null;
+    let lastEventId = "";
+    let closed = false;
+
+    const connect = async () => {
+      try {
+        const { data } = await http.post("/tickets");
+        if (closed) return;
+
+        const params = new URLSearchParams({ ticket: data.ticket });
+        if (lastEventId) params.set("last_event_id", lastEventId);
+        source = new EventSource(
+          `${http.defaults.baseURL}/v1/users/events?${params}`
+        );
+        types.forEach((type) =>
+          source.addEventListener(type, (event) => {
+            lastEventId = event.lastEventId || lastEventId;
+            onEvent(event);
+          })
+        );
+        source.onerror = () => {
+          source.close();
+          retry();
+        };
+      } catch (error) {
+        if (error.response?.status !== 401) retry();
+      }
+    };
+
+    const retry = () => {
+      if (!closed) retryTimer = setTimeout(connect, 3000);
+    };
+
+    connect();
+
+    return {
+      close() {
+        closed = true;
+        clearTimeout(retryTimer);
+        source?.close();
+      },
+    };
   },
 };
 
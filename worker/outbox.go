@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// relayInterval is the wait after the outbox was found empty. It is shorter
+// than pollInterval since it bounds how late events are published.
+const relayInterval = 500 * time.Millisecond
+
+// Relay publishes the outbox. Entries are claimed from Mongo, so every
+// instance can run one.
+type Relay struct {
+	outboxUsecase domain.OutboxUsecase
+	wg            sync.WaitGroup
+}
+
+func NewRelay(outboxUsecase domain.OutboxUsecase) *Relay {
+	return &Relay{outboxUsecase: outboxUsecase}
+}
+
+// Start runs the relay until ctx is done.
+func (r *Relay) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(ctx)
+	}()
+
+	log.Info("Outbox relay started")
+}
+
+// Wait returns once the relay stopped.
+func (r *Relay) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Relay) run(ctx context.Context) {
+	for {
+		published, err := r.outboxUsecase.Relay(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("Failed to relay outbox: %v", err)
+		}
+
+		if published == 0 || err != nil {
+			if !sleep(ctx, relayInterval) {
+				return
+			}
+		}
+	}
+}
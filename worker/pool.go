@@ -0,0 +1,216 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+const (
+	pollInterval      = 2 * time.Second
+	heartbeatInterval = 5 * time.Second
+	// staleAfter must comfortably exceed heartbeatInterval, otherwise live
+	// jobs of other instances would be failed.
+	staleAfter = time.Minute
+)
+
+// Pool runs queued jobs with a fixed number of workers. Jobs are claimed from
+// Mongo, so several instances can share one queue.
+type Pool struct {
+	jobUsecase domain.JobUsecase
+	handlers   map[string]domain.JobHandler
+	size       int
+	wg         sync.WaitGroup
+}
+
+func NewPool(jobUsecase domain.JobUsecase, handlers map[string]domain.JobHandler, size int) *Pool {
+	return &Pool{
+		jobUsecase: jobUsecase,
+		handlers:   handlers,
+		size:       size,
+	}
+}
+
+// Start runs the workers until ctx is done. Jobs running then are
+// interrupted and failed as stale later, by this or another instance.
+func (p *Pool) Start(ctx context.Context) {
+	p.wg.Add(p.size + 1)
+	for i := 0; i < p.size; i++ {
+		go func() {
+			defer p.wg.Done()
+			p.work(ctx)
+		}()
+	}
+	go func() {
+		defer p.wg.Done()
+		p.reap(ctx)
+	}()
+
+	log.Infof("Job worker pool started with %d workers", p.size)
+}
+
+// Wait returns once all workers stopped.
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for {
+		job, input, output, err := p.jobUsecase.Claim(ctx)
+		switch {
+		case errors.Is(err, mongodriver.ErrNoDocuments):
+			if !sleep(ctx, pollInterval) {
+				return
+			}
+			continue
+		case err != nil && job == nil:
+			if ctx.Err() == nil {
+				log.Errorf("Failed to claim job: %v", err)
+			}
+			if !sleep(ctx, pollInterval) {
+				return
+			}
+			continue
+		case err != nil:
+			p.finish(ctx, job, err)
+			continue
+		}
+
+		p.run(ctx, job, input, output)
+	}
+}
+
+func (p *Pool) run(ctx context.Context, job *domain.Job, input io.ReadCloser, output io.WriteCloser) {
+	handler, ok := p.handlers[job.Type]
+	if !ok {
+		closeAll(input, output)
+		p.finish(ctx, job, fmt.Errorf("unknown job type %q", job.Type))
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	progress := &progress{}
+	var canceled atomic.Bool
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cancelRequested, err := p.jobUsecase.Heartbeat(ctx, job, progress.processed.Load(), progress.total.Load())
+				if err != nil {
+					log.Errorf("Failed to store progress of job %s: %v", job.ID.Hex(), err)
+					continue
+				}
+				if cancelRequested {
+					canceled.Store(true)
+					cancel()
+				}
+			}
+		}
+	}()
+
+	err := handle(jobCtx, handler, job, input, output, progress)
+	close(done)
+	closeAll(input, output)
+
+	job.Processed = progress.processed.Load()
+	job.Total = progress.total.Load()
+	if canceled.Load() {
+		err = context.Canceled
+	} else if ctx.Err() != nil {
+		// The job was not canceled but interrupted by shutdown, and its
+		// state can no longer be stored.
+		log.Warnf("Job %s was interrupted", job.ID.Hex())
+		return
+	}
+
+	p.finish(ctx, job, err)
+}
+
+// handle runs handler and turns a panic into an error, so that the job is
+// failed instead of the worker dying with the job left running.
+func handle(ctx context.Context, handler domain.JobHandler, job *domain.Job, input io.Reader, output io.Writer, progress domain.JobProgress) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Job %s panicked: %v\n%s", job.ID.Hex(), r, debug.Stack())
+			err = errors.New("the job failed unexpectedly")
+		}
+	}()
+	return handler(ctx, job, input, output, progress)
+}
+
+func (p *Pool) finish(ctx context.Context, job *domain.Job, err error) {
+	if finishErr := p.jobUsecase.Finish(ctx, job, err); finishErr != nil {
+		log.Errorf("Failed to finish job %s: %v", job.ID.Hex(), finishErr)
+	}
+}
+
+func (p *Pool) reap(ctx context.Context) {
+	for {
+		count, err := p.jobUsecase.FailStale(ctx, staleAfter)
+		if err != nil {
+			log.Errorf("Failed to reap stale jobs: %v", err)
+		} else if count > 0 {
+			log.Warnf("Marked %d interrupted jobs as failed", count)
+		}
+
+		count, err = p.jobUsecase.Expire(ctx)
+		if err != nil {
+			log.Errorf("Failed to delete expired jobs: %v", err)
+		} else if count > 0 {
+			log.Infof("Deleted %d expired jobs", count)
+		}
+
+		if !sleep(ctx, staleAfter) {
+			return
+		}
+	}
+}
+
+type progress struct {
+	processed atomic.Int64
+	total     atomic.Int64
+}
+
+func (p *progress) Add(n int64) {
+	p.processed.Add(n)
+}
+
+func (p *progress) SetTotal(total int64) {
+	p.total.Store(total)
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func closeAll(closers ...io.Closer) {
+	for _, closer := range closers {
+		if closer != nil {
+			closer.Close()
+		}
+	}
+}
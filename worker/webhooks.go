@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nebojsaj1726/user-manager/domain"
+)
+
+// Webhooks queues deliveries of new user events and sends them with a fixed
+// number of senders. Deliveries are claimed from Mongo, so several instances
+// can share the work.
+type Webhooks struct {
+	webhookUsecase domain.WebhookUsecase
+	size           int
+	wg             sync.WaitGroup
+}
+
+func NewWebhooks(webhookUsecase domain.WebhookUsecase, size int) *Webhooks {
+	return &Webhooks{
+		webhookUsecase: webhookUsecase,
+		size:           size,
+	}
+}
+
+// Start runs the dispatcher and senders until ctx is done.
+func (w *Webhooks) Start(ctx context.Context) {
+	w.wg.Add(w.size + 1)
+	go func() {
+		defer w.wg.Done()
+		w.dispatch(ctx)
+	}()
+	for i := 0; i < w.size; i++ {
+		go func() {
+			defer w.wg.Done()
+			w.deliver(ctx)
+		}()
+	}
+
+	log.Infof("Webhook delivery started with %d senders", w.size)
+}
+
+// Wait returns once the dispatcher and all senders stopped.
+func (w *Webhooks) Wait() {
+	w.wg.Wait()
+}
+
+func (w *Webhooks) dispatch(ctx context.Context) {
+	for {
+		if _, err := w.webhookUsecase.Dispatch(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("Failed to dispatch webhook deliveries: %v", err)
+		}
+
+		if !sleep(ctx, pollInterval) {
+			return
+		}
+	}
+}
+
+func (w *Webhooks) deliver(ctx context.Context) {
+	for {
+		delivered, err := w.webhookUsecase.Deliver(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("Failed to deliver webhook: %v", err)
+		}
+
+		if !delivered || err != nil {
+			if !sleep(ctx, pollInterval) {
+				return
+			}
+		}
+	}
+}